@@ -88,6 +88,7 @@ type AccessLogRecord struct {
 	BodyBytesSent        int
 	RequestBytesReceived int
 	ExtraHeadersToLog    []string
+	RouterError          string
 	record               []byte
 }
 
@@ -157,6 +158,11 @@ func (r *AccessLogRecord) makeRecord() []byte {
 	b.WriteString(`app_index:`)
 	b.WriteDashOrStringValue(appIndex)
 
+	if r.RouterError != "" {
+		b.WriteString(` x_cf_routererror:`)
+		b.WriteDashOrStringValue(r.RouterError)
+	}
+
 	r.addExtraHeaders(b)
 
 	b.WriteByte('\n')
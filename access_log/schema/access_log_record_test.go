@@ -188,6 +188,35 @@ var _ = Describe("AccessLogRecord", func() {
 			})
 		})
 
+		Context("with a router error", func() {
+			BeforeEach(func() {
+				record.StatusCode = 503
+				record.RouterError = "maintenance_mode"
+			})
+			It("appends the router error", func() {
+				recordString := "FakeRequestHost - " +
+					"[2000-01-01T00:00:00.000+0000] " +
+					`"FakeRequestMethod http://example.com/request FakeRequestProto" ` +
+					`503 ` +
+					"30 " +
+					"23 " +
+					`"FakeReferer" ` +
+					`"FakeUserAgent" ` +
+					`"FakeRemoteAddr" ` +
+					`"1.2.3.4:1234" ` +
+					`x_forwarded_for:"FakeProxy1, FakeProxy2" ` +
+					`x_forwarded_proto:"FakeOriginalRequestProto" ` +
+					`vcap_request_id:"abc-123-xyz-pdq" ` +
+					`response_time:60 ` +
+					`app_id:"FakeApplicationId" ` +
+					`app_index:"3" ` +
+					`x_cf_routererror:"maintenance_mode"` +
+					"\n"
+
+				Expect(record.LogMessage()).To(Equal(recordString))
+			})
+		})
+
 		Context("when extra headers is an empty slice", func() {
 			It("Makes a record with all values", func() {
 				record := schema.AccessLogRecord{
@@ -89,6 +89,7 @@ type VcapComponent struct {
 	Healthz    *health.Healthz `json:"-"`
 	Health     http.Handler
 	InfoRoutes map[string]json.Marshaler `json:"-"`
+	Handlers   map[string]http.Handler   `json:"-"`
 	Logger     logger.Logger             `json:"-"`
 
 	listener net.Listener
@@ -96,11 +97,28 @@ type VcapComponent struct {
 	quitCh   chan struct{}
 }
 
+// Version of the router, set at build time with
+// -ldflags "-X code.cloudfoundry.org/gorouter/common.Version=..."
+var Version = "dev"
+
 type RouterStart struct {
-	Id                               string   `json:"id"`
-	Hosts                            []string `json:"hosts"`
-	MinimumRegisterIntervalInSeconds int      `json:"minimumRegisterIntervalInSeconds"`
-	PruneThresholdInSeconds          int      `json:"pruneThresholdInSeconds"`
+	Id                               string             `json:"id"`
+	Hosts                            []string           `json:"hosts"`
+	MinimumRegisterIntervalInSeconds int                `json:"minimumRegisterIntervalInSeconds"`
+	PruneThresholdInSeconds          int                `json:"pruneThresholdInSeconds"`
+	Capabilities                     RouterCapabilities `json:"capabilities"`
+}
+
+// RouterCapabilities tell registering components which features of
+// registration messages the router supports.
+type RouterCapabilities struct {
+	TLSBackends       bool     `json:"tlsBackends"`
+	BatchRegistration bool     `json:"batchRegistration"`
+	SignatureRequired bool     `json:"signatureRequired"`
+	Protocols         []string `json:"protocols"`
+	ShardingMode      string   `json:"shardingMode"`
+	IsolationSegments []string `json:"isolationSegments"`
+	Version           string   `json:"version"`
 }
 
 func (c *VcapComponent) UpdateVarz() {
@@ -240,6 +258,10 @@ func (c *VcapComponent) ListenAndServe() {
 		})
 	}
 
+	for path, handler := range c.Handlers {
+		hs.Handle(path, handler)
+	}
+
 	f := func(user, password string) bool {
 		return user == c.Varz.Credentials[0] && password == c.Varz.Credentials[1]
 	}
@@ -116,6 +116,28 @@ var _ = Describe("Component", func() {
 		Expect(body).To(Equal(`{"key":"value"}` + "\n"))
 	})
 
+	It("serves additional handlers behind authentication", func() {
+		path := "/test"
+
+		component.Handlers = map[string]http.Handler{
+			path: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte(r.Method))
+			}),
+		}
+		serveComponent(component)
+
+		req := buildGetRequest(component, path)
+		code, _, _ := doGetRequest(req)
+		Expect(code).To(Equal(401))
+
+		req = buildGetRequest(component, path)
+		req.SetBasicAuth("username", "password")
+		code, _, body := doGetRequest(req)
+		Expect(code).To(Equal(202))
+		Expect(body).To(Equal("GET"))
+	})
+
 	It("updates the uptime statistic", func() {
 		stringMap := make(map[string]interface{})
 		path := "/varz"
@@ -1,6 +1,9 @@
 package http
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 const (
 	VcapBackendHeader     = "X-Vcap-Backend"
@@ -10,6 +13,7 @@ const (
 	CfInstanceIdHeader    = "X-CF-InstanceID"
 	CfAppInstance         = "X-CF-APP-INSTANCE"
 	CfRouterError         = "X-Cf-RouterError"
+	VcapRequestIdHeader   = "X-Vcap-Request-Id"
 )
 
 func SetTraceHeaders(responseWriter http.ResponseWriter, routerIp, addr string) {
@@ -17,3 +21,12 @@ func SetTraceHeaders(responseWriter http.ResponseWriter, routerIp, addr string)
 	responseWriter.Header().Set(VcapBackendHeader, addr)
 	responseWriter.Header().Set(CfRouteEndpointHeader, addr)
 }
+
+// HostWithoutPort removes the port, if any, from the Host header of a request
+func HostWithoutPort(host string) string {
+	pos := strings.Index(host, ":")
+	if pos >= 0 {
+		return host[0:pos]
+	}
+	return host
+}
@@ -3,15 +3,20 @@ package config
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	htmltemplate "html/template"
 	"net/url"
 
 	"io/ioutil"
 	"runtime"
 	"strings"
+	texttemplate "text/template"
 	"time"
 
 	"code.cloudfoundry.org/localip"
+	"golang.org/x/crypto/ed25519"
 	"gopkg.in/yaml.v2"
 )
 
@@ -24,11 +29,21 @@ const (
 	ALWAYS_FORWARD            string = "always_forward"
 	SANITIZE_SET              string = "sanitize_set"
 	FORWARD                   string = "forward"
+	SIGNING_HMAC_SHA256       string = "hmac-sha256"
+	SIGNING_ED25519           string = "ed25519"
+	DNS_SRV                   string = "srv"
+	DNS_A                     string = "a"
+	RATE_LIMIT_CLIENT_IP      string = "client_ip"
+	RATE_LIMIT_HEADER         string = "header"
+	RATE_LIMIT_ROUTE          string = "route"
 )
 
 var LoadBalancingStrategies = []string{LOAD_BALANCE_RR, LOAD_BALANCE_LC}
 var AllowedShardingModes = []string{SHARD_ALL, SHARD_SEGMENTS, SHARD_SHARED_AND_SEGMENTS}
 var AllowedForwardedClientCertModes = []string{ALWAYS_FORWARD, FORWARD, SANITIZE_SET}
+var AllowedSigningAlgorithms = []string{SIGNING_HMAC_SHA256, SIGNING_ED25519}
+var AllowedDNSRecordTypes = []string{DNS_SRV, DNS_A}
+var AllowedRateLimitKeys = []string{RATE_LIMIT_CLIENT_IP, RATE_LIMIT_HEADER, RATE_LIMIT_ROUTE}
 
 type StatusConfig struct {
 	Host string `yaml:"host"`
@@ -45,10 +60,15 @@ var defaultStatusConfig = StatusConfig{
 }
 
 type NatsConfig struct {
-	Host string `yaml:"host"`
-	Port uint16 `yaml:"port"`
-	User string `yaml:"user"`
-	Pass string `yaml:"pass"`
+	Host       string `yaml:"host"`
+	Port       uint16 `yaml:"port"`
+	User       string `yaml:"user"`
+	Pass       string `yaml:"pass"`
+	Token      string `yaml:"token"`
+	TLSEnabled bool   `yaml:"tls_enabled"`
+	CACerts    string `yaml:"ca_certs"`
+
+	TLSPem `yaml:",inline"` // embed to get cert_chain and private_key for client authentication
 }
 
 type RoutingApiConfig struct {
@@ -64,6 +84,28 @@ var defaultNatsConfig = NatsConfig{
 	Pass: "",
 }
 
+// NatsSigningKey is a key that route registration messages can be signed
+// with. HMAC keys use the secret, Ed25519 keys the base64 encoded public key.
+type NatsSigningKey struct {
+	Id        string `yaml:"id"`
+	Algorithm string `yaml:"algorithm"`
+	Secret    string `yaml:"secret"`
+	PublicKey string `yaml:"public_key"`
+	Key       []byte `yaml:"-"`
+}
+
+// NatsSigningConfig configures the keys of signed registration messages.
+// Signed messages older than max_age are rejected as replays.
+type NatsSigningConfig struct {
+	Keys     []NatsSigningKey `yaml:"keys"`
+	Required bool             `yaml:"required"`
+	MaxAge   time.Duration    `yaml:"max_age"`
+}
+
+var defaultNatsSigningConfig = NatsSigningConfig{
+	MaxAge: time.Minute,
+}
+
 type OAuthConfig struct {
 	TokenEndpoint     string `yaml:"token_endpoint"`
 	Port              int    `yaml:"port"`
@@ -76,10 +118,53 @@ type OAuthConfig struct {
 type BackendConfig struct {
 	ClientAuthCertificate tls.Certificate
 	EnableTLS             bool             `yaml:"enable_tls"`
-	MaxConns              int64            `yaml:"max_conns"`
+	ConnectionLimits      `yaml:",inline"` // embed to get max_conns, max_pending_requests and pending_timeout
+	RouteLimits           []RouteLimit     `yaml:"route_limits"`
 	TLSPem                `yaml:",inline"` // embed to get cert_chain and private_key for client authentication
 }
 
+// ConnectionLimits caps the requests in flight to every backend of a route at
+// max_conns. When all backends of a route are at the limit, up to
+// max_pending_requests requests wait for pending_timeout until a backend
+// finishes a request; others are rejected. Zero disables a limit.
+type ConnectionLimits struct {
+	MaxConns           int64         `yaml:"max_conns"`
+	MaxPendingRequests int           `yaml:"max_pending_requests"`
+	PendingTimeout     time.Duration `yaml:"pending_timeout"`
+}
+
+// RouteLimit overrides the connection limits of the backends for a route.
+// Limits that are not set are taken from the backends config.
+type RouteLimit struct {
+	Route            string `yaml:"route"`
+	ConnectionLimits `yaml:",inline"`
+}
+
+var defaultBackendConfig = BackendConfig{
+	ConnectionLimits: ConnectionLimits{PendingTimeout: 5 * time.Second},
+}
+
+// Limits returns the connection limits of a route, given as host and path.
+func (b BackendConfig) Limits(route string) ConnectionLimits {
+	route = strings.TrimSuffix(route, "/")
+	limits := b.ConnectionLimits
+	for _, l := range b.RouteLimits {
+		if !strings.EqualFold(strings.TrimSuffix(l.Route, "/"), route) {
+			continue
+		}
+		if l.MaxConns != 0 {
+			limits.MaxConns = l.MaxConns
+		}
+		if l.MaxPendingRequests != 0 {
+			limits.MaxPendingRequests = l.MaxPendingRequests
+		}
+		if l.PendingTimeout != 0 {
+			limits.PendingTimeout = l.PendingTimeout
+		}
+	}
+	return limits
+}
+
 type LoggingConfig struct {
 	Syslog             string `yaml:"syslog"`
 	Level              string `yaml:"level"`
@@ -99,6 +184,200 @@ type Tracing struct {
 	EnableZipkin bool `yaml:"enable_zipkin"`
 }
 
+type RouteSnapshotConfig struct {
+	File     string        `yaml:"file"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+var defaultRouteSnapshotConfig = RouteSnapshotConfig{
+	Interval: 30 * time.Second,
+}
+
+// RouteSourcesConfig configures the route sources besides NATS and the
+// routing API.
+type RouteSourcesConfig struct {
+	File FileRouteSourceConfig `yaml:"file"`
+	DNS  DNSRouteSourceConfig  `yaml:"dns"`
+}
+
+// FileRouteSourceConfig configures the routes file, which is read again every
+// interval.
+type FileRouteSourceConfig struct {
+	Path     string        `yaml:"path"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// DNSRouteSourceConfig configures the services that are resolved via DNS.
+// Services are resolved again every interval; after a failure they are
+// retried after min_interval, backing off up to interval.
+type DNSRouteSourceConfig struct {
+	Nameservers []string      `yaml:"nameservers"`
+	Interval    time.Duration `yaml:"interval"`
+	MinInterval time.Duration `yaml:"min_interval"`
+	Timeout     time.Duration `yaml:"timeout"`
+	Services    []DNSService  `yaml:"services"`
+}
+
+// DNSService registers the backends of a DNS name under a route. SRV records
+// carry the ports of the backends; A and AAAA records use the configured port.
+type DNSService struct {
+	Route               string `yaml:"route"`
+	Name                string `yaml:"name"`
+	Type                string `yaml:"type"`
+	Port                uint16 `yaml:"port"`
+	TLS                 bool   `yaml:"tls"`
+	ServerCertDomainSAN string `yaml:"server_cert_domain_san"`
+}
+
+var defaultRouteSourcesConfig = RouteSourcesConfig{
+	File: FileRouteSourceConfig{Interval: 5 * time.Second},
+	DNS: DNSRouteSourceConfig{
+		Interval:    30 * time.Second,
+		MinInterval: 1 * time.Second,
+		Timeout:     2 * time.Second,
+	},
+}
+
+// SlowStartConfig ramps up the share of traffic of endpoints added to a route
+// over the window, from min_weight to a full share. Aggression bends the ramp:
+// 1 is linear, higher values give new endpoints more traffic early on.
+type SlowStartConfig struct {
+	Window     time.Duration `yaml:"window"`
+	MinWeight  float64       `yaml:"min_weight"`
+	Aggression float64       `yaml:"aggression"`
+}
+
+var defaultSlowStartConfig = SlowStartConfig{
+	MinWeight:  0.1,
+	Aggression: 1,
+}
+
+// ZoneAwareRoutingConfig makes load balancing prefer the endpoints in the zone
+// of the router, which endpoints are tagged with in their registrations.
+// Requests spill over to all zones when the zone has fewer healthy endpoints
+// than min_healthy_endpoints, or when the requests in flight per healthy
+// endpoint of the zone reach max_load_per_endpoint. Zero disables the load
+// limit.
+type ZoneAwareRoutingConfig struct {
+	Enabled             bool   `yaml:"enabled"`
+	Tag                 string `yaml:"tag"`
+	MinHealthyEndpoints int    `yaml:"min_healthy_endpoints"`
+	MaxLoadPerEndpoint  int64  `yaml:"max_load_per_endpoint"`
+}
+
+var defaultZoneAwareRoutingConfig = ZoneAwareRoutingConfig{
+	Tag:                 "zone",
+	MinHealthyEndpoints: 1,
+}
+
+// RateLimit limits the requests to a route with a token bucket per client,
+// which holds burst requests and is refilled with requests_per_second. Clients
+// are told apart by key: their IP address, the value of a header, or not at
+// all for a single bucket per route. Zero requests per second disables the
+// limit.
+type RateLimit struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second" json:"requests_per_second"`
+	Burst             int     `yaml:"burst" json:"burst,omitempty"`
+	Key               string  `yaml:"key" json:"key,omitempty"`
+	Header            string  `yaml:"header" json:"header,omitempty"`
+}
+
+// Validate returns an error if the rate limit cannot be applied.
+func (l *RateLimit) Validate() error {
+	if l.RequestsPerSecond < 0 {
+		return fmt.Errorf("requests_per_second must not be negative: %v", l.RequestsPerSecond)
+	}
+	if l.Burst < 0 {
+		return fmt.Errorf("burst must not be negative: %d", l.Burst)
+	}
+	switch l.Key {
+	case "", RATE_LIMIT_CLIENT_IP, RATE_LIMIT_ROUTE:
+	case RATE_LIMIT_HEADER:
+		if l.Header == "" {
+			return fmt.Errorf("header is required for key header")
+		}
+	default:
+		return fmt.Errorf("key %q. Allowed values are %s", l.Key, AllowedRateLimitKeys)
+	}
+	return nil
+}
+
+// RateLimitingConfig applies the rate limit to all routes that are neither
+// listed in routes nor registered with a rate limit. TrustedProxies is the
+// number of proxies in front of the router that append the address of their
+// client to X-Forwarded-For; the client IP of a request is the address the
+// outermost of them saw. Without trusted proxies it is the address the
+// request came from. At most MaxClients token buckets are kept.
+type RateLimitingConfig struct {
+	RateLimit      `yaml:",inline"`
+	Routes         []RouteRateLimit `yaml:"routes"`
+	TrustedProxies int              `yaml:"trusted_proxies"`
+	MaxClients     int              `yaml:"max_clients"`
+}
+
+var defaultRateLimitingConfig = RateLimitingConfig{
+	MaxClients: 100000,
+}
+
+// RouteRateLimit sets the rate limit of a route, replacing the one it is
+// registered with.
+type RouteRateLimit struct {
+	Route     string `yaml:"route"`
+	RateLimit `yaml:",inline"`
+}
+
+// Route returns the rate limit configured for a route, given as host and
+// path, or nil if there is none.
+func (r RateLimitingConfig) Route(route string) *RateLimit {
+	route = strings.TrimSuffix(route, "/")
+	for i, l := range r.Routes {
+		if strings.EqualFold(strings.TrimSuffix(l.Route, "/"), route) {
+			return &r.Routes[i].RateLimit
+		}
+	}
+	return nil
+}
+
+// PruneProtectionConfig limits how many endpoints a single pruning cycle may
+// remove. Zero disables a limit.
+type PruneProtectionConfig struct {
+	MaxPercent   int `yaml:"max_percent"`
+	MaxEndpoints int `yaml:"max_endpoints"`
+}
+
+// DomainOwnershipRule restricts the routes of a domain and its subdomains to
+// the endpoints of the isolation segments and apps listed.
+type DomainOwnershipRule struct {
+	Domain            string   `yaml:"domain"`
+	IsolationSegments []string `yaml:"isolation_segments"`
+	AppIds            []string `yaml:"app_ids"`
+}
+
+type DomainOwnershipConfig struct {
+	Rules          []DomainOwnershipRule `yaml:"rules"`
+	File           string                `yaml:"file"`
+	ReloadInterval time.Duration         `yaml:"reload_interval"`
+}
+
+var defaultDomainOwnershipConfig = DomainOwnershipConfig{
+	ReloadInterval: 30 * time.Second,
+}
+
+type ErrorPage struct {
+	StatusCode int    `yaml:"status_code"`
+	Domain     string `yaml:"domain"`
+	HTML       string `yaml:"html"`
+	JSON       string `yaml:"json"`
+}
+
+// ErrorPageJSONFuncs are the functions available to json error page templates
+var ErrorPageJSONFuncs = texttemplate.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
 type TLSPem struct {
 	CertChain  string `yaml:"cert_chain"`
 	PrivateKey string `yaml:"private_key"`
@@ -112,6 +391,8 @@ var defaultLoggingConfig = LoggingConfig{
 type Config struct {
 	Status                   StatusConfig      `yaml:"status,omitempty"`
 	Nats                     []NatsConfig      `yaml:"nats,omitempty"`
+	NatsSigning              NatsSigningConfig `yaml:"nats_signing,omitempty"`
+	NatsTLSConfig            *tls.Config       `yaml:"-"`
 	Logging                  LoggingConfig     `yaml:"logging,omitempty"`
 	Port                     uint16            `yaml:"port,omitempty"`
 	Index                    uint              `yaml:"index,omitempty"`
@@ -156,6 +437,13 @@ type Config struct {
 	RouteServiceTimeout             time.Duration `yaml:"route_services_timeout,omitempty"`
 	FrontendIdleTimeout             time.Duration `yaml:"frontend_idle_timeout,omitempty"`
 
+	// EndpointDrainTimeout keeps unregistered endpoints for requests in
+	// flight, unless the unregistration sets its own drain timeout
+	EndpointDrainTimeout time.Duration `yaml:"endpoint_drain_timeout,omitempty"`
+
+	PruneProtection PruneProtectionConfig `yaml:"prune_protection,omitempty"`
+	DomainOwnership DomainOwnershipConfig `yaml:"domain_ownership,omitempty"`
+
 	RouteLatencyMetricMuzzleDuration time.Duration `yaml:"route_latency_metric_muzzle_duration,omitempty"`
 
 	DrainWait            time.Duration `yaml:"drain_wait,omitempty"`
@@ -175,13 +463,25 @@ type Config struct {
 	NatsClientMessageBufferSize int           `yaml:"-"`
 	Backends                    BackendConfig `yaml:"backends,omitempty"`
 	ExtraHeadersToLog           []string      `yaml:"extra_headers_to_log,omitempty"`
+	ErrorPages                  []ErrorPage   `yaml:"error_pages,omitempty"`
+
+	RouteSnapshot RouteSnapshotConfig `yaml:"route_snapshot,omitempty"`
+	RouteSources  RouteSourcesConfig  `yaml:"route_sources,omitempty"`
+
+	// SourcePrecedence decides whose version of an endpoint is routed to when
+	// several sources (nats, routing-api or a route source) register the same
+	// address for a route. Sources without a precedence have precedence 0.
+	SourcePrecedence map[string]int `yaml:"source_precedence,omitempty"`
 
 	TokenFetcherMaxRetries                    uint32        `yaml:"token_fetcher_max_retries,omitempty"`
 	TokenFetcherRetryInterval                 time.Duration `yaml:"token_fetcher_retry_interval,omitempty"`
 	TokenFetcherExpirationBufferTimeInSeconds int64         `yaml:"token_fetcher_expiration_buffer_time,omitempty"`
 
-	PidFile     string `yaml:"pid_file,omitempty"`
-	LoadBalance string `yaml:"balancing_algorithm,omitempty"`
+	PidFile          string                 `yaml:"pid_file,omitempty"`
+	LoadBalance      string                 `yaml:"balancing_algorithm,omitempty"`
+	SlowStart        SlowStartConfig        `yaml:"slow_start,omitempty"`
+	ZoneAwareRouting ZoneAwareRoutingConfig `yaml:"zone_aware_routing,omitempty"`
+	RateLimiting     RateLimitingConfig     `yaml:"rate_limiting,omitempty"`
 
 	DisableKeepAlives   bool `yaml:"disable_keep_alives,omitempty"`
 	MaxIdleConns        int  `yaml:"max_idle_conns,omitempty"`
@@ -192,6 +492,8 @@ var defaultConfig = Config{
 	Status:        defaultStatusConfig,
 	Nats:          []NatsConfig{defaultNatsConfig},
 	Logging:       defaultLoggingConfig,
+	RouteSnapshot: defaultRouteSnapshotConfig,
+	RouteSources:  defaultRouteSourcesConfig,
 	Port:          8081,
 	Index:         0,
 	GoMaxProcs:    -1,
@@ -215,6 +517,10 @@ var defaultConfig = Config{
 	TokenFetcherExpirationBufferTimeInSeconds: 30,
 	FrontendIdleTimeout:                       900 * time.Second,
 	RouteLatencyMetricMuzzleDuration:          20 * time.Second,
+	DomainOwnership:                           defaultDomainOwnershipConfig,
+	Backends:                                  defaultBackendConfig,
+	NatsSigning:                               defaultNatsSigningConfig,
+	RateLimiting:                              defaultRateLimitingConfig,
 
 	// To avoid routes getting purged because of unresponsive NATS server
 	// we need to set the ping interval of nats client such that it fails over
@@ -227,6 +533,8 @@ var defaultConfig = Config{
 
 	HealthCheckUserAgent: "HTTP-Monitor/1.1",
 	LoadBalance:          LOAD_BALANCE_RR,
+	SlowStart:            defaultSlowStartConfig,
+	ZoneAwareRouting:     defaultZoneAwareRoutingConfig,
 
 	ForwardedClientCert:      "always_forward",
 	RoutingTableShardingMode: "all",
@@ -348,6 +656,24 @@ func (c *Config) Process() error {
 		errMsg := fmt.Sprintf("Invalid load balancer healthy threshold: %s", c.LoadBalancerHealthyThreshold)
 		return fmt.Errorf(errMsg)
 	}
+	if c.SlowStart.Window < 0 {
+		return fmt.Errorf("Invalid slow start window: %s", c.SlowStart.Window)
+	}
+	if c.SlowStart.MinWeight < 0 || c.SlowStart.MinWeight > 1 {
+		return fmt.Errorf("Invalid slow start min_weight: %v. Must be between 0 and 1", c.SlowStart.MinWeight)
+	}
+	if c.SlowStart.Aggression <= 0 {
+		return fmt.Errorf("Invalid slow start aggression: %v", c.SlowStart.Aggression)
+	}
+	if err := c.processZoneAwareRouting(); err != nil {
+		return err
+	}
+	if err := c.processConnectionLimits(); err != nil {
+		return err
+	}
+	if err := c.processRateLimiting(); err != nil {
+		return err
+	}
 
 	validForwardedClientCertMode := false
 	for _, fm := range AllowedForwardedClientCertModes {
@@ -377,12 +703,262 @@ func (c *Config) Process() error {
 		return fmt.Errorf("Expected isolation segments; routing table sharding mode set to segments and none provided.")
 	}
 
+	if c.RouteSnapshot.File != "" && c.RouteSnapshot.Interval <= 0 {
+		return fmt.Errorf("Invalid route snapshot interval: %s", c.RouteSnapshot.Interval)
+	}
+
+	if c.RouteSources.File.Path != "" && c.RouteSources.File.Interval <= 0 {
+		return fmt.Errorf("Invalid file route source interval: %s", c.RouteSources.File.Interval)
+	}
+
+	if c.PruneProtection.MaxPercent < 0 || c.PruneProtection.MaxPercent > 100 {
+		return fmt.Errorf("Invalid prune protection max percent: %d", c.PruneProtection.MaxPercent)
+	}
+
+	if c.EndpointDrainTimeout < 0 {
+		return fmt.Errorf("Invalid endpoint drain timeout: %s", c.EndpointDrainTimeout)
+	}
+
+	if c.PruneProtection.MaxEndpoints < 0 {
+		return fmt.Errorf("Invalid prune protection max endpoints: %d", c.PruneProtection.MaxEndpoints)
+	}
+
+	if c.DomainOwnership.File != "" && c.DomainOwnership.ReloadInterval <= 0 {
+		return fmt.Errorf("Invalid domain ownership reload interval: %s", c.DomainOwnership.ReloadInterval)
+	}
+
+	if err := c.processDNSRouteSource(); err != nil {
+		return err
+	}
+
+	if err := c.processNatsTLS(); err != nil {
+		return err
+	}
+
+	if err := c.processNatsSigningKeys(); err != nil {
+		return err
+	}
+
+	if err := c.processErrorPages(); err != nil {
+		return err
+	}
+
 	if err := c.buildCertPool(); err != nil {
 		return err
 	}
 	return nil
 }
 
+func (c *Config) processZoneAwareRouting() error {
+	z := c.ZoneAwareRouting
+	if !z.Enabled {
+		return nil
+	}
+	if c.Zone == "" {
+		return fmt.Errorf("Zone aware routing requires the zone of the router")
+	}
+	if z.Tag == "" {
+		return fmt.Errorf("Invalid zone aware routing tag: the tag is required")
+	}
+	if z.MinHealthyEndpoints < 1 {
+		return fmt.Errorf("Invalid zone aware routing min_healthy_endpoints: %d", z.MinHealthyEndpoints)
+	}
+	if z.MaxLoadPerEndpoint < 0 {
+		return fmt.Errorf("Invalid zone aware routing max_load_per_endpoint: %d", z.MaxLoadPerEndpoint)
+	}
+	return nil
+}
+
+func (c *Config) processConnectionLimits() error {
+	if err := validateConnectionLimits("", c.Backends.ConnectionLimits); err != nil {
+		return err
+	}
+	for _, l := range c.Backends.RouteLimits {
+		if l.Route == "" {
+			return fmt.Errorf("Invalid route limit: the route is required")
+		}
+		if err := validateConnectionLimits(" of route "+l.Route, l.ConnectionLimits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) processRateLimiting() error {
+	if err := c.RateLimiting.Validate(); err != nil {
+		return fmt.Errorf("Invalid rate limit: %s", err)
+	}
+	if c.RateLimiting.TrustedProxies < 0 {
+		return fmt.Errorf("Invalid rate limiting trusted_proxies: %d", c.RateLimiting.TrustedProxies)
+	}
+	if c.RateLimiting.MaxClients <= 0 {
+		return fmt.Errorf("Invalid rate limiting max_clients: %d", c.RateLimiting.MaxClients)
+	}
+	for _, l := range c.RateLimiting.Routes {
+		if l.Route == "" {
+			return fmt.Errorf("Invalid rate limit: the route is required")
+		}
+		if err := l.Validate(); err != nil {
+			return fmt.Errorf("Invalid rate limit of route %s: %s", l.Route, err)
+		}
+	}
+	return nil
+}
+
+func validateConnectionLimits(of string, l ConnectionLimits) error {
+	if l.MaxConns < 0 {
+		return fmt.Errorf("Invalid backends max_conns%s: %d", of, l.MaxConns)
+	}
+	if l.MaxPendingRequests < 0 {
+		return fmt.Errorf("Invalid backends max_pending_requests%s: %d", of, l.MaxPendingRequests)
+	}
+	if l.PendingTimeout < 0 {
+		return fmt.Errorf("Invalid backends pending_timeout%s: %s", of, l.PendingTimeout)
+	}
+	return nil
+}
+
+func (c *Config) processNatsTLS() error {
+	tlsServers := 0
+	for _, n := range c.Nats {
+		if n.Token != "" && n.User != "" {
+			return fmt.Errorf("Invalid NATS config for %s: user and token cannot both be set", n.Host)
+		}
+		if n.TLSEnabled {
+			tlsServers++
+		}
+	}
+	if tlsServers == 0 {
+		return nil
+	}
+	if tlsServers != len(c.Nats) {
+		return fmt.Errorf("Invalid NATS config: TLS must be enabled for all servers or none")
+	}
+
+	// the NATS client uses one TLS config for all servers of the cluster
+	n := c.Nats[0]
+	for _, other := range c.Nats[1:] {
+		if other.CACerts != n.CACerts || other.TLSPem != n.TLSPem {
+			return fmt.Errorf("Invalid NATS config for %s: ca_certs, cert_chain and private_key must be the same for all servers", other.Host)
+		}
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if n.CACerts != "" {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if ok := tlsConfig.RootCAs.AppendCertsFromPEM([]byte(n.CACerts)); !ok {
+			return fmt.Errorf("Error while adding CA certs of NATS server %s", n.Host)
+		}
+	}
+
+	if n.CertChain != "" || n.PrivateKey != "" {
+		certificate, err := tls.X509KeyPair([]byte(n.CertChain), []byte(n.PrivateKey))
+		if err != nil {
+			return fmt.Errorf("Error loading key pair of NATS server %s: %s", n.Host, err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	c.NatsTLSConfig = tlsConfig
+	return nil
+}
+
+func (c *Config) processNatsSigningKeys() error {
+	if c.NatsSigning.Required && len(c.NatsSigning.Keys) == 0 {
+		return fmt.Errorf("Expected NATS signing keys; signed messages are required and none provided.")
+	}
+	if len(c.NatsSigning.Keys) > 0 && c.NatsSigning.MaxAge <= 0 {
+		return fmt.Errorf("Invalid NATS signing max age: %s", c.NatsSigning.MaxAge)
+	}
+
+	seen := map[string]bool{}
+	for i, k := range c.NatsSigning.Keys {
+		if k.Id == "" {
+			return fmt.Errorf("Invalid NATS signing key: missing id")
+		}
+		if seen[k.Id] {
+			return fmt.Errorf("Duplicate NATS signing key: %s", k.Id)
+		}
+		seen[k.Id] = true
+
+		switch k.Algorithm {
+		case SIGNING_HMAC_SHA256:
+			if k.Secret == "" {
+				return fmt.Errorf("Invalid NATS signing key %s: missing secret", k.Id)
+			}
+			c.NatsSigning.Keys[i].Key = []byte(k.Secret)
+		case SIGNING_ED25519:
+			key, err := base64.StdEncoding.DecodeString(k.PublicKey)
+			if err != nil || len(key) != ed25519.PublicKeySize {
+				return fmt.Errorf("Invalid NATS signing key %s: public_key must be a base64 encoded Ed25519 public key", k.Id)
+			}
+			c.NatsSigning.Keys[i].Key = key
+		default:
+			return fmt.Errorf("Invalid NATS signing key %s: algorithm %q. Allowed values are %s", k.Id, k.Algorithm, AllowedSigningAlgorithms)
+		}
+	}
+	return nil
+}
+
+func (c *Config) processDNSRouteSource() error {
+	dns := &c.RouteSources.DNS
+	if len(dns.Services) == 0 {
+		return nil
+	}
+
+	if dns.MinInterval <= 0 || dns.Interval < dns.MinInterval {
+		return fmt.Errorf("Invalid DNS route source intervals: interval %s, min_interval %s", dns.Interval, dns.MinInterval)
+	}
+	if dns.Timeout <= 0 {
+		return fmt.Errorf("Invalid DNS route source timeout: %s", dns.Timeout)
+	}
+
+	for i, s := range dns.Services {
+		if s.Route == "" || s.Name == "" {
+			return fmt.Errorf("Invalid DNS route source service: route and name are required")
+		}
+
+		switch s.Type {
+		case "":
+			dns.Services[i].Type = DNS_SRV
+		case DNS_SRV:
+		case DNS_A:
+			if s.Port == 0 {
+				return fmt.Errorf("Invalid DNS route source service %s: port is required for type a", s.Name)
+			}
+		default:
+			return fmt.Errorf("Invalid DNS route source service %s: type %q. Allowed values are %s", s.Name, s.Type, AllowedDNSRecordTypes)
+		}
+	}
+	return nil
+}
+
+func (c *Config) processErrorPages() error {
+	seen := map[string]bool{}
+	for i, p := range c.ErrorPages {
+		if p.StatusCode != 0 && (p.StatusCode < 400 || p.StatusCode > 599) {
+			return fmt.Errorf("Invalid error page status code: %d. Must be between 400 and 599, or omitted for all errors", p.StatusCode)
+		}
+		if p.HTML == "" && p.JSON == "" {
+			return fmt.Errorf("Error page %d must provide an html or json template", i)
+		}
+		if _, err := htmltemplate.New("").Parse(p.HTML); err != nil {
+			return fmt.Errorf("Invalid error page %d html template: %s", i, err)
+		}
+		if _, err := texttemplate.New("").Funcs(ErrorPageJSONFuncs).Parse(p.JSON); err != nil {
+			return fmt.Errorf("Invalid error page %d json template: %s", i, err)
+		}
+
+		p.Domain = strings.ToLower(strings.TrimPrefix(p.Domain, "*."))
+		key := fmt.Sprintf("%d %s", p.StatusCode, p.Domain)
+		if seen[key] {
+			return fmt.Errorf("Duplicate error page for status code %d and domain '%s'", p.StatusCode, p.Domain)
+		}
+		seen[key] = true
+		c.ErrorPages[i] = p
+	}
+	return nil
+}
+
 func (c *Config) processCipherSuites() ([]uint16, error) {
 	cipherMap := map[string]uint16{
 		"RC4-SHA":                                 0x0005, // openssl formatted values
@@ -483,12 +1059,22 @@ func (c *Config) NatsServers() []string {
 			User:   url.UserPassword(info.User, info.Pass),
 			Host:   fmt.Sprintf("%s:%d", info.Host, info.Port),
 		}
+		if info.Token != "" {
+			uri.User = url.User(info.Token)
+		}
 		natsServers = append(natsServers, uri.String())
 	}
 
 	return natsServers
 }
 
+// NatsEnabled reports whether NATS servers are configured. Only the default
+// config has a server: a config file that lists none, or `nats: []`, disables
+// NATS, and routes then only come from the routing API and the route sources.
+func (c *Config) NatsEnabled() bool {
+	return len(c.Nats) > 0
+}
+
 func (c *Config) RoutingApiEnabled() bool {
 	return (c.RoutingApi.Uri != "") && (c.RoutingApi.Port != 0)
 }
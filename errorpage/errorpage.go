@@ -0,0 +1,171 @@
+package errorpage
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"net/http"
+	"strings"
+	texttemplate "text/template"
+
+	router_http "code.cloudfoundry.org/gorouter/common/http"
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/logger"
+	"github.com/uber-go/zap"
+)
+
+const (
+	htmlContentType = "text/html; charset=utf-8"
+	jsonContentType = "application/json"
+)
+
+// Data is the value the error page templates are rendered with
+type Data struct {
+	StatusCode  int
+	StatusText  string
+	Message     string
+	RouterError string
+	RequestID   string
+	Host        string
+}
+
+type template interface {
+	Execute(io.Writer, interface{}) error
+}
+
+type page struct {
+	html template
+	json template
+}
+
+type pageKey struct {
+	statusCode int
+	domain     string
+}
+
+// Pages renders the configured error pages for router-generated errors.
+// A nil *Pages renders nothing.
+type Pages struct {
+	logger logger.Logger
+	pages  map[pageKey]*page
+}
+
+func NewPages(logger logger.Logger, configs []config.ErrorPage) (*Pages, error) {
+	p := &Pages{
+		logger: logger,
+		pages:  make(map[pageKey]*page),
+	}
+
+	for _, c := range configs {
+		name := fmt.Sprintf("%d-%s", c.StatusCode, c.Domain)
+		pg := &page{}
+
+		if c.HTML != "" {
+			t, err := htmltemplate.New(name).Parse(c.HTML)
+			if err != nil {
+				return nil, err
+			}
+			pg.html = t
+		}
+
+		if c.JSON != "" {
+			t, err := texttemplate.New(name).Funcs(config.ErrorPageJSONFuncs).Parse(c.JSON)
+			if err != nil {
+				return nil, err
+			}
+			pg.json = t
+		}
+
+		p.pages[pageKey{statusCode: c.StatusCode, domain: c.Domain}] = pg
+	}
+
+	return p, nil
+}
+
+// Write renders the error page configured for the status code and the
+// request's domain. It returns false, having written nothing, when no page
+// applies so that the caller can fall back to its plain text response.
+func (p *Pages) Write(rw http.ResponseWriter, r *http.Request, code int, message string) bool {
+	if p == nil || len(p.pages) == 0 {
+		return false
+	}
+
+	host := strings.ToLower(router_http.HostWithoutPort(r.Host))
+	pg := p.find(code, host)
+	if pg == nil {
+		return false
+	}
+
+	contentType, tmpl := pg.negotiate(r.Header.Get("Accept"))
+
+	data := Data{
+		StatusCode:  code,
+		StatusText:  http.StatusText(code),
+		Message:     message,
+		RouterError: rw.Header().Get(router_http.CfRouterError),
+		RequestID:   r.Header.Get(router_http.VcapRequestIdHeader),
+		Host:        host,
+	}
+
+	var body bytes.Buffer
+	err := tmpl.Execute(&body, data)
+	if err != nil {
+		p.logger.Error("error-page-render-failed", zap.Int("status-code", code), zap.Error(err))
+		return false
+	}
+
+	rw.Header().Set("Content-Type", contentType)
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.WriteHeader(code)
+	rw.Write(body.Bytes())
+	return true
+}
+
+// find prefers the most specific domain, and within a domain a page for the
+// exact status code over the catch-all page.
+func (p *Pages) find(code int, host string) *page {
+	domain := host
+	for {
+		if pg, ok := p.pages[pageKey{statusCode: code, domain: domain}]; ok {
+			return pg
+		}
+		if pg, ok := p.pages[pageKey{domain: domain}]; ok {
+			return pg
+		}
+		if domain == "" {
+			return nil
+		}
+
+		pos := strings.Index(domain, ".")
+		if pos < 0 {
+			domain = ""
+		} else {
+			domain = domain[pos+1:]
+		}
+	}
+}
+
+// negotiate picks the first media type in the Accept header that the page
+// has a template for; quality values are not taken into account. HTML is
+// preferred when nothing matches.
+func (pg *page) negotiate(accept string) (string, template) {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(mediaRange, ";", 2)[0])
+		switch strings.ToLower(mediaType) {
+		case "application/json":
+			if pg.json != nil {
+				return jsonContentType, pg.json
+			}
+		case "text/html":
+			if pg.html != nil {
+				return htmlContentType, pg.html
+			}
+		}
+	}
+
+	if pg.html != nil {
+		return htmlContentType, pg.html
+	}
+	return jsonContentType, pg.json
+}
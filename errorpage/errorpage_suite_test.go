@@ -0,0 +1,13 @@
+package errorpage_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestErrorPage(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ErrorPage Suite")
+}
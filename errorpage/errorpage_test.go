@@ -0,0 +1,115 @@
+package errorpage_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	router_http "code.cloudfoundry.org/gorouter/common/http"
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/errorpage"
+	"code.cloudfoundry.org/gorouter/test_util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pages", func() {
+	var (
+		pages   *errorpage.Pages
+		configs []config.ErrorPage
+		resp    *httptest.ResponseRecorder
+		req     *http.Request
+	)
+
+	BeforeEach(func() {
+		configs = []config.ErrorPage{
+			{
+				StatusCode: 404,
+				HTML:       `<h1>{{.StatusCode}} {{.StatusText}}</h1><p>{{.Message}}</p><p>{{.Host}} {{.RequestID}}</p>`,
+				JSON:       `{"error":{{json .RouterError}},"request_id":{{json .RequestID}}}`,
+			},
+			{
+				HTML: `<h1>Something went wrong</h1>`,
+			},
+			{
+				StatusCode: 404,
+				Domain:     "example.com",
+				HTML:       `<h1>example.com: not found</h1>`,
+			},
+		}
+		resp = httptest.NewRecorder()
+		req = httptest.NewRequest("GET", "http://app.other.com:8080/foo", nil)
+		req.Header.Set(router_http.VcapRequestIdHeader, "some-request-id")
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		pages, err = errorpage.NewPages(test_util.NewTestZapLogger("error-pages"), configs)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("renders the html page for the status code", func() {
+		Expect(pages.Write(resp, req, http.StatusNotFound, "<no route>")).To(BeTrue())
+
+		Expect(resp.Code).To(Equal(http.StatusNotFound))
+		Expect(resp.Header().Get("Content-Type")).To(Equal("text/html; charset=utf-8"))
+		Expect(resp.Body.String()).To(Equal(`<h1>404 Not Found</h1><p>&lt;no route&gt;</p><p>app.other.com some-request-id</p>`))
+	})
+
+	It("renders the json page when the client accepts json", func() {
+		req.Header.Set("Accept", "application/json, text/html;q=0.9")
+		resp.Header().Set(router_http.CfRouterError, "unknown_route")
+
+		Expect(pages.Write(resp, req, http.StatusNotFound, "no route")).To(BeTrue())
+
+		Expect(resp.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(resp.Body.String()).To(Equal(`{"error":"unknown_route","request_id":"some-request-id"}`))
+	})
+
+	It("falls back to the html page when there is no json template", func() {
+		req.Header.Set("Accept", "application/json")
+
+		Expect(pages.Write(resp, req, http.StatusBadGateway, "bad gateway")).To(BeTrue())
+
+		Expect(resp.Header().Get("Content-Type")).To(Equal("text/html; charset=utf-8"))
+		Expect(resp.Body.String()).To(Equal(`<h1>Something went wrong</h1>`))
+	})
+
+	It("prefers the page for the request's domain", func() {
+		req.Host = "app.example.com"
+
+		Expect(pages.Write(resp, req, http.StatusNotFound, "no route")).To(BeTrue())
+		Expect(resp.Body.String()).To(Equal(`<h1>example.com: not found</h1>`))
+	})
+
+	Context("when no page applies", func() {
+		BeforeEach(func() {
+			configs = configs[:1]
+		})
+
+		It("writes nothing", func() {
+			Expect(pages.Write(resp, req, http.StatusBadGateway, "bad gateway")).To(BeFalse())
+			Expect(resp.Body.Len()).To(BeZero())
+		})
+	})
+
+	Context("when the template fails to render", func() {
+		BeforeEach(func() {
+			configs = []config.ErrorPage{{HTML: `{{.Missing}}`}}
+		})
+
+		It("writes nothing", func() {
+			Expect(pages.Write(resp, req, http.StatusBadGateway, "bad gateway")).To(BeFalse())
+			Expect(resp.Body.Len()).To(BeZero())
+		})
+	})
+
+	It("returns an error for an invalid template", func() {
+		_, err := errorpage.NewPages(test_util.NewTestZapLogger("error-pages"), []config.ErrorPage{{HTML: `{{.Foo`}})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("writes nothing when there are no pages", func() {
+		var nilPages *errorpage.Pages
+		Expect(nilPages.Write(resp, req, http.StatusNotFound, "no route")).To(BeFalse())
+	})
+})
@@ -8,6 +8,7 @@ import (
 
 	"code.cloudfoundry.org/gorouter/access_log"
 	"code.cloudfoundry.org/gorouter/access_log/schema"
+	router_http "code.cloudfoundry.org/gorouter/common/http"
 	"code.cloudfoundry.org/gorouter/logger"
 	"code.cloudfoundry.org/gorouter/proxy/utils"
 
@@ -60,6 +61,7 @@ func (a *accessLog) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http
 	alr.BodyBytesSent = proxyWriter.Size()
 	alr.FinishedAt = time.Now()
 	alr.StatusCode = proxyWriter.Status()
+	alr.RouterError = proxyWriter.Header().Get(router_http.CfRouterError)
 	a.accessLogger.Log(*alr)
 }
 
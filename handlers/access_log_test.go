@@ -142,6 +142,30 @@ var _ = Describe("AccessLog", func() {
 		})
 	})
 
+	Context("when the router responds with an error", func() {
+		BeforeEach(func() {
+			routerErrorHandler := negroni.HandlerFunc(func(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+				rw.Header().Set("X-Cf-RouterError", "maintenance_mode")
+				next(rw, req)
+			})
+
+			handler = negroni.New()
+			handler.Use(handlers.NewRequestInfo())
+			handler.Use(handlers.NewProxyWriter(fakeLogger))
+			handler.Use(handlers.NewAccessLog(accessLogger, extraHeadersToLog, fakeLogger))
+			handler.Use(routerErrorHandler)
+			handler.Use(nextHandler)
+		})
+		It("records the router error", func() {
+			handler.ServeHTTP(resp, req)
+
+			Expect(accessLogger.LogCallCount()).To(Equal(1))
+
+			alr := accessLogger.LogArgsForCall(0)
+			Expect(alr.RouterError).To(Equal("maintenance_mode"))
+		})
+	})
+
 	Context("when request info is not set on the request context", func() {
 		BeforeEach(func() {
 			handler = negroni.New()
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/gorouter/errorpage"
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/metrics"
+	"github.com/uber-go/zap"
+	"github.com/urfave/negroni"
+)
+
+type connectionLimit struct {
+	reporter   metrics.ProxyReporter
+	logger     logger.Logger
+	errorPages *errorpage.Pages
+}
+
+// NewConnectionLimit creates a handler that makes requests wait for a backend
+// below the connection limits of their route.
+func NewConnectionLimit(rep metrics.ProxyReporter, logger logger.Logger, errorPages *errorpage.Pages) negroni.Handler {
+	return &connectionLimit{
+		reporter:   rep,
+		logger:     logger,
+		errorPages: errorPages,
+	}
+}
+
+func (h *connectionLimit) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	reqInfo, err := ContextRequestInfo(r)
+	if err != nil {
+		h.logger.Fatal("request-info-err", zap.Error(err))
+		return
+	}
+	if reqInfo.RoutePool == nil {
+		h.logger.Fatal("request-info-err", zap.Error(errors.New("failed-to-access-RoutePool")))
+		return
+	}
+
+	if err := reqInfo.RoutePool.AwaitCapacity(r.Context()); err != nil {
+		h.handleOverloadedRoute(rw, r, err)
+		return
+	}
+	next(rw, r)
+}
+
+func (h *connectionLimit) handleOverloadedRoute(rw http.ResponseWriter, r *http.Request, err error) {
+	h.reporter.CaptureBackendExhaustedConns()
+	h.logger.Info("connection-limit-reached", zap.Error(err))
+
+	rw.Header().Set("X-Cf-RouterError", "Connection Limit Reached")
+
+	writeStatus(
+		rw,
+		r,
+		http.StatusServiceUnavailable,
+		fmt.Sprintf("Requested route ('%s') has reached the connection limit.", r.Host),
+		h.errorPages,
+		h.logger,
+	)
+}
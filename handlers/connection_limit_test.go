@@ -0,0 +1,144 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/handlers"
+	logger_fakes "code.cloudfoundry.org/gorouter/logger/fakes"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/urfave/negroni"
+)
+
+var _ = Describe("ConnectionLimit", func() {
+	var (
+		handler      *negroni.Negroni
+		logger       *logger_fakes.FakeLogger
+		rep          *fakes.FakeCombinedReporter
+		resp         *httptest.ResponseRecorder
+		req          *http.Request
+		pool         *route.Pool
+		limits       config.ConnectionLimits
+		testEndpoint *route.Endpoint
+		nextCalled   bool
+	)
+
+	nextHandler := http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		nextCalled = true
+	})
+
+	testSetupHandler := func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		reqInfo, err := handlers.ContextRequestInfo(r)
+		Expect(err).ToNot(HaveOccurred())
+		reqInfo.RoutePool = pool
+		next(rw, r)
+	}
+
+	BeforeEach(func() {
+		nextCalled = false
+		logger = new(logger_fakes.FakeLogger)
+		rep = &fakes.FakeCombinedReporter{}
+		req = test_util.NewRequest("GET", "example.com", "/", nil)
+		resp = httptest.NewRecorder()
+
+		limits = config.ConnectionLimits{MaxConns: 2}
+		pool = route.NewPool(2*time.Minute, "example.com", "/")
+		testEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "1.3.5.6", Port: 5679})
+		testEndpoint.Stats.NumberConnections.Increment()
+		testEndpoint.Stats.NumberConnections.Increment()
+		testEndpoint.Stats.NumberConnections.Increment()
+		pool.Put(testEndpoint)
+		testEndpoint1 := route.NewEndpoint(&route.EndpointOpts{Host: "1.4.6.7", Port: 5679})
+		testEndpoint1.Stats.NumberConnections.Increment()
+		testEndpoint1.Stats.NumberConnections.Increment()
+		testEndpoint1.Stats.NumberConnections.Increment()
+		pool.Put(testEndpoint1)
+	})
+
+	JustBeforeEach(func() {
+		handler = negroni.New()
+		handler.Use(handlers.NewRequestInfo())
+		handler.UseFunc(testSetupHandler)
+		handler.Use(handlers.NewConnectionLimit(rep, logger, nil))
+		handler.UseHandler(nextHandler)
+		handler.ServeHTTP(resp, req)
+	})
+
+	Context("when the route has no connection limits", func() {
+		It("calls next", func() {
+			Expect(nextCalled).To(BeTrue())
+			Expect(resp.Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("when conn limit is reached for all endpoints and requests do not queue", func() {
+		BeforeEach(func() {
+			pool.SetConnectionLimits(limits)
+		})
+
+		It("returns a 503", func() {
+			Expect(nextCalled).To(BeFalse())
+			Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(resp.Header().Get("X-Cf-RouterError")).To(Equal("Connection Limit Reached"))
+			Expect(resp.Body.String()).To(ContainSubstring("Requested route ('example.com') has reached the connection limit."))
+		})
+
+		It("increments the backend_exhausted_conn metric", func() {
+			Expect(rep.CaptureBackendExhaustedConnsCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when conn limit is reached for all endpoints and requests queue", func() {
+		BeforeEach(func() {
+			limits.MaxPendingRequests = 1
+			limits.PendingTimeout = 50 * time.Millisecond
+			pool.SetConnectionLimits(limits)
+		})
+
+		It("returns a 503 once the pending timeout expires", func() {
+			Expect(nextCalled).To(BeFalse())
+			Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(rep.CaptureBackendExhaustedConnsCallCount()).To(Equal(1))
+		})
+
+		Context("when a backend finishes a request", func() {
+			BeforeEach(func() {
+				iter := pool.Endpoints("", "")
+				time.AfterFunc(10*time.Millisecond, func() {
+					iter.PostRequest(testEndpoint)
+					iter.PostRequest(testEndpoint)
+				})
+				limits.PendingTimeout = time.Minute
+				pool.SetConnectionLimits(limits)
+			})
+
+			It("calls next", func() {
+				Expect(nextCalled).To(BeTrue())
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(pool.Endpoints("", "").Next()).To(Equal(testEndpoint))
+			})
+		})
+	})
+
+	Context("when request info is not set on the request context", func() {
+		BeforeEach(func() {
+			pool = nil
+		})
+
+		It("calls Fatal on the logger", func() {
+			handler = negroni.New()
+			handler.Use(handlers.NewConnectionLimit(rep, logger, nil))
+			handler.UseHandler(nextHandler)
+			handler.ServeHTTP(httptest.NewRecorder(), test_util.NewRequest("GET", "example.com", "/", nil))
+
+			Expect(logger.FatalCallCount()).To(Equal(2))
+		})
+	})
+})
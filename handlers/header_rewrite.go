@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"code.cloudfoundry.org/gorouter/logger"
+	"github.com/uber-go/zap"
+	"github.com/urfave/negroni"
+)
+
+type headerRewrite struct {
+	logger logger.Logger
+}
+
+// NewHeaderRewrite creates a handler responsible for applying the request
+// header rules of the route to requests sent to its backends
+func NewHeaderRewrite(logger logger.Logger) negroni.Handler {
+	return &headerRewrite{
+		logger: logger,
+	}
+}
+
+func (h *headerRewrite) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	reqInfo, err := ContextRequestInfo(r)
+	if err != nil {
+		h.logger.Fatal("request-info-err", zap.Error(err))
+		return
+	}
+	if reqInfo.RoutePool == nil {
+		h.logger.Fatal("request-info-err", zap.Error(errors.New("failed-to-access-RoutePool")))
+		return
+	}
+
+	rules := reqInfo.RoutePool.HeaderRules()
+	// rules are applied once the request comes back from the route service
+	if rules == nil || reqInfo.RouteServiceURL != nil || (rules.Host == "" && rules.Request.IsEmpty()) {
+		next(rw, r)
+		return
+	}
+
+	// copy the request so the access log records what the client sent
+	newReq := new(http.Request)
+	*newReq = *r
+	newReq.Header = make(http.Header, len(r.Header))
+	for k, v := range r.Header {
+		newReq.Header[k] = append([]string(nil), v...)
+	}
+
+	rules.Request.Apply(newReq.Header)
+	if rules.Host != "" {
+		newReq.Host = rules.Host
+	}
+
+	next(rw, newReq)
+}
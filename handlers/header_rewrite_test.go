@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/handlers"
+	logger_fakes "code.cloudfoundry.org/gorouter/logger/fakes"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/urfave/negroni"
+)
+
+var _ = Describe("HeaderRewrite", func() {
+	var (
+		handler         *negroni.Negroni
+		logger          *logger_fakes.FakeLogger
+		resp            *httptest.ResponseRecorder
+		req             *http.Request
+		routePool       *route.Pool
+		routeServiceURL *url.URL
+		nextRequest     *http.Request
+	)
+
+	nextHandler := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		nextRequest = r
+	})
+
+	testSetupHandler := func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		reqInfo, err := handlers.ContextRequestInfo(r)
+		Expect(err).ToNot(HaveOccurred())
+		reqInfo.RoutePool = routePool
+		reqInfo.RouteServiceURL = routeServiceURL
+		next(rw, r)
+	}
+
+	BeforeEach(func() {
+		nextRequest = nil
+		routeServiceURL = nil
+		logger = new(logger_fakes.FakeLogger)
+		routePool = route.NewPool(2*time.Minute, "example.com", "/")
+		req = test_util.NewRequest("GET", "example.com", "/", nil)
+		req.Header.Set("X-Secret", "s3cr3t")
+		req.Header.Set("X-Existing", "client")
+		resp = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		handler = negroni.New()
+		handler.Use(handlers.NewRequestInfo())
+		handler.UseFunc(testSetupHandler)
+		handler.Use(handlers.NewHeaderRewrite(logger))
+		handler.UseHandler(nextHandler)
+		handler.ServeHTTP(resp, req)
+	})
+
+	Context("when the route has no header rules", func() {
+		BeforeEach(func() {
+			routePool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678}))
+		})
+
+		It("passes the request through untouched", func() {
+			Expect(nextRequest.Host).To(Equal("example.com"))
+			Expect(nextRequest.Header.Get("X-Secret")).To(Equal("s3cr3t"))
+		})
+	})
+
+	Context("when the route has header rules", func() {
+		BeforeEach(func() {
+			routePool.Put(route.NewEndpoint(&route.EndpointOpts{
+				Host: "1.2.3.4",
+				Port: 5678,
+				HeaderRules: &route.HeaderRules{
+					Host: "internal.example.com",
+					Request: route.HeaderRuleSet{
+						Set:    map[string]string{"X-Forwarded-Prefix": "/api"},
+						Append: map[string]string{"X-Existing": "router"},
+						Remove: []string{"X-Secret"},
+					},
+				},
+			}))
+		})
+
+		It("applies the request rules", func() {
+			Expect(nextRequest.Header.Get("X-Forwarded-Prefix")).To(Equal("/api"))
+			Expect(nextRequest.Header["X-Existing"]).To(Equal([]string{"client", "router"}))
+			Expect(nextRequest.Header).ToNot(HaveKey("X-Secret"))
+		})
+
+		It("rewrites the host", func() {
+			Expect(nextRequest.Host).To(Equal("internal.example.com"))
+		})
+
+		It("does not modify the original request", func() {
+			Expect(req.Host).To(Equal("example.com"))
+			Expect(req.Header.Get("X-Secret")).To(Equal("s3cr3t"))
+			Expect(req.Header["X-Existing"]).To(Equal([]string{"client"}))
+		})
+
+		Context("when the request is being sent to a route service", func() {
+			BeforeEach(func() {
+				routeServiceURL = &url.URL{Scheme: "https", Host: "rs.example.com"}
+			})
+
+			It("does not apply the rules", func() {
+				Expect(nextRequest.Host).To(Equal("example.com"))
+				Expect(nextRequest.Header.Get("X-Secret")).To(Equal("s3cr3t"))
+			})
+		})
+	})
+})
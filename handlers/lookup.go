@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"fmt"
 
 	router_http "code.cloudfoundry.org/gorouter/common/http"
+	"code.cloudfoundry.org/gorouter/errorpage"
 	"code.cloudfoundry.org/gorouter/logger"
 	"code.cloudfoundry.org/gorouter/metrics"
 	"code.cloudfoundry.org/gorouter/registry"
@@ -21,38 +23,34 @@ const (
 )
 
 type lookupHandler struct {
-	registry           registry.Registry
-	reporter           metrics.ProxyReporter
-	logger             logger.Logger
-	maxConnsPerBackend int64
+	registry   registry.Registry
+	reporter   metrics.ProxyReporter
+	logger     logger.Logger
+	errorPages *errorpage.Pages
 }
 
 // NewLookup creates a handler responsible for looking up a route.
-func NewLookup(registry registry.Registry, rep metrics.ProxyReporter, logger logger.Logger, maxConnsPerBackend int64) negroni.Handler {
+func NewLookup(registry registry.Registry, rep metrics.ProxyReporter, logger logger.Logger, errorPages *errorpage.Pages) negroni.Handler {
 	return &lookupHandler{
-		registry:           registry,
-		reporter:           rep,
-		logger:             logger,
-		maxConnsPerBackend: maxConnsPerBackend,
+		registry:   registry,
+		reporter:   rep,
+		logger:     logger,
+		errorPages: errorPages,
 	}
 }
 
 func (l *lookupHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if m := l.registry.LookupMaintenance(requestUri(r)); m != nil {
+		l.handleMaintenance(rw, r, m)
+		return
+	}
+
 	pool := l.lookup(r)
 	if pool == nil || pool.IsEmpty() {
 		l.handleMissingRoute(rw, r)
 		return
 	}
 
-	if l.maxConnsPerBackend > 0 {
-		newPool := pool.FilteredPool(l.maxConnsPerBackend)
-		if newPool.IsEmpty() {
-			l.handleOverloadedRoute(rw, r)
-			return
-		}
-		pool = newPool
-	}
-
 	requestInfo, err := ContextRequestInfo(r)
 	if err != nil {
 		l.logger.Fatal("request-info-err", zap.Error(err))
@@ -70,30 +68,31 @@ func (l *lookupHandler) handleMissingRoute(rw http.ResponseWriter, r *http.Reque
 
 	writeStatus(
 		rw,
+		r,
 		http.StatusNotFound,
 		fmt.Sprintf("Requested route ('%s') does not exist.", r.Host),
+		l.errorPages,
 		l.logger,
 	)
 }
 
-func (l *lookupHandler) handleOverloadedRoute(rw http.ResponseWriter, r *http.Request) {
-	l.reporter.CaptureBackendExhaustedConns()
-	l.logger.Info("connection-limit-reached")
+func (l *lookupHandler) handleMaintenance(rw http.ResponseWriter, r *http.Request, m *route.Maintenance) {
+	l.logger.Info("route-in-maintenance", zap.Stringer("route", m.Route))
 
-	rw.Header().Set("X-Cf-RouterError", "Connection Limit Reached")
-
-	writeStatus(
-		rw,
-		http.StatusServiceUnavailable,
-		fmt.Sprintf("Requested route ('%s') has reached the connection limit.", r.Host),
-		l.logger,
-	)
+	rw.Header().Set(router_http.CfRouterError, "maintenance_mode")
+	if m.RetryAfter > 0 {
+		rw.Header().Set("Retry-After", strconv.Itoa(m.RetryAfter))
+	}
+	rw.Header().Set("Content-Type", m.ResponseContentType())
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.WriteHeader(m.Code())
+	if r.Method != http.MethodHead {
+		rw.Write([]byte(m.ResponseBody()))
+	}
 }
 
 func (l *lookupHandler) lookup(r *http.Request) *route.Pool {
-	requestPath := r.URL.EscapedPath()
-
-	uri := route.Uri(hostWithoutPort(r.Host) + requestPath)
+	uri := requestUri(r)
 	appInstanceHeader := r.Header.Get(router_http.CfAppInstance)
 
 	if appInstanceHeader != "" {
@@ -110,6 +109,10 @@ func (l *lookupHandler) lookup(r *http.Request) *route.Pool {
 	return l.registry.Lookup(uri)
 }
 
+func requestUri(r *http.Request) route.Uri {
+	return route.Uri(router_http.HostWithoutPort(r.Host) + r.URL.EscapedPath())
+}
+
 func validateCfAppInstance(appInstanceHeader string) (string, string, error) {
 	appDetails := strings.Split(appInstanceHeader, ":")
 	if len(appDetails) != 2 {
@@ -5,6 +5,8 @@ import (
 	"net/http/httptest"
 	"time"
 
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/errorpage"
 	"code.cloudfoundry.org/gorouter/handlers"
 	logger_fakes "code.cloudfoundry.org/gorouter/logger/fakes"
 	"code.cloudfoundry.org/gorouter/metrics/fakes"
@@ -19,16 +21,16 @@ import (
 
 var _ = Describe("Lookup", func() {
 	var (
-		handler        *negroni.Negroni
-		nextHandler    http.HandlerFunc
-		logger         *logger_fakes.FakeLogger
-		reg            *fakeRegistry.FakeRegistry
-		rep            *fakes.FakeCombinedReporter
-		resp           *httptest.ResponseRecorder
-		req            *http.Request
-		nextCalled     bool
-		nextRequest    *http.Request
-		maxConnections int64
+		handler     *negroni.Negroni
+		nextHandler http.HandlerFunc
+		logger      *logger_fakes.FakeLogger
+		reg         *fakeRegistry.FakeRegistry
+		rep         *fakes.FakeCombinedReporter
+		resp        *httptest.ResponseRecorder
+		req         *http.Request
+		nextCalled  bool
+		nextRequest *http.Request
+		errorPages  *errorpage.Pages
 	)
 
 	nextHandler = http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
@@ -39,7 +41,7 @@ var _ = Describe("Lookup", func() {
 	BeforeEach(func() {
 		nextCalled = false
 		nextRequest = &http.Request{}
-		maxConnections = 2
+		errorPages = nil
 		logger = new(logger_fakes.FakeLogger)
 		rep = &fakes.FakeCombinedReporter{}
 		reg = &fakeRegistry.FakeRegistry{}
@@ -50,7 +52,7 @@ var _ = Describe("Lookup", func() {
 
 	JustBeforeEach(func() {
 		handler.Use(handlers.NewRequestInfo())
-		handler.Use(handlers.NewLookup(reg, rep, logger, maxConnections))
+		handler.Use(handlers.NewLookup(reg, rep, logger, errorPages))
 		handler.UseHandler(nextHandler)
 		handler.ServeHTTP(resp, req)
 	})
@@ -72,6 +74,56 @@ var _ = Describe("Lookup", func() {
 		It("has a meaningful response", func() {
 			Expect(resp.Body.String()).To(ContainSubstring("Requested route ('example.com') does not exist"))
 		})
+
+		Context("when an error page is configured", func() {
+			BeforeEach(func() {
+				var err error
+				errorPages, err = errorpage.NewPages(logger, []config.ErrorPage{
+					{StatusCode: http.StatusNotFound, HTML: "<p>{{.Host}} not found ({{.RouterError}})</p>"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("renders the error page", func() {
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+				Expect(resp.Header().Get("Content-Type")).To(Equal("text/html; charset=utf-8"))
+				Expect(resp.Body.String()).To(Equal("<p>example.com not found (unknown_route)</p>"))
+			})
+		})
+	})
+
+	Context("when the route is in maintenance mode", func() {
+		BeforeEach(func() {
+			reg.LookupMaintenanceReturns(&route.Maintenance{
+				Route:       "example.com",
+				RetryAfter:  120,
+				ContentType: "application/json",
+				Body:        `{"status":"down"}`,
+			})
+			pool := route.NewPool(2*time.Minute, "example.com", "/")
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678}))
+			reg.LookupReturns(pool)
+		})
+
+		It("returns the maintenance response and does not call next", func() {
+			Expect(nextCalled).To(BeFalse())
+			Expect(reg.LookupCallCount()).To(Equal(0))
+			Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(resp.Header().Get("Content-Type")).To(Equal("application/json"))
+			Expect(resp.Body.String()).To(Equal(`{"status":"down"}`))
+		})
+
+		It("sets the Retry-After header", func() {
+			Expect(resp.Header().Get("Retry-After")).To(Equal("120"))
+		})
+
+		It("Sets X-Cf-RouterError to maintenance_mode", func() {
+			Expect(resp.Header().Get("X-Cf-RouterError")).To(Equal("maintenance_mode"))
+		})
+
+		It("looks up the maintenance settings for the requested route", func() {
+			Expect(reg.LookupMaintenanceArgsForCall(0)).To(Equal(route.Uri("example.com")))
+		})
 	})
 
 	Context("when there is a pool that matches the request, but it has no endpoints", func() {
@@ -113,7 +165,6 @@ var _ = Describe("Lookup", func() {
 
 		Context("when conn limit is set to zero (unlimited)", func() {
 			BeforeEach(func() {
-				maxConnections = 0
 				pool = route.NewPool(2*time.Minute, "example.com", "/")
 				testEndpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.3.5.6", Port: 5679})
 				for i := 0; i < 5; i++ {
@@ -142,6 +193,7 @@ var _ = Describe("Lookup", func() {
 		Context("when conn limit is reached for an endpoint", func() {
 			BeforeEach(func() {
 				pool = route.NewPool(2*time.Minute, "example.com", "/")
+				pool.SetConnectionLimits(config.ConnectionLimits{MaxConns: 2})
 				testEndpoint := route.NewEndpoint(&route.EndpointOpts{AppId: "testid1", Host: "1.3.5.6", Port: 5679})
 				testEndpoint.Stats.NumberConnections.Increment()
 				testEndpoint.Stats.NumberConnections.Increment()
@@ -152,49 +204,19 @@ var _ = Describe("Lookup", func() {
 				reg.LookupReturns(pool)
 			})
 
-			It("does not include the overloaded backend in the pool", func() {
+			It("does not route to the overloaded backend", func() {
 				Expect(nextCalled).To(BeTrue())
 				requestInfo, err := handlers.ContextRequestInfo(nextRequest)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(requestInfo.RoutePool.IsEmpty()).To(BeFalse())
-				len := 0
-				var expectedAppId string
-				requestInfo.RoutePool.Each(func(endpoint *route.Endpoint) {
-					expectedAppId = endpoint.ApplicationId
-					len++
-				})
-				Expect(len).To(Equal(1))
-				Expect(expectedAppId).To(Equal("testid2"))
+				Expect(requestInfo.RoutePool).To(Equal(pool))
+				iter := requestInfo.RoutePool.Endpoints("", "")
+				for i := 0; i < 5; i++ {
+					Expect(iter.Next().ApplicationId).To(Equal("testid2"))
+				}
 				Expect(resp.Code).To(Equal(http.StatusOK))
 			})
 		})
 
-		Context("when conn limit is reached for all requested endpoints", func() {
-			var testEndpoint *route.Endpoint
-			BeforeEach(func() {
-				pool = route.NewPool(2*time.Minute, "example.com", "/")
-				testEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "1.3.5.6", Port: 5679})
-				testEndpoint.Stats.NumberConnections.Increment()
-				testEndpoint.Stats.NumberConnections.Increment()
-				testEndpoint.Stats.NumberConnections.Increment()
-				pool.Put(testEndpoint)
-				testEndpoint1 := route.NewEndpoint(&route.EndpointOpts{Host: "1.4.6.7", Port: 5679})
-				testEndpoint1.Stats.NumberConnections.Increment()
-				testEndpoint1.Stats.NumberConnections.Increment()
-				testEndpoint1.Stats.NumberConnections.Increment()
-				pool.Put(testEndpoint1)
-				reg.LookupReturns(pool)
-			})
-			It("returns a 503", func() {
-				Expect(nextCalled).To(BeFalse())
-				Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
-			})
-
-			It("increments the backend_exhausted_conn metric", func() {
-				Expect(rep.CaptureBackendExhaustedConnsCallCount()).To(Equal(1))
-			})
-		})
-
 		It("calls next with the pool", func() {
 			Expect(nextCalled).To(BeTrue())
 			requestInfo, err := handlers.ContextRequestInfo(nextRequest)
@@ -271,7 +293,7 @@ var _ = Describe("Lookup", func() {
 		Context("when request info is not set on the request context", func() {
 			BeforeEach(func() {
 				handler = negroni.New()
-				handler.Use(handlers.NewLookup(reg, rep, logger, 0))
+				handler.Use(handlers.NewLookup(reg, rep, logger, nil))
 				handler.UseHandler(nextHandler)
 			})
 			It("calls Fatal on the logger", func() {
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"github.com/uber-go/zap"
+)
+
+type maintenance struct {
+	registry *registry.RouteRegistry
+	logger   logger.Logger
+}
+
+// NewMaintenance creates the status API handler used to list, set and remove
+// the routes in maintenance mode
+func NewMaintenance(registry *registry.RouteRegistry, logger logger.Logger) http.Handler {
+	return &maintenance{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+func (h *maintenance) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Connection", "close")
+
+	switch r.Method {
+	case http.MethodGet:
+		h.writeJSON(rw, http.StatusOK, h.registry.MaintenanceRoutes())
+
+	case http.MethodPut, http.MethodPost:
+		m := &route.Maintenance{}
+		err := json.NewDecoder(r.Body).Decode(m)
+		if err == nil {
+			err = m.Validate()
+		}
+		if err != nil {
+			h.logger.Info("invalid-maintenance-request", zap.Error(err))
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		h.registry.SetMaintenance(m)
+		h.writeJSON(rw, http.StatusOK, m)
+
+	case http.MethodDelete:
+		uri := r.URL.Query().Get("route")
+		if uri == "" {
+			http.Error(rw, "route is required", http.StatusBadRequest)
+			return
+		}
+
+		if !h.registry.RemoveMaintenance(route.Uri(uri)) {
+			http.Error(rw, "route is not in maintenance mode", http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+
+	default:
+		rw.Header().Set("Allow", "GET, PUT, POST, DELETE")
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *maintenance) writeJSON(rw http.ResponseWriter, code int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+
+	err := json.NewEncoder(rw).Encode(v)
+	if err != nil {
+		h.logger.Error("maintenance-response-err", zap.Error(err))
+	}
+}
@@ -0,0 +1,131 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/handlers"
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Maintenance", func() {
+	var (
+		handler http.Handler
+		logger  logger.Logger
+		reg     *registry.RouteRegistry
+		resp    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		logger = test_util.NewTestZapLogger("maintenance")
+		cfg, err := config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		reg = registry.NewRouteRegistry(logger, cfg, new(fakes.FakeRouteRegistryReporter))
+		resp = httptest.NewRecorder()
+
+		handler = handlers.NewMaintenance(reg, logger)
+	})
+
+	Describe("PUT", func() {
+		It("puts the route into maintenance mode", func() {
+			body := `{"route":"Example.com/","retry_after":60,"body":"<p>back soon</p>"}`
+			req, err := http.NewRequest("PUT", "/routes/maintenance", strings.NewReader(body))
+			Expect(err).ToNot(HaveOccurred())
+			handler.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			m := reg.LookupMaintenance("example.com/foo")
+			Expect(m).ToNot(BeNil())
+			Expect(m.Route).To(Equal(route.Uri("example.com")))
+			Expect(m.RetryAfter).To(Equal(60))
+		})
+
+		It("rejects invalid settings", func() {
+			req, err := http.NewRequest("PUT", "/routes/maintenance", strings.NewReader(`{"route":"example.com","status_code":200}`))
+			Expect(err).ToNot(HaveOccurred())
+			handler.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(reg.LookupMaintenance("example.com")).To(BeNil())
+		})
+
+		It("rejects malformed json", func() {
+			req, err := http.NewRequest("PUT", "/routes/maintenance", strings.NewReader(`{`))
+			Expect(err).ToNot(HaveOccurred())
+			handler.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("GET", func() {
+		BeforeEach(func() {
+			reg.SetMaintenance(&route.Maintenance{Route: "*.example.com"})
+			reg.SetMaintenance(&route.Maintenance{Route: "app.example.com", StatusCode: 502})
+		})
+
+		It("lists the routes in maintenance mode", func() {
+			req, err := http.NewRequest("GET", "/routes/maintenance", nil)
+			Expect(err).ToNot(HaveOccurred())
+			handler.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Header().Get("Content-Type")).To(Equal("application/json"))
+
+			var routes []route.Maintenance
+			Expect(json.Unmarshal(resp.Body.Bytes(), &routes)).To(Succeed())
+			Expect(routes).To(Equal([]route.Maintenance{
+				{Route: "*.example.com"},
+				{Route: "app.example.com", StatusCode: 502},
+			}))
+		})
+	})
+
+	Describe("DELETE", func() {
+		BeforeEach(func() {
+			reg.SetMaintenance(&route.Maintenance{Route: "example.com"})
+		})
+
+		It("takes the route out of maintenance mode", func() {
+			req, err := http.NewRequest("DELETE", "/routes/maintenance?route=example.com", nil)
+			Expect(err).ToNot(HaveOccurred())
+			handler.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusNoContent))
+			Expect(reg.LookupMaintenance("example.com")).To(BeNil())
+		})
+
+		It("returns 404 when the route is not in maintenance mode", func() {
+			req, err := http.NewRequest("DELETE", "/routes/maintenance?route=other.com", nil)
+			Expect(err).ToNot(HaveOccurred())
+			handler.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("requires a route", func() {
+			req, err := http.NewRequest("DELETE", "/routes/maintenance", nil)
+			Expect(err).ToNot(HaveOccurred())
+			handler.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	It("rejects other methods", func() {
+		req, err := http.NewRequest("PATCH", "/routes/maintenance", nil)
+		Expect(err).ToNot(HaveOccurred())
+		handler.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -8,19 +8,22 @@ import (
 
 	"fmt"
 
+	"code.cloudfoundry.org/gorouter/errorpage"
 	"code.cloudfoundry.org/gorouter/logger"
 	"github.com/urfave/negroni"
 )
 
 type protocolCheck struct {
-	logger logger.Logger
+	logger     logger.Logger
+	errorPages *errorpage.Pages
 }
 
 // NewProtocolCheck creates a handler responsible for checking the protocol of
 // the request
-func NewProtocolCheck(logger logger.Logger) negroni.Handler {
+func NewProtocolCheck(logger logger.Logger, errorPages *errorpage.Pages) negroni.Handler {
 	return &protocolCheck{
-		logger: logger,
+		logger:     logger,
+		errorPages: errorPages,
 	}
 }
 
@@ -31,8 +34,10 @@ func (p *protocolCheck) ServeHTTP(rw http.ResponseWriter, r *http.Request, next
 		if err != nil {
 			writeStatus(
 				rw,
+				r,
 				http.StatusBadRequest,
 				"Unsupported protocol",
+				p.errorPages,
 				p.logger,
 			)
 			return
@@ -31,7 +31,7 @@ var _ = Describe("Protocolcheck", func() {
 		n.UseFunc(func(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
 			next(rw, req)
 		})
-		n.Use(handlers.NewProtocolCheck(logger))
+		n.Use(handlers.NewProtocolCheck(logger, nil))
 		n.UseHandlerFunc(func(http.ResponseWriter, *http.Request) {
 			nextCalled = true
 		})
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/errorpage"
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/metrics"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/routeservice"
+	"github.com/uber-go/zap"
+	"github.com/urfave/negroni"
+)
+
+const (
+	// bucketSweepInterval is how often the buckets of a shard that have
+	// filled up again are dropped
+	bucketSweepInterval = time.Minute
+	// bucketShards is the number of independently locked maps the buckets
+	// are spread over
+	bucketShards = 32
+)
+
+type rateLimit struct {
+	config             config.RateLimitingConfig
+	routeServiceConfig *routeservice.RouteServiceConfig
+	registry           registry.Registry
+	reporter           metrics.ProxyReporter
+	logger             logger.Logger
+	errorPages         *errorpage.Pages
+
+	maxShardBuckets int
+	shards          [bucketShards]bucketShard
+}
+
+type bucketShard struct {
+	lock    sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+// tokenBucket holds the requests a client has left at the time it was last
+// updated
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	used    time.Time
+	limit   config.RateLimit
+}
+
+// NewRateLimit creates a handler that rejects requests to a route beyond its
+// rate limit: the one configured for the route, the one the route is
+// registered with, or the default one, in that order. Requests that come back
+// from a route service were limited on their way there and pass.
+func NewRateLimit(c config.RateLimitingConfig, routeServiceConfig *routeservice.RouteServiceConfig, routeRegistry registry.Registry, rep metrics.ProxyReporter, logger logger.Logger, errorPages *errorpage.Pages) negroni.Handler {
+	h := &rateLimit{
+		config:             c,
+		routeServiceConfig: routeServiceConfig,
+		registry:           routeRegistry,
+		reporter:           rep,
+		logger:             logger,
+		errorPages:         errorPages,
+		maxShardBuckets:    (c.MaxClients + bucketShards - 1) / bucketShards,
+	}
+	for i := range h.shards {
+		h.shards[i].buckets = make(map[string]*tokenBucket)
+	}
+	return h
+}
+
+func (h *rateLimit) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	reqInfo, err := ContextRequestInfo(r)
+	if err != nil {
+		h.logger.Fatal("request-info-err", zap.Error(err))
+		return
+	}
+	if reqInfo.RoutePool == nil {
+		h.logger.Fatal("request-info-err", zap.Error(errors.New("failed-to-access-RoutePool")))
+		return
+	}
+
+	routeKey := reqInfo.RoutePool.Host() + reqInfo.RoutePool.ContextPath()
+	limit := h.limit(reqInfo.RoutePool, routeKey)
+	if limit.RequestsPerSecond <= 0 || h.fromRouteService(r, reqInfo) {
+		next(rw, r)
+		return
+	}
+
+	wait, ok := h.take(routeKey+" "+h.clientKey(r, limit), limit, time.Now())
+	if !ok {
+		h.handleRateLimited(rw, r, routeKey, limit, wait)
+		return
+	}
+	next(rw, r)
+}
+
+// fromRouteService reports whether the request comes back from the route
+// service of its route, which counts it a second time and from the address
+// of the route service otherwise. Invalid signatures are rejected by the
+// route service handler.
+func (h *rateLimit) fromRouteService(r *http.Request, reqInfo *RequestInfo) bool {
+	if h.routeServiceConfig == nil || !h.routeServiceConfig.RouteServiceEnabled() {
+		return false
+	}
+	if !hasBeenToRouteService(reqInfo.RoutePool.RouteServiceUrl(), r.Header.Get(routeservice.HeaderKeySignature)) {
+		return false
+	}
+	return validateRouteServiceSignature(h.routeServiceConfig, h.registry, r, reqInfo) == nil
+}
+
+func (h *rateLimit) limit(pool *route.Pool, routeKey string) config.RateLimit {
+	if l := h.config.Route(routeKey); l != nil {
+		return *l
+	}
+	if l := pool.RateLimit(); l != nil {
+		return *l
+	}
+	return h.config.RateLimit
+}
+
+// take removes a request from the bucket of the key. It returns false and
+// how long until the next request is allowed when the bucket is empty.
+func (h *rateLimit) take(key string, limit config.RateLimit, now time.Time) (time.Duration, bool) {
+	shard := h.shard(key)
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	if now.Sub(shard.swept) >= bucketSweepInterval {
+		shard.sweep(now)
+	}
+
+	b := shard.buckets[key]
+	if b == nil {
+		if h.maxShardBuckets > 0 && len(shard.buckets) >= h.maxShardBuckets {
+			shard.evict(now)
+		}
+		b = &tokenBucket{tokens: burst(limit), updated: now}
+		shard.buckets[key] = b
+	}
+	b.limit = limit
+	b.used = now
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	return time.Duration((1 - b.tokens) / limit.RequestsPerSecond * float64(time.Second)), false
+}
+
+func (h *rateLimit) shard(key string) *bucketShard {
+	f := fnv.New32a()
+	f.Write([]byte(key))
+	return &h.shards[f.Sum32()%bucketShards]
+}
+
+// sweep drops the buckets that are full, which are the same as new ones
+func (s *bucketShard) sweep(now time.Time) int {
+	swept := 0
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= burst(b.limit) {
+			delete(s.buckets, key)
+			swept++
+		}
+	}
+	s.swept = now
+	return swept
+}
+
+// evict makes room for a new bucket in a full shard. Full buckets are dropped
+// first, since that resets no limit; otherwise the bucket of the client that
+// made a request longest ago is dropped, so that clients that keep making
+// requests keep their limits.
+func (s *bucketShard) evict(now time.Time) {
+	if s.sweep(now) > 0 {
+		return
+	}
+
+	var oldest string
+	var oldestUsed time.Time
+	for key, b := range s.buckets {
+		if oldest == "" || b.used.Before(oldestUsed) {
+			oldest, oldestUsed = key, b.used
+		}
+	}
+	delete(s.buckets, oldest)
+}
+
+func (h *rateLimit) handleRateLimited(rw http.ResponseWriter, r *http.Request, routeKey string, limit config.RateLimit, wait time.Duration) {
+	h.reporter.CaptureRateLimited()
+	h.logger.Info("rate-limit-exceeded", zap.String("route", routeKey), zap.String("key", limit.Key))
+
+	rw.Header().Set("X-Cf-RouterError", "rate_limit_exceeded")
+	rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+
+	writeStatus(
+		rw,
+		r,
+		http.StatusTooManyRequests,
+		fmt.Sprintf("Requested route ('%s') has exceeded the rate limit.", r.Host),
+		h.errorPages,
+		h.logger,
+	)
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(burst(b.limit), b.tokens+elapsed.Seconds()*b.limit.RequestsPerSecond)
+		b.updated = now
+	}
+}
+
+// burst returns the size of the bucket, which defaults to the requests of a
+// second
+func burst(l config.RateLimit) float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.RequestsPerSecond))
+}
+
+// clientKey returns what tells the clients of a route apart
+func (h *rateLimit) clientKey(r *http.Request, l config.RateLimit) string {
+	switch l.Key {
+	case config.RATE_LIMIT_ROUTE:
+		return ""
+	case config.RATE_LIMIT_HEADER:
+		return r.Header.Get(l.Header)
+	default:
+		return h.clientIP(r)
+	}
+}
+
+// clientIP returns the address the outermost trusted proxy received the
+// request from. Addresses to the left of it are set by the client and cannot
+// be trusted. When the request passed fewer proxies than are trusted, the
+// first address is used.
+func (h *rateLimit) clientIP(r *http.Request) string {
+	if h.config.TrustedProxies > 0 {
+		var forwardedFor []string
+		for _, v := range r.Header["X-Forwarded-For"] {
+			for _, addr := range strings.Split(v, ",") {
+				if addr = strings.TrimSpace(addr); addr != "" {
+					forwardedFor = append(forwardedFor, addr)
+				}
+			}
+		}
+		if n := len(forwardedFor); n > 0 {
+			if n < h.config.TrustedProxies {
+				return forwardedFor[0]
+			}
+			return forwardedFor[n-h.config.TrustedProxies]
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,268 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/common/secure"
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/handlers"
+	logger_fakes "code.cloudfoundry.org/gorouter/logger/fakes"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	fakeRegistry "code.cloudfoundry.org/gorouter/registry/fakes"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/routeservice"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/urfave/negroni"
+)
+
+var _ = Describe("RateLimit", func() {
+	var (
+		handler      *negroni.Negroni
+		logger       *logger_fakes.FakeLogger
+		rep          *fakes.FakeCombinedReporter
+		routePool    *route.Pool
+		rateLimiting config.RateLimitingConfig
+		nextCalls    int
+
+		routeServiceConfig *routeservice.RouteServiceConfig
+		reg                *fakeRegistry.FakeRegistry
+	)
+
+	nextHandler := http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		nextCalls++
+	})
+
+	testSetupHandler := func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		reqInfo, err := handlers.ContextRequestInfo(r)
+		Expect(err).ToNot(HaveOccurred())
+		reqInfo.RoutePool = routePool
+		next(rw, r)
+	}
+
+	serve := func(remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "http://example.com/foo", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.RemoteAddr = remoteAddr
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	BeforeEach(func() {
+		nextCalls = 0
+		logger = new(logger_fakes.FakeLogger)
+		rep = &fakes.FakeCombinedReporter{}
+		routePool = route.NewPool(2*time.Minute, "example.com", "/")
+		routePool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678}))
+		rateLimiting = config.RateLimitingConfig{}
+
+		crypto, err := secure.NewAesGCM([]byte("ABCDEFGHIJKLMNOP"))
+		Expect(err).ToNot(HaveOccurred())
+		routeServiceConfig = routeservice.NewRouteServiceConfig(logger, true, time.Minute, crypto, nil, true)
+		reg = &fakeRegistry.FakeRegistry{}
+		reg.LookupStub = func(route.Uri) *route.Pool { return routePool }
+	})
+
+	JustBeforeEach(func() {
+		handler = negroni.New()
+		handler.Use(handlers.NewRequestInfo())
+		handler.UseFunc(testSetupHandler)
+		handler.Use(handlers.NewRateLimit(rateLimiting, routeServiceConfig, reg, rep, logger, nil))
+		handler.UseHandler(nextHandler)
+	})
+
+	Context("when there is no rate limit", func() {
+		It("calls next", func() {
+			for i := 0; i < 10; i++ {
+				Expect(serve("10.0.0.1:1234", nil).Code).To(Equal(http.StatusOK))
+			}
+			Expect(nextCalls).To(Equal(10))
+		})
+	})
+
+	Context("when the default rate limit applies", func() {
+		BeforeEach(func() {
+			rateLimiting.RateLimit = config.RateLimit{RequestsPerSecond: 0.5, Burst: 2}
+		})
+
+		It("allows a burst of requests per client", func() {
+			Expect(serve("10.0.0.1:1234", nil).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.1:1235", nil).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.2:1234", nil).Code).To(Equal(http.StatusOK))
+			Expect(nextCalls).To(Equal(3))
+		})
+
+		It("rejects requests beyond the limit with a 429", func() {
+			serve("10.0.0.1:1234", nil)
+			serve("10.0.0.1:1234", nil)
+			resp := serve("10.0.0.1:1234", nil)
+
+			Expect(resp.Code).To(Equal(http.StatusTooManyRequests))
+			Expect(resp.Header().Get("X-Cf-RouterError")).To(Equal("rate_limit_exceeded"))
+			Expect(resp.Header().Get("Retry-After")).To(Equal("2"))
+			Expect(resp.Body.String()).To(ContainSubstring("Requested route ('example.com') has exceeded the rate limit."))
+			Expect(nextCalls).To(Equal(2))
+			Expect(rep.CaptureRateLimitedCallCount()).To(Equal(1))
+		})
+
+		It("allows requests again once the bucket refills", func() {
+			rateLimiting.RateLimit = config.RateLimit{RequestsPerSecond: 50, Burst: 1}
+			handler = negroni.New()
+			handler.Use(handlers.NewRequestInfo())
+			handler.UseFunc(testSetupHandler)
+			handler.Use(handlers.NewRateLimit(rateLimiting, routeServiceConfig, reg, rep, logger, nil))
+			handler.UseHandler(nextHandler)
+
+			Expect(serve("10.0.0.1:1234", nil).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.1:1234", nil).Code).To(Equal(http.StatusTooManyRequests))
+			Eventually(func() int { return serve("10.0.0.1:1234", nil).Code }).Should(Equal(http.StatusOK))
+		})
+	})
+
+	Context("when the buckets of max clients are kept", func() {
+		BeforeEach(func() {
+			rateLimiting.RateLimit = config.RateLimit{RequestsPerSecond: 0.5, Burst: 1}
+			rateLimiting.MaxClients = 1
+		})
+
+		It("drops buckets for new clients", func() {
+			Expect(serve("10.0.0.1:1234", nil).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.1:1234", nil).Code).To(Equal(http.StatusTooManyRequests))
+
+			for i := 0; i < 256; i++ {
+				Expect(serve(fmt.Sprintf("10.0.1.%d:1234", i), nil).Code).To(Equal(http.StatusOK))
+			}
+			Expect(serve("10.0.0.1:1234", nil).Code).To(Equal(http.StatusOK))
+		})
+
+		Context("when a shard has room for more than one bucket", func() {
+			BeforeEach(func() {
+				rateLimiting.MaxClients = 64
+			})
+
+			It("drops the bucket of the client that made a request longest ago", func() {
+				Expect(serve("10.0.0.1:1234", nil).Code).To(Equal(http.StatusOK))
+
+				for i := 0; i < 256; i++ {
+					Expect(serve(fmt.Sprintf("10.0.1.%d:1234", i), nil).Code).To(Equal(http.StatusOK))
+					Expect(serve("10.0.0.1:1234", nil).Code).To(Equal(http.StatusTooManyRequests))
+				}
+			})
+		})
+	})
+
+	Context("when there are trusted proxies in front of the router", func() {
+		BeforeEach(func() {
+			rateLimiting.RateLimit = config.RateLimit{RequestsPerSecond: 0.5, Burst: 1}
+			rateLimiting.TrustedProxies = 2
+		})
+
+		It("limits the requests per address the outermost trusted proxy saw", func() {
+			Expect(serve("10.0.0.1:1234", http.Header{"X-Forwarded-For": {"1.1.1.1, 2.2.2.2, 10.0.0.2"}}).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.1:1234", http.Header{"X-Forwarded-For": {"3.3.3.3, 2.2.2.2", "10.0.0.3"}}).Code).To(Equal(http.StatusTooManyRequests))
+			Expect(serve("10.0.0.1:1234", http.Header{"X-Forwarded-For": {"1.1.1.1, 4.4.4.4, 10.0.0.2"}}).Code).To(Equal(http.StatusOK))
+		})
+
+		It("uses the first address when the request passed fewer proxies", func() {
+			Expect(serve("10.0.0.1:1234", http.Header{"X-Forwarded-For": {"5.5.5.5"}}).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.2:1234", http.Header{"X-Forwarded-For": {"5.5.5.5"}}).Code).To(Equal(http.StatusTooManyRequests))
+		})
+
+		It("uses the address the request came from without X-Forwarded-For", func() {
+			Expect(serve("10.0.0.1:1234", nil).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.1:1235", nil).Code).To(Equal(http.StatusTooManyRequests))
+			Expect(serve("10.0.0.2:1234", nil).Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("when the route is registered with a rate limit", func() {
+		BeforeEach(func() {
+			rateLimiting.RateLimit = config.RateLimit{RequestsPerSecond: 100}
+			routePool = route.NewPool(2*time.Minute, "example.com", "/")
+			routePool.Put(route.NewEndpoint(&route.EndpointOpts{
+				Host:      "1.2.3.4",
+				Port:      5678,
+				RateLimit: &config.RateLimit{RequestsPerSecond: 1, Key: config.RATE_LIMIT_HEADER, Header: "X-Api-Key"},
+			}))
+		})
+
+		It("limits the requests per header value", func() {
+			Expect(serve("10.0.0.1:1234", http.Header{"X-Api-Key": {"a"}}).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.2:1234", http.Header{"X-Api-Key": {"a"}}).Code).To(Equal(http.StatusTooManyRequests))
+			Expect(serve("10.0.0.1:1234", http.Header{"X-Api-Key": {"b"}}).Code).To(Equal(http.StatusOK))
+		})
+
+		Context("when the route is configured with a rate limit", func() {
+			BeforeEach(func() {
+				rateLimiting.Routes = []config.RouteRateLimit{
+					{Route: "example.com", RateLimit: config.RateLimit{RequestsPerSecond: 1, Key: config.RATE_LIMIT_ROUTE}},
+				}
+			})
+
+			It("limits all requests to the route", func() {
+				Expect(serve("10.0.0.1:1234", http.Header{"X-Api-Key": {"a"}}).Code).To(Equal(http.StatusOK))
+				Expect(serve("10.0.0.2:1234", http.Header{"X-Api-Key": {"b"}}).Code).To(Equal(http.StatusTooManyRequests))
+			})
+		})
+	})
+
+	Context("when the route has a route service", func() {
+		BeforeEach(func() {
+			rateLimiting.RateLimit = config.RateLimit{RequestsPerSecond: 0.5, Burst: 1}
+			routePool = route.NewPool(2*time.Minute, "example.com", "/")
+			routePool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, RouteServiceUrl: "https://rs.example.com"}))
+		})
+
+		signed := func(forwardedURL string) http.Header {
+			args, err := routeServiceConfig.Request("https://rs.example.com", forwardedURL)
+			Expect(err).ToNot(HaveOccurred())
+			header := http.Header{}
+			header.Set(routeservice.HeaderKeySignature, args.Signature)
+			header.Set(routeservice.HeaderKeyMetadata, args.Metadata)
+			return header
+		}
+
+		It("does not count requests that come back from the route service", func() {
+			Expect(serve("10.0.0.1:1234", nil).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.9:1234", signed("https://example.com/foo")).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.9:1234", signed("https://example.com/foo")).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.1:1234", nil).Code).To(Equal(http.StatusTooManyRequests))
+			Expect(nextCalls).To(Equal(3))
+		})
+
+		It("counts requests with an invalid signature", func() {
+			header := signed("https://example.com/foo")
+			header.Set(routeservice.HeaderKeySignature, "invalid")
+
+			Expect(serve("10.0.0.9:1234", header).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.9:1234", header).Code).To(Equal(http.StatusTooManyRequests))
+		})
+
+		It("counts requests signed for another route", func() {
+			reg.LookupStub = func(route.Uri) *route.Pool { return route.NewPool(time.Minute, "other.example.com", "/") }
+
+			Expect(serve("10.0.0.9:1234", signed("https://other.example.com/foo")).Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.9:1234", signed("https://other.example.com/foo")).Code).To(Equal(http.StatusTooManyRequests))
+		})
+	})
+
+	Context("when request info is not set on the request context", func() {
+		It("calls Fatal on the logger", func() {
+			handler = negroni.New()
+			handler.Use(handlers.NewRateLimit(rateLimiting, routeServiceConfig, reg, rep, logger, nil))
+			handler.UseHandler(nextHandler)
+			serve("10.0.0.1:1234", nil)
+
+			Expect(logger.FatalCallCount()).To(Equal(1))
+			Expect(nextCalls).To(BeZero())
+		})
+	})
+})
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	router_http "code.cloudfoundry.org/gorouter/common/http"
+	"code.cloudfoundry.org/gorouter/logger"
+	"github.com/uber-go/zap"
+	"github.com/urfave/negroni"
+)
+
+type redirect struct {
+	logger                   logger.Logger
+	forceForwardedProtoHttps bool
+	sanitizeForwardedProto   bool
+}
+
+// NewRedirect creates a handler responsible for answering requests to
+// redirect routes and for redirecting plain HTTP requests on HTTPS only
+// routes
+func NewRedirect(logger logger.Logger, forceForwardedProtoHttps, sanitizeForwardedProto bool) negroni.Handler {
+	return &redirect{
+		logger:                   logger,
+		forceForwardedProtoHttps: forceForwardedProtoHttps,
+		sanitizeForwardedProto:   sanitizeForwardedProto,
+	}
+}
+
+func (h *redirect) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	reqInfo, err := ContextRequestInfo(r)
+	if err != nil {
+		h.logger.Fatal("request-info-err", zap.Error(err))
+		return
+	}
+	if reqInfo.RoutePool == nil {
+		h.logger.Fatal("request-info-err", zap.Error(errors.New("failed-to-access-RoutePool")))
+		return
+	}
+
+	if reqInfo.RoutePool.HTTPSOnly() && !h.isHTTPS(r) {
+		code := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			// preserve the method and body of the request
+			code = http.StatusPermanentRedirect
+		}
+		location := "https://" + router_http.HostWithoutPort(r.Host) + r.URL.RequestURI()
+		h.logger.Debug("https-only-redirect", zap.String("location", location))
+
+		http.Redirect(rw, r, location, code)
+		return
+	}
+
+	if rd := reqInfo.RoutePool.Redirect(); rd != nil {
+		location := rd.Location(r)
+		h.logger.Debug("route-redirect", zap.String("location", location))
+
+		http.Redirect(rw, r, location, rd.Code())
+		return
+	}
+
+	next(rw, r)
+}
+
+// isHTTPS reports whether the client used https, following the same rules
+// as the X-Forwarded-Proto handler
+func (h *redirect) isHTTPS(r *http.Request) bool {
+	if h.forceForwardedProtoHttps {
+		return true
+	}
+	if !h.sanitizeForwardedProto {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			return proto == "https"
+		}
+	}
+	return r.TLS != nil
+}
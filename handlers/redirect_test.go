@@ -0,0 +1,154 @@
+package handlers_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/handlers"
+	logger_fakes "code.cloudfoundry.org/gorouter/logger/fakes"
+	"code.cloudfoundry.org/gorouter/route"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/urfave/negroni"
+)
+
+var _ = Describe("Redirect", func() {
+	var (
+		handler                  *negroni.Negroni
+		logger                   *logger_fakes.FakeLogger
+		resp                     *httptest.ResponseRecorder
+		req                      *http.Request
+		routePool                *route.Pool
+		nextCalled               bool
+		forceForwardedProtoHttps bool
+		sanitizeForwardedProto   bool
+	)
+
+	nextHandler := http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		nextCalled = true
+	})
+
+	testSetupHandler := func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		reqInfo, err := handlers.ContextRequestInfo(r)
+		Expect(err).ToNot(HaveOccurred())
+		reqInfo.RoutePool = routePool
+		next(rw, r)
+	}
+
+	BeforeEach(func() {
+		nextCalled = false
+		forceForwardedProtoHttps = false
+		sanitizeForwardedProto = false
+		logger = new(logger_fakes.FakeLogger)
+		routePool = route.NewPool(2*time.Minute, "example.com", "/")
+		var err error
+		req, err = http.NewRequest("GET", "http://example.com:80/foo?bar=baz", nil)
+		Expect(err).ToNot(HaveOccurred())
+		resp = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		handler = negroni.New()
+		handler.Use(handlers.NewRequestInfo())
+		handler.UseFunc(testSetupHandler)
+		handler.Use(handlers.NewRedirect(logger, forceForwardedProtoHttps, sanitizeForwardedProto))
+		handler.UseHandler(nextHandler)
+		handler.ServeHTTP(resp, req)
+	})
+
+	Context("when the route is a regular route", func() {
+		BeforeEach(func() {
+			routePool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678}))
+		})
+
+		It("calls next", func() {
+			Expect(nextCalled).To(BeTrue())
+		})
+	})
+
+	Context("when the route is a redirect route", func() {
+		BeforeEach(func() {
+			routePool.Put(route.NewEndpoint(&route.EndpointOpts{
+				Redirect: &route.Redirect{
+					URL:          "https://new.example.com",
+					StatusCode:   http.StatusMovedPermanently,
+					PreservePath: true,
+				},
+			}))
+		})
+
+		It("responds with the redirect and does not call next", func() {
+			Expect(nextCalled).To(BeFalse())
+			Expect(resp.Code).To(Equal(http.StatusMovedPermanently))
+			Expect(resp.Header().Get("Location")).To(Equal("https://new.example.com/foo"))
+		})
+	})
+
+	Context("when the route is HTTPS only", func() {
+		BeforeEach(func() {
+			routePool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, HTTPSOnly: true}))
+		})
+
+		Context("when the request is plain HTTP", func() {
+			It("redirects to https", func() {
+				Expect(nextCalled).To(BeFalse())
+				Expect(resp.Code).To(Equal(http.StatusMovedPermanently))
+				Expect(resp.Header().Get("Location")).To(Equal("https://example.com/foo?bar=baz"))
+			})
+
+			Context("when the request is not a GET or HEAD", func() {
+				BeforeEach(func() {
+					req.Method = "POST"
+				})
+
+				It("redirects with a 308 to preserve the method", func() {
+					Expect(resp.Code).To(Equal(http.StatusPermanentRedirect))
+				})
+			})
+		})
+
+		Context("when the request uses TLS", func() {
+			BeforeEach(func() {
+				req.TLS = &tls.ConnectionState{}
+			})
+
+			It("calls next", func() {
+				Expect(nextCalled).To(BeTrue())
+			})
+		})
+
+		Context("when X-Forwarded-Proto is https", func() {
+			BeforeEach(func() {
+				req.Header.Set("X-Forwarded-Proto", "https")
+			})
+
+			It("calls next", func() {
+				Expect(nextCalled).To(BeTrue())
+			})
+
+			Context("when the forwarded proto is sanitized", func() {
+				BeforeEach(func() {
+					sanitizeForwardedProto = true
+				})
+
+				It("redirects to https", func() {
+					Expect(nextCalled).To(BeFalse())
+					Expect(resp.Code).To(Equal(http.StatusMovedPermanently))
+				})
+			})
+		})
+
+		Context("when the forwarded proto is forced to https", func() {
+			BeforeEach(func() {
+				forceForwardedProtoHttps = true
+			})
+
+			It("calls next", func() {
+				Expect(nextCalled).To(BeTrue())
+			})
+		})
+	})
+})
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	router_http "code.cloudfoundry.org/gorouter/common/http"
 	"code.cloudfoundry.org/gorouter/common/uuid"
 	"code.cloudfoundry.org/gorouter/logger"
 	"github.com/uber-go/zap"
@@ -10,7 +11,7 @@ import (
 )
 
 const (
-	VcapRequestIdHeader = "X-Vcap-Request-Id"
+	VcapRequestIdHeader = router_http.VcapRequestIdHeader
 )
 
 type setVcapRequestIdHeader struct {
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/registry"
+	"github.com/uber-go/zap"
+)
+
+const (
+	routeEventsBufferSize = 1024
+	routeEventsHeartbeat  = 15 * time.Second
+)
+
+type routeEvents struct {
+	registry  *registry.RouteRegistry
+	heartbeat time.Duration
+	logger    logger.Logger
+}
+
+// NewRouteEvents creates the status API handler that streams the changes of
+// the routing table as server-sent events
+func NewRouteEvents(registry *registry.RouteRegistry, logger logger.Logger) http.Handler {
+	return &routeEvents{
+		registry:  registry,
+		heartbeat: routeEventsHeartbeat,
+		logger:    logger,
+	}
+}
+
+func (h *routeEvents) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", "GET")
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	hijacker, ok := rw.(http.Hijacker)
+	if !ok {
+		http.Error(rw, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// the connection is hijacked so that the write timeout of the status
+	// server does not end the stream
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		h.logger.Error("route-events-hijack-failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Time{})
+
+	sub := h.registry.Subscribe(routeEventsBufferSize)
+	defer sub.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, buf)
+		close(closed)
+	}()
+
+	buf.WriteString("HTTP/1.1 200 OK\r\n" +
+		"Content-Type: text/event-stream\r\n" +
+		"Cache-Control: no-cache\r\n" +
+		"Connection: close\r\n\r\n")
+	if buf.Flush() != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.C:
+			if !ok {
+				h.logger.Info("route-events-subscriber-too-slow")
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				h.logger.Error("route-events-marshal-failed", zap.Error(err))
+				continue
+			}
+			fmt.Fprintf(buf, "event: %s\ndata: %s\n\n", event.Type, data)
+		case <-heartbeat.C:
+			buf.WriteString(": heartbeat\n\n")
+		case <-closed:
+			return
+		}
+
+		if buf.Flush() != nil {
+			return
+		}
+	}
+}
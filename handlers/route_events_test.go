@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/handlers"
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RouteEvents", func() {
+	var (
+		server *httptest.Server
+		logger logger.Logger
+		reg    *registry.RouteRegistry
+	)
+
+	BeforeEach(func() {
+		logger = test_util.NewTestZapLogger("route-events")
+		cfg, err := config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		reg = registry.NewRouteRegistry(logger, cfg, new(fakes.FakeRouteRegistryReporter))
+
+		server = httptest.NewServer(handlers.NewRouteEvents(reg, logger))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("streams the changes of the routing table", func() {
+		resp, err := http.Get(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+		endpoint := route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 1234})
+		reg.Register("example.com", endpoint)
+		reg.Unregister("example.com", endpoint)
+
+		reader := bufio.NewReader(resp.Body)
+		type event struct {
+			Type     string                 `json:"type"`
+			Uri      string                 `json:"uri"`
+			Endpoint map[string]interface{} `json:"endpoint"`
+		}
+		readEvent := func() (string, event) {
+			var eventType string
+			var e event
+			for {
+				line, err := reader.ReadString('\n')
+				Expect(err).ToNot(HaveOccurred())
+				line = strings.TrimSuffix(line, "\n")
+				switch {
+				case line == "":
+					return eventType, e
+				case strings.HasPrefix(line, "event: "):
+					eventType = strings.TrimPrefix(line, "event: ")
+				case strings.HasPrefix(line, "data: "):
+					Expect(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e)).To(Succeed())
+				}
+			}
+		}
+
+		types := []string{}
+		for i := 0; i < 4; i++ {
+			t, e := readEvent()
+			Expect(e.Type).To(Equal(t))
+			Expect(e.Uri).To(Equal("example.com"))
+			if t == "endpoint-added" {
+				Expect(e.Endpoint["address"]).To(Equal("192.168.1.1:1234"))
+			}
+			types = append(types, t)
+		}
+		Expect(types).To(Equal([]string{"route-added", "endpoint-added", "endpoint-removed", "route-removed"}))
+	})
+
+	It("only allows GET", func() {
+		resp, err := http.Post(server.URL, "text/plain", nil)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	router_http "code.cloudfoundry.org/gorouter/common/http"
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"github.com/uber-go/zap"
+)
+
+type routes struct {
+	registry *registry.RouteRegistry
+	logger   logger.Logger
+}
+
+// NewRoutes creates the status API handler that lists the routing table,
+// optionally filtered and paginated
+func NewRoutes(registry *registry.RouteRegistry, logger logger.Logger) http.Handler {
+	return &routes{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+type routeFilter struct {
+	hostPrefix       string
+	appID            string
+	isolationSegment string
+	address          string
+}
+
+func (f *routeFilter) filtersEndpoints() bool {
+	return f.appID != "" || f.isolationSegment != "" || f.address != ""
+}
+
+// endpoints returns the endpoints of the pool that match the filter.
+func (f *routeFilter) endpoints(pool *route.Pool) []route.EndpointView {
+	endpoints := []route.EndpointView{}
+	for _, e := range pool.EndpointViews() {
+		if f.appID != "" && e.ApplicationId != f.appID {
+			continue
+		}
+		if f.isolationSegment != "" && e.IsolationSegment != f.isolationSegment {
+			continue
+		}
+		if f.address != "" && e.CanonicalAddr() != f.address && !strings.HasPrefix(e.CanonicalAddr(), f.address+":") {
+			continue
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints
+}
+
+type routeEntry struct {
+	uri       route.Uri
+	endpoints []route.EndpointView
+}
+
+type byUri []routeEntry
+
+func (s byUri) Len() int           { return len(s) }
+func (s byUri) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byUri) Less(i, j int) bool { return s[i].uri < s[j].uri }
+
+func (h *routes) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Connection", "close")
+
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", "GET")
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := &routeFilter{
+		hostPrefix:       strings.ToLower(query.Get("host_prefix")),
+		appID:            query.Get("app_id"),
+		isolationSegment: query.Get("isolation_segment"),
+		address:          query.Get("address"),
+	}
+	cursor := route.Uri(query.Get("cursor"))
+
+	limit := 0
+	if l := query.Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			http.Error(rw, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var entries []routeEntry
+	h.registry.EachRoute(func(uri route.Uri, pool *route.Pool) {
+		if !strings.HasPrefix(string(uri), filter.hostPrefix) || (cursor != "" && uri <= cursor) {
+			return
+		}
+
+		endpoints := filter.endpoints(pool)
+		if len(endpoints) == 0 && filter.filtersEndpoints() {
+			return
+		}
+		entries = append(entries, routeEntry{uri: uri, endpoints: endpoints})
+	})
+
+	if query.Get("count") == "true" {
+		addresses := make(map[string]struct{})
+		for _, entry := range entries {
+			for _, e := range entry.endpoints {
+				addresses[e.CanonicalAddr()] = struct{}{}
+			}
+		}
+		h.writeJSON(rw, http.StatusOK, map[string]int{
+			"routes":    len(entries),
+			"endpoints": len(addresses),
+		})
+		return
+	}
+
+	if limit > 0 {
+		sort.Sort(byUri(entries))
+		if len(entries) > limit {
+			entries = entries[:limit]
+
+			next := *r.URL
+			nextQuery := next.Query()
+			nextQuery.Set("cursor", string(entries[limit-1].uri))
+			next.RawQuery = nextQuery.Encode()
+			rw.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+		}
+	}
+
+	routes := make(map[route.Uri][]route.EndpointView, len(entries))
+	for _, entry := range entries {
+		routes[entry.uri] = entry.endpoints
+	}
+	h.writeJSON(rw, http.StatusOK, routes)
+}
+
+func (h *routes) writeJSON(rw http.ResponseWriter, code int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+
+	err := json.NewEncoder(rw).Encode(v)
+	if err != nil {
+		h.logger.Error("routes-response-err", zap.Error(err))
+	}
+}
+
+type routeExplain struct {
+	registry *registry.RouteRegistry
+	logger   logger.Logger
+}
+
+// NewRouteExplain creates the status API handler that reports which route a
+// URL would be routed to
+func NewRouteExplain(registry *registry.RouteRegistry, logger logger.Logger) http.Handler {
+	return &routeExplain{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+type explanation struct {
+	Uri             route.Uri            `json:"uri"`
+	Route           route.Uri            `json:"route,omitempty"`
+	Wildcard        bool                 `json:"wildcard"`
+	RouteServiceUrl string               `json:"route_service_url,omitempty"`
+	Endpoints       []route.EndpointView `json:"endpoints"`
+	Maintenance     *route.Maintenance   `json:"maintenance,omitempty"`
+}
+
+func (h *routeExplain) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Connection", "close")
+
+	if r.Method != http.MethodGet {
+		rw.Header().Set("Allow", "GET")
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	rawurl := r.URL.Query().Get("url")
+	if rawurl == "" {
+		http.Error(rw, "url is required", http.StatusBadRequest)
+		return
+	}
+	if !strings.Contains(rawurl, "://") {
+		rawurl = "http://" + rawurl
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Host == "" {
+		http.Error(rw, "invalid url", http.StatusBadRequest)
+		return
+	}
+
+	e := &explanation{
+		Uri:       route.Uri(router_http.HostWithoutPort(u.Host) + u.EscapedPath()),
+		Endpoints: []route.EndpointView{},
+	}
+	e.Maintenance = h.registry.LookupMaintenance(e.Uri)
+
+	pool := h.registry.Lookup(e.Uri)
+	if pool == nil {
+		h.writeJSON(rw, http.StatusNotFound, e)
+		return
+	}
+
+	e.Route = route.Uri(pool.Host() + strings.TrimSuffix(pool.ContextPath(), "/"))
+	e.Wildcard = strings.HasPrefix(pool.Host(), "*.")
+	e.RouteServiceUrl = pool.RouteServiceUrl()
+	e.Endpoints = append(e.Endpoints, pool.EndpointViews()...)
+	h.writeJSON(rw, http.StatusOK, e)
+}
+
+func (h *routeExplain) writeJSON(rw http.ResponseWriter, code int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+
+	err := json.NewEncoder(rw).Encode(v)
+	if err != nil {
+		h.logger.Error("route-explain-response-err", zap.Error(err))
+	}
+}
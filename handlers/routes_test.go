@@ -0,0 +1,234 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/handlers"
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Routes", func() {
+	var (
+		handler http.Handler
+		logger  logger.Logger
+		reg     *registry.RouteRegistry
+		resp    *httptest.ResponseRecorder
+	)
+
+	get := func(path string) map[string][]map[string]interface{} {
+		req, err := http.NewRequest("GET", path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		handler.ServeHTTP(resp, req)
+		Expect(resp.Code).To(Equal(http.StatusOK))
+
+		routes := map[string][]map[string]interface{}{}
+		Expect(json.Unmarshal(resp.Body.Bytes(), &routes)).To(Succeed())
+		return routes
+	}
+
+	BeforeEach(func() {
+		logger = test_util.NewTestZapLogger("routes")
+		cfg, err := config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		reg = registry.NewRouteRegistry(logger, cfg, new(fakes.FakeRouteRegistryReporter))
+		resp = httptest.NewRecorder()
+
+		reg.Register("a.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-a", Host: "10.0.0.1", Port: 8080}))
+		reg.Register("a.example.com/api", route.NewEndpoint(&route.EndpointOpts{AppId: "app-a", Host: "10.0.0.1", Port: 8080}))
+		reg.Register("b.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-b", Host: "10.0.0.2", Port: 8080, IsolationSegment: "iso"}))
+		reg.Register("b.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-c", Host: "10.0.0.3", Port: 8080}))
+		reg.Register("*.other.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-d", Host: "10.0.0.4", Port: 8080}))
+
+		handler = handlers.NewRoutes(reg, logger)
+	})
+
+	It("lists all the routes", func() {
+		routes := get("/routes")
+		Expect(routes).To(HaveLen(4))
+		Expect(routes["b.example.com"]).To(HaveLen(2))
+	})
+
+	It("filters by host prefix", func() {
+		routes := get("/routes?host_prefix=A.example")
+		Expect(routes).To(HaveLen(2))
+		Expect(routes).To(HaveKey("a.example.com"))
+		Expect(routes).To(HaveKey("a.example.com/api"))
+	})
+
+	It("filters the endpoints by app id", func() {
+		routes := get("/routes?app_id=app-c")
+		Expect(routes).To(HaveLen(1))
+		Expect(routes["b.example.com"]).To(HaveLen(1))
+		Expect(routes["b.example.com"][0]["address"]).To(Equal("10.0.0.3:8080"))
+	})
+
+	It("reports the state of the filtered endpoints in their pool", func() {
+		reg.Register("a.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-a", Host: "10.0.0.1", Port: 8080, Source: route.SourceRoutingAPI}))
+
+		routes := get("/routes?app_id=app-a")
+		Expect(routes["a.example.com"]).To(HaveLen(1))
+		Expect(routes["a.example.com"][0]["sources"]).To(Equal([]interface{}{"routing-api"}))
+	})
+
+	It("lists the sources that registered an endpoint", func() {
+		reg.Register("c.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-e", Host: "10.0.0.5", Port: 8080, Source: route.SourceRoutingAPI}))
+		reg.Register("c.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-e", Host: "10.0.0.5", Port: 8080, Source: route.SourceNATS}))
+
+		routes := get("/routes?host_prefix=c.example.com")
+		Expect(routes["c.example.com"]).To(HaveLen(1))
+		Expect(routes["c.example.com"][0]["sources"]).To(Equal([]interface{}{"nats", "routing-api"}))
+	})
+
+	Context("when slow start is configured", func() {
+		BeforeEach(func() {
+			cfg, err := config.DefaultConfig()
+			Expect(err).ToNot(HaveOccurred())
+			cfg.SlowStart.Window = time.Hour
+			reg = registry.NewRouteRegistry(logger, cfg, new(fakes.FakeRouteRegistryReporter))
+			handler = handlers.NewRoutes(reg, logger)
+		})
+
+		It("shows the weight of endpoints that are warming up", func() {
+			reg.Register("c.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-e", Host: "10.0.0.5", Port: 8080}))
+
+			routes := get("/routes")
+			Expect(routes["c.example.com"]).To(HaveLen(1))
+			Expect(routes["c.example.com"][0]["slow_start_weight"]).To(BeNumerically("~", 0.1, 0.01))
+		})
+	})
+
+	Context("when endpoints drain", func() {
+		BeforeEach(func() {
+			cfg, err := config.DefaultConfig()
+			Expect(err).ToNot(HaveOccurred())
+			cfg.EndpointDrainTimeout = time.Hour
+			reg = registry.NewRouteRegistry(logger, cfg, new(fakes.FakeRouteRegistryReporter))
+			handler = handlers.NewRoutes(reg, logger)
+		})
+
+		It("shows the draining endpoints", func() {
+			endpoint := route.NewEndpoint(&route.EndpointOpts{AppId: "app-e", Host: "10.0.0.5", Port: 8080})
+			reg.Register("c.example.com", endpoint)
+			reg.Register("c.example.com", route.NewEndpoint(&route.EndpointOpts{AppId: "app-e", Host: "10.0.0.6", Port: 8080}))
+			reg.Unregister("c.example.com", endpoint)
+
+			routes := get("/routes")
+			Expect(routes["c.example.com"]).To(HaveLen(2))
+			Expect(routes["c.example.com"][0]["address"]).To(Equal("10.0.0.5:8080"))
+			Expect(routes["c.example.com"][0]["draining"]).To(BeTrue())
+			Expect(routes["c.example.com"][1]).ToNot(HaveKey("draining"))
+		})
+	})
+
+	It("filters the endpoints by isolation segment", func() {
+		routes := get("/routes?isolation_segment=iso")
+		Expect(routes).To(HaveLen(1))
+		Expect(routes["b.example.com"][0]["address"]).To(Equal("10.0.0.2:8080"))
+	})
+
+	It("filters the endpoints by address, with or without the port", func() {
+		Expect(get("/routes?address=10.0.0.1")).To(HaveLen(2))
+		resp = httptest.NewRecorder()
+		Expect(get("/routes?address=10.0.0.1:8080")).To(HaveLen(2))
+		resp = httptest.NewRecorder()
+		Expect(get("/routes?address=10.0.0.1:9090")).To(BeEmpty())
+	})
+
+	It("paginates the routes in order", func() {
+		routes := get("/routes?limit=3&host_prefix=")
+		Expect(routes).To(HaveLen(3))
+		Expect(routes).To(HaveKey("*.other.com"))
+		Expect(routes).To(HaveKey("a.example.com"))
+		Expect(routes).To(HaveKey("a.example.com/api"))
+		link := resp.Header().Get("Link")
+		Expect(link).To(Equal(`</routes?cursor=a.example.com%2Fapi&host_prefix=&limit=3>; rel="next"`))
+
+		resp = httptest.NewRecorder()
+		routes = get("/routes?cursor=a.example.com%2Fapi&host_prefix=&limit=3")
+		Expect(routes).To(HaveLen(1))
+		Expect(routes).To(HaveKey("b.example.com"))
+		Expect(resp.Header().Get("Link")).To(BeEmpty())
+	})
+
+	It("rejects an invalid limit", func() {
+		req, err := http.NewRequest("GET", "/routes?limit=0", nil)
+		Expect(err).ToNot(HaveOccurred())
+		handler.ServeHTTP(resp, req)
+		Expect(resp.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("only counts the routes and endpoints", func() {
+		req, err := http.NewRequest("GET", "/routes?count=true&host_prefix=a.", nil)
+		Expect(err).ToNot(HaveOccurred())
+		handler.ServeHTTP(resp, req)
+
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(MatchJSON(`{"routes":2,"endpoints":1}`))
+	})
+
+	Describe("explain", func() {
+		explain := func(u string) (int, map[string]interface{}) {
+			req, err := http.NewRequest("GET", "/routes/explain?url="+u, nil)
+			Expect(err).ToNot(HaveOccurred())
+			handlers.NewRouteExplain(reg, logger).ServeHTTP(resp, req)
+
+			e := map[string]interface{}{}
+			Expect(json.Unmarshal(resp.Body.Bytes(), &e)).To(Succeed())
+			return resp.Code, e
+		}
+
+		It("reports the route that matches the url", func() {
+			code, e := explain("https%3A%2F%2Fa.example.com%3A443%2Fapi%2Fusers%3Fid%3D1")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(e["uri"]).To(Equal("a.example.com/api/users"))
+			Expect(e["route"]).To(Equal("a.example.com/api"))
+			Expect(e["wildcard"]).To(BeFalse())
+			Expect(e["endpoints"]).To(HaveLen(1))
+		})
+
+		It("reports the state of the endpoints in the pool", func() {
+			reg.Register("a.example.com/api", route.NewEndpoint(&route.EndpointOpts{AppId: "app-a", Host: "10.0.0.1", Port: 8080, Source: route.SourceRoutingAPI}))
+
+			_, e := explain("a.example.com/api")
+			Expect(e["endpoints"]).To(HaveLen(1))
+			endpoint := e["endpoints"].([]interface{})[0].(map[string]interface{})
+			Expect(endpoint["sources"]).To(Equal([]interface{}{"routing-api"}))
+		})
+
+		It("reports wildcard matches", func() {
+			code, e := explain("app.other.com/foo")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(e["route"]).To(Equal("*.other.com"))
+			Expect(e["wildcard"]).To(BeTrue())
+		})
+
+		It("reports maintenance mode", func() {
+			reg.SetMaintenance(&route.Maintenance{Route: "a.example.com"})
+			_, e := explain("a.example.com")
+			Expect(e["maintenance"]).ToNot(BeNil())
+		})
+
+		It("returns 404 when no route matches", func() {
+			code, e := explain("missing.com")
+			Expect(code).To(Equal(http.StatusNotFound))
+			Expect(e["route"]).To(BeNil())
+		})
+
+		It("requires a url", func() {
+			req, err := http.NewRequest("GET", "/routes/explain", nil)
+			Expect(err).ToNot(HaveOccurred())
+			handlers.NewRouteExplain(reg, logger).ServeHTTP(resp, req)
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+})
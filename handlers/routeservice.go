@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"net/url"
 
+	router_http "code.cloudfoundry.org/gorouter/common/http"
+	"code.cloudfoundry.org/gorouter/errorpage"
 	"code.cloudfoundry.org/gorouter/logger"
 	"code.cloudfoundry.org/gorouter/registry"
 	"code.cloudfoundry.org/gorouter/routeservice"
@@ -16,17 +18,19 @@ import (
 )
 
 type routeService struct {
-	config   *routeservice.RouteServiceConfig
-	logger   logger.Logger
-	registry registry.Registry
+	config     *routeservice.RouteServiceConfig
+	logger     logger.Logger
+	registry   registry.Registry
+	errorPages *errorpage.Pages
 }
 
 // NewRouteService creates a handler responsible for handling route services
-func NewRouteService(config *routeservice.RouteServiceConfig, logger logger.Logger, routeRegistry registry.Registry) negroni.Handler {
+func NewRouteService(config *routeservice.RouteServiceConfig, logger logger.Logger, routeRegistry registry.Registry, errorPages *errorpage.Pages) negroni.Handler {
 	return &routeService{
-		config:   config,
-		logger:   logger,
-		registry: routeRegistry,
+		config:     config,
+		logger:     logger,
+		registry:   routeRegistry,
+		errorPages: errorPages,
 	}
 }
 
@@ -49,8 +53,10 @@ func (r *routeService) ServeHTTP(rw http.ResponseWriter, req *http.Request, next
 		rw.Header().Set("X-Cf-RouterError", "route_service_unsupported")
 		writeStatus(
 			rw,
+			req,
 			http.StatusBadGateway,
 			"Support for route services is disabled.",
+			r.errorPages,
 			r.logger,
 		)
 		return
@@ -61,8 +67,10 @@ func (r *routeService) ServeHTTP(rw http.ResponseWriter, req *http.Request, next
 		rw.Header().Set("X-Cf-RouterError", "route_service_unsupported")
 		writeStatus(
 			rw,
+			req,
 			http.StatusServiceUnavailable,
 			"TCP requests are not supported for routes bound to Route Services.",
+			r.errorPages,
 			r.logger,
 		)
 		return
@@ -73,8 +81,10 @@ func (r *routeService) ServeHTTP(rw http.ResponseWriter, req *http.Request, next
 		rw.Header().Set("X-Cf-RouterError", "route_service_unsupported")
 		writeStatus(
 			rw,
+			req,
 			http.StatusServiceUnavailable,
 			"Websocket requests are not supported for routes bound to Route Services.",
+			r.errorPages,
 			r.logger,
 		)
 		return
@@ -82,38 +92,19 @@ func (r *routeService) ServeHTTP(rw http.ResponseWriter, req *http.Request, next
 
 	if routeServiceURL != "" {
 		rsSignature := req.Header.Get(routeservice.HeaderKeySignature)
-
-		var recommendedScheme string
-
-		if r.config.RouteServiceRecommendHttps() {
-			recommendedScheme = "https"
-		} else {
-			recommendedScheme = "http"
-		}
-
-		forwardedURLRaw := recommendedScheme + "://" + hostWithoutPort(req.Host) + req.RequestURI
+		forwardedURLRaw := forwardedURL(r.config, req)
 		if hasBeenToRouteService(routeServiceURL, rsSignature) {
 			// A request from a route service destined for a backend instances
-			validatedSig, err := r.config.ValidatedSignature(&req.Header, forwardedURLRaw)
-			if err != nil {
-				r.logger.Error("signature-validation-failed", zap.Error(err))
-
-				writeStatus(
-					rw,
-					http.StatusBadRequest,
-					"Failed to validate Route Service Signature",
-					r.logger,
-				)
-				return
-			}
-			err = r.validateRouteServicePool(validatedSig, reqInfo)
+			err := validateRouteServiceSignature(r.config, r.registry, req, reqInfo)
 			if err != nil {
 				r.logger.Error("signature-validation-failed", zap.Error(err))
 
 				writeStatus(
 					rw,
+					req,
 					http.StatusBadRequest,
 					"Failed to validate Route Service Signature",
+					r.errorPages,
 					r.logger,
 				)
 				return
@@ -130,8 +121,10 @@ func (r *routeService) ServeHTTP(rw http.ResponseWriter, req *http.Request, next
 
 				writeStatus(
 					rw,
+					req,
 					http.StatusInternalServerError,
 					"Route service request failed.",
+					r.errorPages,
 					r.logger,
 				)
 				return
@@ -143,7 +136,7 @@ func (r *routeService) ServeHTTP(rw http.ResponseWriter, req *http.Request, next
 			reqInfo.RouteServiceURL = routeServiceArgs.ParsedUrl
 
 			rsu := routeServiceArgs.ParsedUrl
-			uri := route.Uri(hostWithoutPort(rsu.Host) + rsu.EscapedPath())
+			uri := route.Uri(router_http.HostWithoutPort(rsu.Host) + rsu.EscapedPath())
 			if r.registry.Lookup(uri) != nil {
 				reqInfo.IsInternalRouteService = true
 			}
@@ -153,13 +146,36 @@ func (r *routeService) ServeHTTP(rw http.ResponseWriter, req *http.Request, next
 	next(rw, req)
 }
 
-func (r *routeService) validateRouteServicePool(validatedSig *routeservice.Signature, reqInfo *RequestInfo) error {
+// forwardedURL returns the URL a route service forwards the request to
+func forwardedURL(config *routeservice.RouteServiceConfig, req *http.Request) string {
+	var recommendedScheme string
+
+	if config.RouteServiceRecommendHttps() {
+		recommendedScheme = "https"
+	} else {
+		recommendedScheme = "http"
+	}
+
+	return recommendedScheme + "://" + router_http.HostWithoutPort(req.Host) + req.RequestURI
+}
+
+// validateRouteServiceSignature checks that a request coming back from a
+// route service carries a valid signature for the route it is sent to
+func validateRouteServiceSignature(config *routeservice.RouteServiceConfig, registry registry.Registry, req *http.Request, reqInfo *RequestInfo) error {
+	validatedSig, err := config.ValidatedSignature(&req.Header, forwardedURL(config, req))
+	if err != nil {
+		return err
+	}
+	return validateRouteServicePool(registry, validatedSig, reqInfo)
+}
+
+func validateRouteServicePool(registry registry.Registry, validatedSig *routeservice.Signature, reqInfo *RequestInfo) error {
 	forwardedURL, err := url.Parse(validatedSig.ForwardedUrl)
 	if err != nil {
 		return err
 	}
-	uri := route.Uri(hostWithoutPort(forwardedURL.Host) + forwardedURL.EscapedPath())
-	forwardedPool := r.registry.Lookup(uri)
+	uri := route.Uri(router_http.HostWithoutPort(forwardedURL.Host) + forwardedURL.EscapedPath())
+	forwardedPool := registry.Lookup(uri)
 	if forwardedPool == nil {
 		return fmt.Errorf("original request URL %s does not exist in the routing table", uri.String())
 	}
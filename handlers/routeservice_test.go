@@ -104,7 +104,7 @@ var _ = Describe("Route Service Handler", func() {
 		handler = negroni.New()
 		handler.Use(handlers.NewRequestInfo())
 		handler.UseFunc(testSetupHandler)
-		handler.Use(handlers.NewRouteService(config, fakeLogger, reg))
+		handler.Use(handlers.NewRouteService(config, fakeLogger, reg, nil))
 		handler.UseHandlerFunc(nextHandler)
 	})
 
@@ -564,7 +564,7 @@ var _ = Describe("Route Service Handler", func() {
 		var badHandler *negroni.Negroni
 		BeforeEach(func() {
 			badHandler = negroni.New()
-			badHandler.Use(handlers.NewRouteService(config, fakeLogger, reg))
+			badHandler.Use(handlers.NewRouteService(config, fakeLogger, reg, nil))
 			badHandler.UseHandlerFunc(nextHandler)
 		})
 		It("calls Fatal on the logger", func() {
@@ -579,7 +579,7 @@ var _ = Describe("Route Service Handler", func() {
 		BeforeEach(func() {
 			badHandler = negroni.New()
 			badHandler.Use(handlers.NewRequestInfo())
-			badHandler.Use(handlers.NewRouteService(config, fakeLogger, reg))
+			badHandler.Use(handlers.NewRouteService(config, fakeLogger, reg, nil))
 			badHandler.UseHandlerFunc(nextHandler)
 		})
 		It("calls Fatal on the logger", func() {
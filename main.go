@@ -18,6 +18,7 @@ import (
 	"code.cloudfoundry.org/gorouter/route_fetcher"
 	"code.cloudfoundry.org/gorouter/router"
 	"code.cloudfoundry.org/gorouter/routeservice"
+	"code.cloudfoundry.org/gorouter/routesource"
 	rvarz "code.cloudfoundry.org/gorouter/varz"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/routing-api"
@@ -95,9 +96,14 @@ func main() {
 		debugserver.Run(c.DebugAddr, reconfigurableSink)
 	}
 
-	logger.Info("setting-up-nats-connection")
+	var natsClient *nats.Conn
 	natsReconnected := make(chan mbus.Signal)
-	natsClient := mbus.Connect(c, natsReconnected, logger.Session("nats"))
+	if c.NatsEnabled() {
+		logger.Info("setting-up-nats-connection")
+		natsClient = mbus.Connect(c, natsReconnected, logger.Session("nats"))
+	} else {
+		logger.Info("nats-disabled")
+	}
 
 	var routingAPIClient routing_api.Client
 
@@ -115,9 +121,23 @@ func main() {
 	metricsReporter := initializeMetrics(sender)
 	fdMonitor := initializeFDMonitor(sender, logger)
 	registry := rregistry.NewRouteRegistry(logger.Session("registry"), c, metricsReporter)
-	if c.SuspendPruningIfNatsUnavailable {
+	if c.SuspendPruningIfNatsUnavailable && natsClient != nil {
 		registry.SuspendPruning(func() bool { return !(natsClient.Status() == nats.CONNECTED) })
 	}
+	if len(c.DomainOwnership.Rules) > 0 || c.DomainOwnership.File != "" {
+		domainPolicy, err := rregistry.LoadDomainPolicy(c.DomainOwnership.Rules, c.DomainOwnership.File)
+		if err != nil {
+			logger.Fatal("error-loading-domain-policy", zap.Error(err))
+		}
+		registry.SetDomainPolicy(domainPolicy)
+	}
+	restoredEndpoints := 0
+	if c.RouteSnapshot.File != "" {
+		restoredEndpoints, err = registry.LoadSnapshot(c.RouteSnapshot.File)
+		if err != nil {
+			logger.Error("error-loading-route-snapshot", zap.Error(err))
+		}
+	}
 
 	varz := rvarz.NewVarz(registry)
 	compositeReporter := &metrics.CompositeReporter{VarzReporter: varz, ProxyReporter: metricsReporter}
@@ -162,6 +182,7 @@ func main() {
 	if err != nil {
 		logger.Fatal("initialize-router-error", zap.Error(err))
 	}
+	router.SetRestoredEndpoints(restoredEndpoints)
 
 	members := grouper.Members{}
 
@@ -170,13 +191,29 @@ func main() {
 		members = append(members, grouper.Member{Name: "router-fetcher", Runner: routeFetcher})
 	}
 
-	subscriber := mbus.NewSubscriber(natsClient, registry, c, natsReconnected, logger.Session("subscriber"))
-	natsMonitor := initializeNATSMonitor(subscriber, sender, logger)
-
 	members = append(members, grouper.Member{Name: "fdMonitor", Runner: fdMonitor})
-	members = append(members, grouper.Member{Name: "subscriber", Runner: subscriber})
-	members = append(members, grouper.Member{Name: "natsMonitor", Runner: natsMonitor})
+	if natsClient != nil {
+		subscriber := mbus.NewSubscriber(natsClient, registry, metricsReporter, c, natsReconnected, logger.Session("subscriber"))
+		natsMonitor := initializeNATSMonitor(subscriber, sender, logger)
+		members = append(members, grouper.Member{Name: "subscriber", Runner: subscriber})
+		members = append(members, grouper.Member{Name: "natsMonitor", Runner: natsMonitor})
+	}
 	members = append(members, grouper.Member{Name: "router", Runner: router})
+	if c.RouteSnapshot.File != "" {
+		snapshotter := rregistry.NewSnapshotter(registry, c.RouteSnapshot.File, c.RouteSnapshot.Interval, clock.NewClock(), logger.Session("route-snapshot"))
+		members = append(members, grouper.Member{Name: "routeSnapshot", Runner: snapshotter})
+	}
+	routeSources, err := routesource.NewManager(c, registry, logger.Session("route-source"))
+	if err != nil {
+		logger.Fatal("error-creating-route-sources", zap.Error(err))
+	}
+	if len(routeSources.Sources()) > 0 {
+		members = append(members, grouper.Member{Name: "routeSources", Runner: routeSources})
+	}
+	if c.DomainOwnership.File != "" {
+		domainPolicyReloader := rregistry.NewDomainPolicyReloader(registry, c.DomainOwnership.Rules, c.DomainOwnership.File, c.DomainOwnership.ReloadInterval, clock.NewClock(), logger.Session("domain-policy"))
+		members = append(members, grouper.Member{Name: "domainPolicy", Runner: domainPolicyReloader})
+	}
 
 	group := grouper.NewOrdered(os.Interrupt, members)
 
@@ -1139,6 +1139,36 @@ var _ = Describe("Router Integration", func() {
 		})
 	})
 
+	Context("when no NATS servers are configured", func() {
+		It("starts with the routes of the routes file", func() {
+			routesFile := filepath.Join(tmpdir, "routes.yml")
+			Expect(ioutil.WriteFile(routesFile, []byte("routes:\n- host: legacy.example.com\n  endpoints:\n  - host: 127.0.0.1\n    port: 8080\n"), 0644)).To(Succeed())
+
+			tempCfg := createConfig(cfgFile, defaultPruneInterval, defaultPruneThreshold, 0, false, 0, natsPort)
+			tempCfg.RouteSources.File.Path = routesFile
+			cfgBytes, err := yaml.Marshal(tempCfg)
+			Expect(err).ToNot(HaveOccurred())
+			// an empty list of servers is omitted when marshalled
+			cfgBytes = append(cfgBytes, []byte("nats: []\n")...)
+			Expect(ioutil.WriteFile(cfgFile, cfgBytes, os.ModePerm)).To(Succeed())
+
+			natsRunner.Stop()
+			natsRunner = nil
+
+			session, err := Start(exec.Command(gorouterPath, "-c", cfgFile), GinkgoWriter, GinkgoWriter)
+			Expect(err).ToNot(HaveOccurred())
+			gorouterSession = session
+			Eventually(session, 10*time.Second).Should(Say("nats-disabled"))
+			Eventually(session, 10*time.Second).Should(Say("gorouter.started"))
+
+			routesUri := fmt.Sprintf("http://%s:%s@%s:%d/routes", tempCfg.Status.User, tempCfg.Status.Pass, localIP, statusPort)
+			Eventually(func() (bool, error) {
+				return routeExists(routesUri, "legacy.example.com")
+			}).Should(BeTrue())
+			stopGorouter(session)
+		})
+	})
+
 	Context("when routing api is disabled", func() {
 		BeforeEach(func() {
 			cfg = createConfig(cfgFile, defaultPruneInterval, defaultPruneThreshold, 0, false, 0, natsPort)
@@ -3,6 +3,7 @@ package mbus
 import (
 	"errors"
 	"net/url"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -26,6 +27,7 @@ func Connect(c *config.Config, reconnected chan<- Signal, l logger.Logger) *nats
 	var err error
 
 	options := natsOptions(l, c, &natsHost, reconnected)
+	servers := zap.String("servers", strings.Join(redactedServers(options.Servers), ","))
 	attempts := 3
 	for attempts > 0 {
 		natsClient, err = options.Connect()
@@ -33,12 +35,13 @@ func Connect(c *config.Config, reconnected chan<- Signal, l logger.Logger) *nats
 			break
 		} else {
 			attempts--
+			l.Error("nats-connection-attempt-failed", zap.Error(err), servers, zap.Bool("tls", options.Secure), zap.Int("attempts-left", attempts))
 			time.Sleep(100 * time.Millisecond)
 		}
 	}
 
 	if err != nil {
-		l.Fatal("nats-connection-error", zap.Error(err))
+		l.Fatal("nats-connection-error", zap.Error(err), servers, zap.Bool("tls", options.Secure))
 	}
 
 	var natsHostStr string
@@ -60,6 +63,10 @@ func natsOptions(l logger.Logger, c *config.Config, natsHost *atomic.Value, reco
 	options.Servers = natsServers
 	options.PingInterval = c.NatsClientPingInterval
 	options.MaxReconnect = -1
+	if c.NatsTLSConfig != nil {
+		options.Secure = true
+		options.TLSConfig = c.NatsTLSConfig
+	}
 	notDisconnected := make(chan Signal)
 
 	options.ClosedCB = func(conn *nats.Conn) {
@@ -106,3 +113,18 @@ func natsOptions(l logger.Logger, c *config.Config, natsHost *atomic.Value, reco
 
 	return options
 }
+
+// redactedServers strips the credentials from the server URLs so that they
+// can be logged.
+func redactedServers(servers []string) []string {
+	redacted := make([]string, 0, len(servers))
+	for _, s := range servers {
+		u, err := url.Parse(s)
+		if err != nil {
+			continue
+		}
+		u.User = nil
+		redacted = append(redacted, u.String())
+	}
+	return redacted
+}
@@ -0,0 +1,185 @@
+package mbus
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"golang.org/x/crypto/ed25519"
+)
+
+// MessageSignature is carried in the signature field of a registry message.
+// The value is the base64 encoded signature of the canonical payload, made
+// with the key of the given id.
+type MessageSignature struct {
+	KeyId string `json:"key_id"`
+	Value string `json:"value"`
+}
+
+// MessageVerifier checks the signatures of registry messages against the
+// configured keys. Several keys can be configured at once so that publishers
+// can move to a new key before the old one is removed.
+type MessageVerifier struct {
+	keys     map[string]config.NatsSigningKey
+	required bool
+	maxAge   time.Duration
+}
+
+func NewMessageVerifier(c config.NatsSigningConfig) *MessageVerifier {
+	keys := make(map[string]config.NatsSigningKey, len(c.Keys))
+	for _, k := range c.Keys {
+		keys[k.Id] = k
+	}
+	return &MessageVerifier{keys: keys, required: c.Required, maxAge: c.MaxAge}
+}
+
+// Verify returns an error if the message is signed with an unknown key, the
+// signature does not match the message and the subject it was published on,
+// or its signed timestamp is further than the max age from now. Unsigned
+// messages are rejected as well when signatures are required. Without keys
+// every message is accepted.
+func (v *MessageVerifier) Verify(subject string, data []byte) error {
+	if len(v.keys) == 0 {
+		return nil
+	}
+
+	fields, err := decodeMessage(data)
+	if err != nil {
+		return err
+	}
+
+	raw, ok := fields["signature"]
+	if !ok {
+		if v.required {
+			return errors.New("message is not signed")
+		}
+		return nil
+	}
+	delete(fields, "signature")
+
+	sig, err := parseSignature(raw)
+	if err != nil {
+		return err
+	}
+
+	key, ok := v.keys[sig.KeyId]
+	if !ok {
+		return fmt.Errorf("message is signed with unknown key %q", sig.KeyId)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(sig.Value)
+	if err != nil {
+		return errors.New("message signature is not base64 encoded")
+	}
+
+	payload, err := signedPayload(subject, fields)
+	if err != nil {
+		return err
+	}
+
+	if !verifySignature(key, payload, signature) {
+		return fmt.Errorf("message signature does not match key %q", sig.KeyId)
+	}
+	return v.verifyTimestamp(fields["timestamp"], time.Now())
+}
+
+// verifyTimestamp rejects messages whose timestamp, in seconds since the
+// epoch, is not within the max age of now, so that captured messages cannot
+// be replayed later.
+func (v *MessageVerifier) verifyTimestamp(raw interface{}, now time.Time) error {
+	if raw == nil {
+		return errors.New("message has no timestamp")
+	}
+	n, ok := raw.(json.Number)
+	if !ok {
+		return errors.New("message timestamp must be a number")
+	}
+	seconds, err := n.Int64()
+	if err != nil {
+		return errors.New("message timestamp must be an integer")
+	}
+
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > v.maxAge {
+		return fmt.Errorf("message is older than %s", v.maxAge)
+	}
+	if age < -v.maxAge {
+		return fmt.Errorf("message timestamp is more than %s in the future", v.maxAge)
+	}
+	return nil
+}
+
+// CanonicalPayload returns the bytes a registry message published on the
+// subject is signed over: the subject and a newline, followed by the message
+// without its signature field, with the keys of all objects sorted and
+// without insignificant whitespace or HTML escaping.
+func CanonicalPayload(subject string, data []byte) ([]byte, error) {
+	fields, err := decodeMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	delete(fields, "signature")
+	return signedPayload(subject, fields)
+}
+
+func signedPayload(subject string, fields map[string]interface{}) ([]byte, error) {
+	body, err := encodeCanonical(fields)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(subject+"\n"), body...), nil
+}
+
+func decodeMessage(data []byte) (map[string]interface{}, error) {
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&fields)
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func encodeCanonical(fields map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(fields)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func parseSignature(raw interface{}) (MessageSignature, error) {
+	var sig MessageSignature
+	fields, ok := raw.(map[string]interface{})
+	if !ok {
+		return sig, errors.New("message signature must be an object")
+	}
+	sig.KeyId, _ = fields["key_id"].(string)
+	sig.Value, _ = fields["value"].(string)
+	if sig.KeyId == "" || sig.Value == "" {
+		return sig, errors.New("message signature must have a key_id and a value")
+	}
+	return sig, nil
+}
+
+func verifySignature(key config.NatsSigningKey, payload, signature []byte) bool {
+	switch key.Algorithm {
+	case config.SIGNING_HMAC_SHA256:
+		mac := hmac.New(sha256.New, key.Key)
+		mac.Write(payload)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case config.SIGNING_ED25519:
+		return ed25519.Verify(ed25519.PublicKey(key.Key), payload, signature)
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package mbus_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/mbus"
+	"golang.org/x/crypto/ed25519"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MessageVerifier", func() {
+	var (
+		verifier   *mbus.MessageVerifier
+		privateKey ed25519.PrivateKey
+		data       []byte
+	)
+
+	withSignature := func(data []byte, keyId string, signature []byte) []byte {
+		var fields map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		Expect(decoder.Decode(&fields)).To(Succeed())
+		fields["signature"] = mbus.MessageSignature{KeyId: keyId, Value: base64.StdEncoding.EncodeToString(signature)}
+		signed, err := json.Marshal(fields)
+		Expect(err).ToNot(HaveOccurred())
+		return signed
+	}
+
+	hmacSign := func(data []byte, keyId, secret string) []byte {
+		payload, err := mbus.CanonicalPayload("router.register", data)
+		Expect(err).ToNot(HaveOccurred())
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(payload)
+		return withSignature(data, keyId, mac.Sum(nil))
+	}
+
+	ed25519Sign := func(data []byte, keyId string) []byte {
+		payload, err := mbus.CanonicalPayload("router.register", data)
+		Expect(err).ToNot(HaveOccurred())
+		return withSignature(data, keyId, ed25519.Sign(privateKey, payload))
+	}
+
+	BeforeEach(func() {
+		var (
+			publicKey ed25519.PublicKey
+			err       error
+		)
+		publicKey, privateKey, err = ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		verifier = mbus.NewMessageVerifier(config.NatsSigningConfig{
+			Keys: []config.NatsSigningKey{
+				{Id: "hmac", Algorithm: config.SIGNING_HMAC_SHA256, Key: []byte("secret")},
+				{Id: "ed", Algorithm: config.SIGNING_ED25519, Key: publicKey},
+			},
+			Required: true,
+			MaxAge:   time.Minute,
+		})
+
+		data = []byte(fmt.Sprintf(`{"host":"192.168.1.1","port":8080,"uris":["foo.example.com"],"tags":{"b":"2","a":"1"},"endpoint_updated_at_ns":1504112345123456789,"route_service_url":"https://rs.example.com/?a=1&b=2","timestamp":%d}`, time.Now().Unix()))
+	})
+
+	It("accepts messages signed with any of the keys", func() {
+		Expect(verifier.Verify("router.register", hmacSign(data, "hmac", "secret"))).To(Succeed())
+		Expect(verifier.Verify("router.register", ed25519Sign(data, "ed"))).To(Succeed())
+	})
+
+	It("rejects messages signed with the wrong key", func() {
+		Expect(verifier.Verify("router.register", hmacSign(data, "hmac", "other-secret"))).To(MatchError(`message signature does not match key "hmac"`))
+		Expect(verifier.Verify("router.register", hmacSign(data, "ed", "secret"))).To(MatchError(`message signature does not match key "ed"`))
+	})
+
+	It("rejects messages signed with an unknown key", func() {
+		Expect(verifier.Verify("router.register", hmacSign(data, "retired", "secret"))).To(MatchError(`message is signed with unknown key "retired"`))
+	})
+
+	It("rejects messages that were changed after signing", func() {
+		signed := hmacSign(data, "hmac", "secret")
+		var fields map[string]interface{}
+		Expect(json.Unmarshal(signed, &fields)).To(Succeed())
+		fields["host"] = "10.0.0.1"
+		tampered, err := json.Marshal(fields)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(verifier.Verify("router.register", tampered)).To(HaveOccurred())
+	})
+
+	It("rejects messages replayed on another subject", func() {
+		Expect(verifier.Verify("router.unregister", hmacSign(data, "hmac", "secret"))).To(MatchError(`message signature does not match key "hmac"`))
+		Expect(verifier.Verify("router.unregister", ed25519Sign(data, "ed"))).To(MatchError(`message signature does not match key "ed"`))
+	})
+
+	It("rejects signed messages without a timestamp", func() {
+		data = []byte(`{"host":"192.168.1.1","port":8080,"uris":["foo.example.com"]}`)
+		Expect(verifier.Verify("router.register", hmacSign(data, "hmac", "secret"))).To(MatchError("message has no timestamp"))
+	})
+
+	It("rejects expired messages", func() {
+		data = []byte(fmt.Sprintf(`{"host":"192.168.1.1","port":8080,"uris":["foo.example.com"],"timestamp":%d}`, time.Now().Add(-2*time.Minute).Unix()))
+		Expect(verifier.Verify("router.register", hmacSign(data, "hmac", "secret"))).To(MatchError("message is older than 1m0s"))
+	})
+
+	It("rejects messages from the future", func() {
+		data = []byte(fmt.Sprintf(`{"host":"192.168.1.1","port":8080,"uris":["foo.example.com"],"timestamp":%d}`, time.Now().Add(2*time.Minute).Unix()))
+		Expect(verifier.Verify("router.register", hmacSign(data, "hmac", "secret"))).To(MatchError("message timestamp is more than 1m0s in the future"))
+	})
+
+	It("rejects malformed signatures", func() {
+		Expect(verifier.Verify("router.register", []byte(`{"host":"h","signature":"abc"}`))).To(MatchError("message signature must be an object"))
+		Expect(verifier.Verify("router.register", []byte(`{"host":"h","signature":{"key_id":"hmac"}}`))).To(MatchError("message signature must have a key_id and a value"))
+		Expect(verifier.Verify("router.register", []byte(`{"host":"h","signature":{"key_id":"hmac","value":"%%%"}}`))).To(MatchError("message signature is not base64 encoded"))
+	})
+
+	It("rejects unsigned messages when signatures are required", func() {
+		Expect(verifier.Verify("router.register", data)).To(MatchError("message is not signed"))
+	})
+
+	It("accepts unsigned messages when signatures are not required", func() {
+		verifier = mbus.NewMessageVerifier(config.NatsSigningConfig{
+			Keys: []config.NatsSigningKey{{Id: "hmac", Algorithm: config.SIGNING_HMAC_SHA256, Key: []byte("secret")}},
+		})
+		Expect(verifier.Verify("router.register", data)).To(Succeed())
+		Expect(verifier.Verify("router.register", hmacSign(data, "hmac", "other-secret"))).To(HaveOccurred())
+	})
+
+	It("accepts every message without keys", func() {
+		verifier = mbus.NewMessageVerifier(config.NatsSigningConfig{})
+		Expect(verifier.Verify("router.register", []byte("not json"))).To(Succeed())
+	})
+
+	Describe("CanonicalPayload", func() {
+		It("prefixes the subject, sorts the keys and keeps numbers and URLs as they are", func() {
+			data = []byte(`{"host":"192.168.1.1","port":8080,"uris":["foo.example.com"],"tags":{"b":"2","a":"1"},"endpoint_updated_at_ns":1504112345123456789,"route_service_url":"https://rs.example.com/?a=1&b=2","timestamp":1504112345}`)
+			payload, err := mbus.CanonicalPayload("router.register", hmacSign(data, "hmac", "secret"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(payload)).To(Equal("router.register\n" + `{"endpoint_updated_at_ns":1504112345123456789,"host":"192.168.1.1","port":8080,"route_service_url":"https://rs.example.com/?a=1&b=2","tags":{"a":"1","b":"2"},"timestamp":1504112345,"uris":["foo.example.com"]}`))
+		})
+	})
+})
@@ -1,9 +1,13 @@
 package mbus
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"strings"
 	"time"
@@ -12,6 +16,7 @@ import (
 	"code.cloudfoundry.org/gorouter/common/uuid"
 	"code.cloudfoundry.org/gorouter/config"
 	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/metrics"
 	"code.cloudfoundry.org/gorouter/registry"
 	"code.cloudfoundry.org/gorouter/route"
 	"code.cloudfoundry.org/localip"
@@ -26,21 +31,38 @@ import (
 // RegistryMessage defines the format of a route registration/unregistration
 // easyjson:json
 type RegistryMessage struct {
-	Host                    string            `json:"host"`
-	Port                    uint16            `json:"port"`
-	TLSPort                 uint16            `json:"tls_port"`
-	Uris                    []route.Uri       `json:"uris"`
-	Tags                    map[string]string `json:"tags"`
-	App                     string            `json:"app"`
-	StaleThresholdInSeconds int               `json:"stale_threshold_in_seconds"`
-	RouteServiceURL         string            `json:"route_service_url"`
-	PrivateInstanceID       string            `json:"private_instance_id"`
-	ServerCertDomainSAN     string            `json:"server_cert_domain_san"`
-	PrivateInstanceIndex    string            `json:"private_instance_index"`
-	IsolationSegment        string            `json:"isolation_segment"`
-	EndpointUpdatedAtNs     int64             `json:"endpoint_updated_at_ns"`
+	Host                    string             `json:"host"`
+	Port                    uint16             `json:"port"`
+	TLSPort                 uint16             `json:"tls_port"`
+	Uris                    []route.Uri        `json:"uris"`
+	Tags                    map[string]string  `json:"tags"`
+	App                     string             `json:"app"`
+	StaleThresholdInSeconds int                `json:"stale_threshold_in_seconds"`
+	RouteServiceURL         string             `json:"route_service_url"`
+	PrivateInstanceID       string             `json:"private_instance_id"`
+	ServerCertDomainSAN     string             `json:"server_cert_domain_san"`
+	PrivateInstanceIndex    string             `json:"private_instance_index"`
+	IsolationSegment        string             `json:"isolation_segment"`
+	EndpointUpdatedAtNs     int64              `json:"endpoint_updated_at_ns"`
+	HeaderRules             *route.HeaderRules `json:"header_rules"`
+	Redirect                *route.Redirect    `json:"redirect"`
+	HTTPSOnly               bool               `json:"https_only"`
+	DrainTimeoutInSeconds   int                `json:"drain_timeout_in_seconds"`
+	RateLimit               *config.RateLimit  `json:"rate_limit"`
 }
 
+// RegistryBatchMessage carries the registrations and unregistrations of many
+// endpoints on the router.register.batch subject. Unregistrations are applied
+// before registrations.
+// easyjson:json
+type RegistryBatchMessage struct {
+	Register   []RegistryMessage `json:"register"`
+	Unregister []RegistryMessage `json:"unregister"`
+}
+
+// maxBatchMessageSize limits the size of a decompressed batch message
+const maxBatchMessageSize = 64 * 1024 * 1024
+
 func (rm *RegistryMessage) makeEndpoint(acceptTLS bool) (*route.Endpoint, error) {
 	port, useTls, err := rm.port(acceptTLS)
 	if err != nil {
@@ -65,6 +87,12 @@ func (rm *RegistryMessage) makeEndpoint(acceptTLS bool) (*route.Endpoint, error)
 		IsolationSegment:        rm.IsolationSegment,
 		UseTLS:                  useTls,
 		UpdatedAt:               updatedAt,
+		HeaderRules:             rm.HeaderRules,
+		Redirect:                rm.Redirect,
+		HTTPSOnly:               rm.HTTPSOnly,
+		Source:                  route.SourceNATS,
+		DrainTimeout:            time.Duration(rm.DrainTimeoutInSeconds) * time.Second,
+		RateLimit:               rm.RateLimit,
 	}), nil
 }
 
@@ -73,8 +101,37 @@ func (rm *RegistryMessage) ValidateMessage() bool {
 	return rm.RouteServiceURL == "" || strings.HasPrefix(rm.RouteServiceURL, "https")
 }
 
+func (rm *RegistryMessage) validate() error {
+	if !rm.ValidateMessage() {
+		return errors.New("Unable to validate message. route_service_url must be https")
+	}
+
+	if rm.HeaderRules != nil {
+		if err := rm.HeaderRules.Validate(); err != nil {
+			return fmt.Errorf("Unable to validate message. header_rules: %s", err)
+		}
+	}
+
+	if rm.Redirect != nil {
+		if err := rm.Redirect.Validate(); err != nil {
+			return fmt.Errorf("Unable to validate message. redirect: %s", err)
+		}
+	}
+
+	if rm.RateLimit != nil {
+		if err := rm.RateLimit.Validate(); err != nil {
+			return fmt.Errorf("Unable to validate message. rate_limit: %s", err)
+		}
+	}
+	return nil
+}
+
 // Prefer TLS Port instead of HTTP Port in Registrty Message
 func (rm *RegistryMessage) port(acceptTLS bool) (uint16, bool, error) {
+	// redirect routes are answered by the router and need no backend
+	if rm.Redirect != nil && rm.Port == 0 && rm.TLSPort == 0 {
+		return 0, false, nil
+	}
 	if !acceptTLS && rm.Port == 0 {
 		return 0, false, errors.New("Invalid registry message: backend tls is not enabled")
 	} else if acceptTLS && rm.TLSPort != 0 {
@@ -85,14 +142,18 @@ func (rm *RegistryMessage) port(acceptTLS bool) (uint16, bool, error) {
 
 // Subscriber subscribes to NATS for all router.* messages and handles them
 type Subscriber struct {
-	mbusClient       Client
-	routeRegistry    registry.Registry
-	subscription     *nats.Subscription
-	reconnected      <-chan Signal
-	natsPendingLimit int
-
-	params    startMessageParams
-	acceptTLS bool
+	mbusClient        Client
+	routeRegistry     registry.Registry
+	reporter          metrics.RouteRegistryReporter
+	verifier          *MessageVerifier
+	subscription      *nats.Subscription
+	batchSubscription *nats.Subscription
+	reconnected       <-chan Signal
+	natsPendingLimit  int
+
+	params       startMessageParams
+	capabilities common.RouterCapabilities
+	acceptTLS    bool
 
 	logger logger.Logger
 }
@@ -107,6 +168,7 @@ type startMessageParams struct {
 func NewSubscriber(
 	mbusClient Client,
 	routeRegistry registry.Registry,
+	reporter metrics.RouteRegistryReporter,
 	c *config.Config,
 	reconnected <-chan Signal,
 	l logger.Logger,
@@ -119,13 +181,16 @@ func NewSubscriber(
 	return &Subscriber{
 		mbusClient:    mbusClient,
 		routeRegistry: routeRegistry,
+		reporter:      reporter,
+		verifier:      NewMessageVerifier(c.NatsSigning),
 
 		params: startMessageParams{
 			id: fmt.Sprintf("%d-%s", c.Index, guid),
 			minimumRegisterIntervalInSeconds: int(c.StartResponseDelayInterval.Seconds()),
 			pruneThresholdInSeconds:          int(c.DropletStaleThreshold.Seconds()),
 		},
-		acceptTLS: c.Backends.EnableTLS,
+		capabilities: capabilities(c),
+		acceptTLS:    c.Backends.EnableTLS,
 
 		reconnected:      reconnected,
 		natsPendingLimit: c.NatsClientMessageBufferSize,
@@ -147,10 +212,18 @@ func (s *Subscriber) Run(signals <-chan os.Signal, ready chan<- struct{}) error
 	if err != nil {
 		return err
 	}
+	err = s.subscribeToCapabilitiesMessage()
+	if err != nil {
+		return err
+	}
 	s.subscription, err = s.subscribeRoutes()
 	if err != nil {
 		return err
 	}
+	s.batchSubscription, err = s.subscribeBatches()
+	if err != nil {
+		return err
+	}
 
 	close(ready)
 	s.logger.Info("subscriber-started")
@@ -176,7 +249,12 @@ func (s *Subscriber) Pending() (int, error) {
 	}
 
 	msgs, _, err := s.subscription.Pending()
-	return msgs, err
+	if err != nil || s.batchSubscription == nil {
+		return msgs, err
+	}
+
+	batchMsgs, _, err := s.batchSubscription.Pending()
+	return msgs + batchMsgs, err
 }
 
 func (s *Subscriber) Dropped() (int, error) {
@@ -186,7 +264,12 @@ func (s *Subscriber) Dropped() (int, error) {
 	}
 
 	msgs, err := s.subscription.Dropped()
-	return msgs, err
+	if err != nil || s.batchSubscription == nil {
+		return msgs, err
+	}
+
+	batchMsgs, err := s.batchSubscription.Dropped()
+	return msgs + batchMsgs, err
 }
 
 func (s *Subscriber) subscribeToGreetMessage() error {
@@ -198,6 +281,15 @@ func (s *Subscriber) subscribeToGreetMessage() error {
 	return err
 }
 
+func (s *Subscriber) subscribeToCapabilitiesMessage() error {
+	_, err := s.mbusClient.Subscribe("router.capabilities", func(msg *nats.Msg) {
+		response, _ := json.Marshal(s.capabilities)
+		_ = s.mbusClient.Publish(msg.Reply, response)
+	})
+
+	return err
+}
+
 func (s *Subscriber) subscribeRoutes() (*nats.Subscription, error) {
 	natsSubscription, err := s.mbusClient.Subscribe("router.*", func(message *nats.Msg) {
 		msg, regErr := createRegistryMessage(message.Data)
@@ -211,8 +303,14 @@ func (s *Subscriber) subscribeRoutes() (*nats.Subscription, error) {
 		}
 		switch message.Subject {
 		case "router.register":
+			if !s.verified(message.Subject, message.Data) {
+				return
+			}
 			s.registerEndpoint(msg)
 		case "router.unregister":
+			if !s.verified(message.Subject, message.Data) {
+				return
+			}
 			s.unregisterEndpoint(msg)
 			s.logger.Info("unregister-route", zap.String("message", string(message.Data)))
 		default:
@@ -231,6 +329,57 @@ func (s *Subscriber) subscribeRoutes() (*nats.Subscription, error) {
 	return natsSubscription, nil
 }
 
+func (s *Subscriber) subscribeBatches() (*nats.Subscription, error) {
+	natsSubscription, err := s.mbusClient.Subscribe("router.register.batch", func(message *nats.Msg) {
+		data, err := decompressBatch(message.Data)
+		if err != nil {
+			s.logger.Error("validation-error",
+				zap.Error(err),
+				zap.String("subject", message.Subject),
+			)
+			return
+		}
+		if !s.verified(message.Subject, data) {
+			return
+		}
+
+		batch, err := createRegistryBatchMessage(data)
+		if err != nil {
+			s.logger.Error("validation-error",
+				zap.Error(err),
+				zap.String("subject", message.Subject),
+			)
+			return
+		}
+		s.updateEndpoints(batch)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = natsSubscription.SetPendingLimits(s.natsPendingLimit, s.natsPendingLimit*1024)
+	if err != nil {
+		return nil, fmt.Errorf("subscriber: SetPendingLimits: %s", err)
+	}
+
+	return natsSubscription, nil
+}
+
+func (s *Subscriber) verified(subject string, data []byte) bool {
+	err := s.verifier.Verify(subject, data)
+	if err != nil {
+		s.logger.Error("signature-verification-error",
+			zap.Error(err),
+			zap.String("payload", string(data)),
+			zap.String("subject", subject),
+		)
+		s.reporter.CaptureRejectedRegistryMessage()
+		return false
+	}
+	return true
+}
+
 func (s *Subscriber) registerEndpoint(msg *RegistryMessage) {
 	endpoint, err := msg.makeEndpoint(s.acceptTLS)
 	if err != nil {
@@ -260,6 +409,45 @@ func (s *Subscriber) unregisterEndpoint(msg *RegistryMessage) {
 	}
 }
 
+func (s *Subscriber) updateEndpoints(batch *RegistryBatchMessage) {
+	updates := make([]registry.RouteUpdate, 0, len(batch.Register)+len(batch.Unregister))
+	updates = s.appendUpdates(updates, batch.Unregister, true)
+	updates = s.appendUpdates(updates, batch.Register, false)
+
+	s.logger.Debug("register-batch",
+		zap.Int("registrations", len(batch.Register)),
+		zap.Int("unregistrations", len(batch.Unregister)),
+	)
+	s.routeRegistry.UpdateBatch(updates)
+}
+
+func (s *Subscriber) appendUpdates(updates []registry.RouteUpdate, msgs []RegistryMessage, unregister bool) []registry.RouteUpdate {
+	for i := range msgs {
+		msg := &msgs[i]
+		endpoint, err := s.batchEndpoint(msg)
+		if err != nil {
+			s.logger.Error("Unable to update route",
+				zap.Error(err),
+				zap.Object("message", msg),
+				zap.Bool("unregister", unregister),
+			)
+			continue
+		}
+		for _, uri := range msg.Uris {
+			updates = append(updates, registry.RouteUpdate{Uri: uri, Endpoint: endpoint, Unregister: unregister})
+		}
+	}
+	return updates
+}
+
+func (s *Subscriber) batchEndpoint(msg *RegistryMessage) (*route.Endpoint, error) {
+	err := msg.validate()
+	if err != nil {
+		return nil, err
+	}
+	return msg.makeEndpoint(s.acceptTLS)
+}
+
 func (s *Subscriber) startMessage() ([]byte, error) {
 	host, err := localip.LocalIP()
 	if err != nil {
@@ -271,6 +459,7 @@ func (s *Subscriber) startMessage() ([]byte, error) {
 		Hosts: []string{host},
 		MinimumRegisterIntervalInSeconds: s.params.minimumRegisterIntervalInSeconds,
 		PruneThresholdInSeconds:          s.params.pruneThresholdInSeconds,
+		Capabilities:                     s.capabilities,
 	}
 	message, err := json.Marshal(d)
 	if err != nil {
@@ -289,6 +478,32 @@ func (s *Subscriber) sendStartMessage() error {
 	return s.mbusClient.Publish("router.start", message)
 }
 
+func capabilities(c *config.Config) common.RouterCapabilities {
+	var protocols []string
+	if !c.DisableHTTP {
+		protocols = append(protocols, "http")
+	}
+	if c.EnableSSL {
+		protocols = append(protocols, "https")
+	}
+	protocols = append(protocols, "websocket")
+
+	segments := c.IsolationSegments
+	if segments == nil {
+		segments = []string{}
+	}
+
+	return common.RouterCapabilities{
+		TLSBackends:       c.Backends.EnableTLS,
+		BatchRegistration: true,
+		SignatureRequired: c.NatsSigning.Required,
+		Protocols:         protocols,
+		ShardingMode:      c.RoutingTableShardingMode,
+		IsolationSegments: segments,
+		Version:           common.Version,
+	}
+}
+
 func createRegistryMessage(data []byte) (*RegistryMessage, error) {
 	var msg RegistryMessage
 
@@ -297,9 +512,43 @@ func createRegistryMessage(data []byte) (*RegistryMessage, error) {
 		return nil, jsonErr
 	}
 
-	if !msg.ValidateMessage() {
-		return nil, errors.New("Unable to validate message. route_service_url must be https")
+	if err := msg.validate(); err != nil {
+		return nil, err
 	}
 
 	return &msg, nil
 }
+
+func createRegistryBatchMessage(data []byte) (*RegistryBatchMessage, error) {
+	var batch RegistryBatchMessage
+
+	jsonErr := easyjson.Unmarshal(data, &batch)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+
+	return &batch, nil
+}
+
+// decompressBatch returns the payload of a batch message, which publishers
+// may gzip.
+func decompressBatch(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(r, maxBatchMessageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxBatchMessageSize {
+		return nil, fmt.Errorf("batch message exceeds %d bytes", maxBatchMessageSize)
+	}
+	return b, nil
+}
@@ -3,6 +3,7 @@
 package mbus
 
 import (
+	config "code.cloudfoundry.org/gorouter/config"
 	route "code.cloudfoundry.org/gorouter/route"
 	json "encoding/json"
 	easyjson "github.com/mailru/easyjson"
@@ -220,6 +221,40 @@ func easyjson639f989aDecodeCodeCloudfoundryOrgGorouterMbus2(in *jlexer.Lexer, ou
 			out.IsolationSegment = string(in.String())
 		case "endpoint_updated_at_ns":
 			out.EndpointUpdatedAtNs = int64(in.Int64())
+		case "header_rules":
+			if in.IsNull() {
+				in.Skip()
+				out.HeaderRules = nil
+			} else {
+				if out.HeaderRules == nil {
+					out.HeaderRules = new(route.HeaderRules)
+				}
+				easyjson639f989aDecodeCodeCloudfoundryOrgGorouterRoute(in, &*out.HeaderRules)
+			}
+		case "redirect":
+			if in.IsNull() {
+				in.Skip()
+				out.Redirect = nil
+			} else {
+				if out.Redirect == nil {
+					out.Redirect = new(route.Redirect)
+				}
+				easyjson639f989aDecodeCodeCloudfoundryOrgGorouterRoute1(in, &*out.Redirect)
+			}
+		case "https_only":
+			out.HTTPSOnly = bool(in.Bool())
+		case "drain_timeout_in_seconds":
+			out.DrainTimeoutInSeconds = int(in.Int())
+		case "rate_limit":
+			if in.IsNull() {
+				in.Skip()
+				out.RateLimit = nil
+			} else {
+				if out.RateLimit == nil {
+					out.RateLimit = new(config.RateLimit)
+				}
+				easyjson639f989aDecodeCodeCloudfoundryOrgGorouterConfig(in, &*out.RateLimit)
+			}
 		default:
 			in.SkipRecursive()
 		}
@@ -338,6 +373,48 @@ func easyjson639f989aEncodeCodeCloudfoundryOrgGorouterMbus2(out *jwriter.Writer,
 	first = false
 	out.RawString("\"endpoint_updated_at_ns\":")
 	out.Int64(int64(in.EndpointUpdatedAtNs))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"header_rules\":")
+	if in.HeaderRules == nil {
+		out.RawString("null")
+	} else {
+		easyjson639f989aEncodeCodeCloudfoundryOrgGorouterRoute(out, *in.HeaderRules)
+	}
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"redirect\":")
+	if in.Redirect == nil {
+		out.RawString("null")
+	} else {
+		easyjson639f989aEncodeCodeCloudfoundryOrgGorouterRoute1(out, *in.Redirect)
+	}
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"https_only\":")
+	out.Bool(bool(in.HTTPSOnly))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"drain_timeout_in_seconds\":")
+	out.Int(int(in.DrainTimeoutInSeconds))
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"rate_limit\":")
+	if in.RateLimit == nil {
+		out.RawString("null")
+	} else {
+		easyjson639f989aEncodeCodeCloudfoundryOrgGorouterConfig(out, *in.RateLimit)
+	}
 	out.RawByte('}')
 }
 
@@ -364,3 +441,512 @@ func (v *RegistryMessage) UnmarshalJSON(data []byte) error {
 func (v *RegistryMessage) UnmarshalEasyJSON(l *jlexer.Lexer) {
 	easyjson639f989aDecodeCodeCloudfoundryOrgGorouterMbus2(l, v)
 }
+func easyjson639f989aDecodeCodeCloudfoundryOrgGorouterConfig(in *jlexer.Lexer, out *config.RateLimit) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "requests_per_second":
+			out.RequestsPerSecond = float64(in.Float64())
+		case "burst":
+			out.Burst = int(in.Int())
+		case "key":
+			out.Key = string(in.String())
+		case "header":
+			out.Header = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson639f989aEncodeCodeCloudfoundryOrgGorouterConfig(out *jwriter.Writer, in config.RateLimit) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"requests_per_second\":")
+	out.Float64(float64(in.RequestsPerSecond))
+	if in.Burst != 0 {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"burst\":")
+		out.Int(int(in.Burst))
+	}
+	if in.Key != "" {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"key\":")
+		out.String(string(in.Key))
+	}
+	if in.Header != "" {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"header\":")
+		out.String(string(in.Header))
+	}
+	out.RawByte('}')
+}
+func easyjson639f989aDecodeCodeCloudfoundryOrgGorouterRoute1(in *jlexer.Lexer, out *route.Redirect) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "url":
+			out.URL = string(in.String())
+		case "status_code":
+			out.StatusCode = int(in.Int())
+		case "preserve_path":
+			out.PreservePath = bool(in.Bool())
+		case "preserve_query":
+			out.PreserveQuery = bool(in.Bool())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson639f989aEncodeCodeCloudfoundryOrgGorouterRoute1(out *jwriter.Writer, in route.Redirect) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"url\":")
+	out.String(string(in.URL))
+	if in.StatusCode != 0 {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"status_code\":")
+		out.Int(int(in.StatusCode))
+	}
+	if in.PreservePath {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"preserve_path\":")
+		out.Bool(bool(in.PreservePath))
+	}
+	if in.PreserveQuery {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"preserve_query\":")
+		out.Bool(bool(in.PreserveQuery))
+	}
+	out.RawByte('}')
+}
+func easyjson639f989aDecodeCodeCloudfoundryOrgGorouterRoute(in *jlexer.Lexer, out *route.HeaderRules) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "host":
+			out.Host = string(in.String())
+		case "request":
+			easyjson639f989aDecodeCodeCloudfoundryOrgGorouterRoute2(in, &out.Request)
+		case "response":
+			easyjson639f989aDecodeCodeCloudfoundryOrgGorouterRoute2(in, &out.Response)
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson639f989aEncodeCodeCloudfoundryOrgGorouterRoute(out *jwriter.Writer, in route.HeaderRules) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if in.Host != "" {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"host\":")
+		out.String(string(in.Host))
+	}
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"request\":")
+	easyjson639f989aEncodeCodeCloudfoundryOrgGorouterRoute2(out, in.Request)
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"response\":")
+	easyjson639f989aEncodeCodeCloudfoundryOrgGorouterRoute2(out, in.Response)
+	out.RawByte('}')
+}
+func easyjson639f989aDecodeCodeCloudfoundryOrgGorouterRoute2(in *jlexer.Lexer, out *route.HeaderRuleSet) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "set":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.Set = make(map[string]string)
+				} else {
+					out.Set = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v6 string
+					v6 = string(in.String())
+					(out.Set)[key] = v6
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		case "append":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.Append = make(map[string]string)
+				} else {
+					out.Append = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v7 string
+					v7 = string(in.String())
+					(out.Append)[key] = v7
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		case "remove":
+			if in.IsNull() {
+				in.Skip()
+				out.Remove = nil
+			} else {
+				in.Delim('[')
+				if out.Remove == nil {
+					if !in.IsDelim(']') {
+						out.Remove = make([]string, 0, 4)
+					} else {
+						out.Remove = []string{}
+					}
+				} else {
+					out.Remove = (out.Remove)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v8 string
+					v8 = string(in.String())
+					out.Remove = append(out.Remove, v8)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson639f989aEncodeCodeCloudfoundryOrgGorouterRoute2(out *jwriter.Writer, in route.HeaderRuleSet) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if len(in.Set) != 0 {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"set\":")
+		if in.Set == nil && (out.Flags&jwriter.NilMapAsEmpty) == 0 {
+			out.RawString(`null`)
+		} else {
+			out.RawByte('{')
+			v9First := true
+			for v9Name, v9Value := range in.Set {
+				if !v9First {
+					out.RawByte(',')
+				}
+				v9First = false
+				out.String(string(v9Name))
+				out.RawByte(':')
+				out.String(string(v9Value))
+			}
+			out.RawByte('}')
+		}
+	}
+	if len(in.Append) != 0 {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"append\":")
+		if in.Append == nil && (out.Flags&jwriter.NilMapAsEmpty) == 0 {
+			out.RawString(`null`)
+		} else {
+			out.RawByte('{')
+			v10First := true
+			for v10Name, v10Value := range in.Append {
+				if !v10First {
+					out.RawByte(',')
+				}
+				v10First = false
+				out.String(string(v10Name))
+				out.RawByte(':')
+				out.String(string(v10Value))
+			}
+			out.RawByte('}')
+		}
+	}
+	if len(in.Remove) != 0 {
+		if !first {
+			out.RawByte(',')
+		}
+		first = false
+		out.RawString("\"remove\":")
+		if in.Remove == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v11, v12 := range in.Remove {
+				if v11 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v12))
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+func easyjson639f989aDecodeCodeCloudfoundryOrgGorouterMbus3(in *jlexer.Lexer, out *RegistryBatchMessage) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "register":
+			if in.IsNull() {
+				in.Skip()
+				out.Register = nil
+			} else {
+				in.Delim('[')
+				if out.Register == nil {
+					if !in.IsDelim(']') {
+						out.Register = make([]RegistryMessage, 0, 1)
+					} else {
+						out.Register = []RegistryMessage{}
+					}
+				} else {
+					out.Register = (out.Register)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v13 RegistryMessage
+					(v13).UnmarshalEasyJSON(in)
+					out.Register = append(out.Register, v13)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "unregister":
+			if in.IsNull() {
+				in.Skip()
+				out.Unregister = nil
+			} else {
+				in.Delim('[')
+				if out.Unregister == nil {
+					if !in.IsDelim(']') {
+						out.Unregister = make([]RegistryMessage, 0, 1)
+					} else {
+						out.Unregister = []RegistryMessage{}
+					}
+				} else {
+					out.Unregister = (out.Unregister)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v14 RegistryMessage
+					(v14).UnmarshalEasyJSON(in)
+					out.Unregister = append(out.Unregister, v14)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson639f989aEncodeCodeCloudfoundryOrgGorouterMbus3(out *jwriter.Writer, in RegistryBatchMessage) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"register\":")
+	if in.Register == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v15, v16 := range in.Register {
+			if v15 > 0 {
+				out.RawByte(',')
+			}
+			(v16).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+	if !first {
+		out.RawByte(',')
+	}
+	first = false
+	out.RawString("\"unregister\":")
+	if in.Unregister == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v17, v18 := range in.Unregister {
+			if v17 > 0 {
+				out.RawByte(',')
+			}
+			(v18).MarshalEasyJSON(out)
+		}
+		out.RawByte(']')
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v RegistryBatchMessage) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson639f989aEncodeCodeCloudfoundryOrgGorouterMbus3(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v RegistryBatchMessage) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson639f989aEncodeCodeCloudfoundryOrgGorouterMbus3(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *RegistryBatchMessage) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson639f989aDecodeCodeCloudfoundryOrgGorouterMbus3(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *RegistryBatchMessage) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson639f989aDecodeCodeCloudfoundryOrgGorouterMbus3(l, v)
+}
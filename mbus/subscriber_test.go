@@ -1,6 +1,11 @@
 package mbus_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"os"
@@ -12,6 +17,7 @@ import (
 	"code.cloudfoundry.org/gorouter/logger"
 	"code.cloudfoundry.org/gorouter/mbus"
 	mbusFakes "code.cloudfoundry.org/gorouter/mbus/fakes"
+	metricsFakes "code.cloudfoundry.org/gorouter/metrics/fakes"
 	registryFakes "code.cloudfoundry.org/gorouter/registry/fakes"
 	"code.cloudfoundry.org/gorouter/route"
 	"code.cloudfoundry.org/gorouter/test_util"
@@ -30,6 +36,7 @@ var _ = Describe("Subscriber", func() {
 		process ifrit.Process
 
 		registry *registryFakes.FakeRegistry
+		reporter *metricsFakes.FakeRouteRegistryReporter
 
 		natsRunner  *test_util.NATSRunner
 		natsPort    uint16
@@ -47,6 +54,7 @@ var _ = Describe("Subscriber", func() {
 		natsClient = natsRunner.MessageBus
 
 		registry = new(registryFakes.FakeRegistry)
+		reporter = new(metricsFakes.FakeRouteRegistryReporter)
 
 		l = test_util.NewTestZapLogger("mbus-test")
 
@@ -58,7 +66,7 @@ var _ = Describe("Subscriber", func() {
 		cfg.StartResponseDelayInterval = 60 * time.Second
 		cfg.DropletStaleThreshold = 120 * time.Second
 
-		sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, l)
+		sub = mbus.NewSubscriber(natsClient, registry, reporter, cfg, reconnected, l)
 	})
 
 	AfterEach(func() {
@@ -104,10 +112,18 @@ var _ = Describe("Subscriber", func() {
 		Expect(startMsg.Hosts).ToNot(BeEmpty())
 		Expect(startMsg.MinimumRegisterIntervalInSeconds).To(Equal(int(cfg.StartResponseDelayInterval.Seconds())))
 		Expect(startMsg.PruneThresholdInSeconds).To(Equal(int(cfg.DropletStaleThreshold.Seconds())))
+		Expect(startMsg.Capabilities).To(Equal(common.RouterCapabilities{
+			TLSBackends:       false,
+			BatchRegistration: true,
+			Protocols:         []string{"http", "websocket"},
+			ShardingMode:      "all",
+			IsolationSegments: []string{},
+			Version:           common.Version,
+		}))
 	})
 
 	It("errors when mbus client is nil", func() {
-		sub = mbus.NewSubscriber(nil, registry, cfg, reconnected, l)
+		sub = mbus.NewSubscriber(nil, registry, reporter, cfg, reconnected, l)
 		process = ifrit.Invoke(sub)
 
 		var err error
@@ -117,7 +133,7 @@ var _ = Describe("Subscriber", func() {
 
 	It("errors when pending limit is 0", func() {
 		cfg.NatsClientMessageBufferSize = 0
-		sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, l)
+		sub = mbus.NewSubscriber(natsClient, registry, reporter, cfg, reconnected, l)
 		process = ifrit.Invoke(sub)
 
 		var err error
@@ -172,7 +188,7 @@ var _ = Describe("Subscriber", func() {
 		var droppedMsgs func() int
 		BeforeEach(func() {
 			cfg.NatsClientMessageBufferSize = 1
-			sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, l)
+			sub = mbus.NewSubscriber(natsClient, registry, reporter, cfg, reconnected, l)
 			droppedMsgs = func() int {
 				msgs, errs := sub.Dropped()
 				Expect(errs).ToNot(HaveOccurred())
@@ -226,7 +242,7 @@ var _ = Describe("Subscriber", func() {
 			fakeClient.PublishReturns(errors.New("potato"))
 		})
 		It("errors", func() {
-			sub = mbus.NewSubscriber(fakeClient, registry, cfg, reconnected, l)
+			sub = mbus.NewSubscriber(fakeClient, registry, reporter, cfg, reconnected, l)
 			process = ifrit.Invoke(sub)
 
 			var err error
@@ -312,9 +328,41 @@ var _ = Describe("Subscriber", func() {
 		})
 	})
 
+	Context("when a capabilities request is received", func() {
+		BeforeEach(func() {
+			cfg.Backends.EnableTLS = true
+			cfg.EnableSSL = true
+			cfg.NatsSigning.Required = true
+			cfg.RoutingTableShardingMode = config.SHARD_SEGMENTS
+			cfg.IsolationSegments = []string{"is1", "is2"}
+			sub = mbus.NewSubscriber(natsClient, registry, reporter, cfg, reconnected, l)
+			process = ifrit.Invoke(sub)
+			Eventually(process.Ready()).Should(BeClosed())
+		})
+
+		It("responds with the capabilities of the router", func() {
+			msg, err := natsClient.Request("router.capabilities", []byte{}, 4*time.Second)
+			Expect(err).ToNot(HaveOccurred())
+
+			var capabilities common.RouterCapabilities
+			err = json.Unmarshal(msg.Data, &capabilities)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(capabilities).To(Equal(common.RouterCapabilities{
+				TLSBackends:       true,
+				BatchRegistration: true,
+				SignatureRequired: true,
+				Protocols:         []string{"http", "https", "websocket"},
+				ShardingMode:      "segments",
+				IsolationSegments: []string{"is1", "is2"},
+				Version:           common.Version,
+			}))
+		})
+	})
+
 	Context("when the message cannot be unmarshaled", func() {
 		BeforeEach(func() {
-			sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, l)
+			sub = mbus.NewSubscriber(natsClient, registry, reporter, cfg, reconnected, l)
 			process = ifrit.Invoke(sub)
 			Eventually(process.Ready()).Should(BeClosed())
 		})
@@ -328,7 +376,7 @@ var _ = Describe("Subscriber", func() {
 	Context("when TLS is enabled for backends", func() {
 		BeforeEach(func() {
 			cfg.Backends.EnableTLS = true
-			sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, l)
+			sub = mbus.NewSubscriber(natsClient, registry, reporter, cfg, reconnected, l)
 			process = ifrit.Invoke(sub)
 			Eventually(process.Ready()).Should(BeClosed())
 		})
@@ -365,6 +413,7 @@ var _ = Describe("Subscriber", func() {
 					PrivateInstanceIndex:    "index",
 					StaleThresholdInSeconds: 120,
 					Tags: map[string]string{"key": "value"},
+					Source: route.SourceNATS,
 				})
 
 				Expect(originalEndpoint).To(Equal(expectedEndpoint))
@@ -372,6 +421,181 @@ var _ = Describe("Subscriber", func() {
 		})
 	})
 
+	Context("when a batch message is received", func() {
+		var batch mbus.RegistryBatchMessage
+
+		BeforeEach(func() {
+			process = ifrit.Invoke(sub)
+			Eventually(process.Ready()).Should(BeClosed())
+
+			batch = mbus.RegistryBatchMessage{
+				Register: []mbus.RegistryMessage{
+					{Host: "host", App: "app1", Port: 1111, Uris: []route.Uri{"app1.example.com", "www.app1.example.com"}},
+					{Host: "host", App: "app2", Port: 2222, Uris: []route.Uri{"app2.example.com"}},
+				},
+				Unregister: []mbus.RegistryMessage{
+					{Host: "host", App: "app3", Port: 3333, Uris: []route.Uri{"app3.example.com"}},
+				},
+			}
+		})
+
+		It("applies the registrations and unregistrations in one batch", func() {
+			data, err := json.Marshal(batch)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(natsClient.Publish("router.register.batch", data)).To(Succeed())
+
+			Eventually(registry.UpdateBatchCallCount).Should(Equal(1))
+			updates := registry.UpdateBatchArgsForCall(0)
+			Expect(updates).To(HaveLen(4))
+			Expect(updates[0].Uri).To(Equal(route.Uri("app3.example.com")))
+			Expect(updates[0].Unregister).To(BeTrue())
+			Expect(updates[0].Endpoint.CanonicalAddr()).To(Equal("host:3333"))
+			Expect(updates[1].Uri).To(Equal(route.Uri("app1.example.com")))
+			Expect(updates[2].Uri).To(Equal(route.Uri("www.app1.example.com")))
+			Expect(updates[1].Endpoint).To(BeIdenticalTo(updates[2].Endpoint))
+			Expect(updates[3].Uri).To(Equal(route.Uri("app2.example.com")))
+			Expect(updates[3].Unregister).To(BeFalse())
+			Expect(registry.RegisterCallCount()).To(BeZero())
+		})
+
+		It("accepts gzipped batches", func() {
+			data, err := json.Marshal(batch)
+			Expect(err).NotTo(HaveOccurred())
+
+			var buf bytes.Buffer
+			w := gzip.NewWriter(&buf)
+			_, err = w.Write(data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Close()).To(Succeed())
+
+			Expect(natsClient.Publish("router.register.batch", buf.Bytes())).To(Succeed())
+
+			Eventually(registry.UpdateBatchCallCount).Should(Equal(1))
+			Expect(registry.UpdateBatchArgsForCall(0)).To(HaveLen(4))
+		})
+
+		It("skips invalid registrations", func() {
+			batch.Register[0].RouteServiceURL = "http://not-tls.example.com"
+
+			data, err := json.Marshal(batch)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(natsClient.Publish("router.register.batch", data)).To(Succeed())
+
+			Eventually(registry.UpdateBatchCallCount).Should(Equal(1))
+			Expect(registry.UpdateBatchArgsForCall(0)).To(HaveLen(2))
+			Expect(l).To(gbytes.Say("Unable to update route"))
+		})
+
+		It("does not update the registry when the message cannot be unmarshaled", func() {
+			Expect(natsClient.Publish("router.register.batch", []byte(`{"register":`))).To(Succeed())
+			Consistently(registry.UpdateBatchCallCount).Should(BeZero())
+		})
+	})
+
+	Context("when message signing is configured", func() {
+		var msg mbus.RegistryMessage
+
+		sign := func(subject string, data []byte, keyId, secret string, timestamp time.Time) []byte {
+			var fields map[string]interface{}
+			Expect(json.Unmarshal(data, &fields)).To(Succeed())
+			fields["timestamp"] = timestamp.Unix()
+			data, err := json.Marshal(fields)
+			Expect(err).ToNot(HaveOccurred())
+
+			payload, err := mbus.CanonicalPayload(subject, data)
+			Expect(err).ToNot(HaveOccurred())
+
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(payload)
+
+			fields["signature"] = mbus.MessageSignature{KeyId: keyId, Value: base64.StdEncoding.EncodeToString(mac.Sum(nil))}
+			signed, err := json.Marshal(fields)
+			Expect(err).ToNot(HaveOccurred())
+			return signed
+		}
+
+		BeforeEach(func() {
+			cfg.NatsSigning = config.NatsSigningConfig{
+				Keys: []config.NatsSigningKey{
+					{Id: "old", Algorithm: config.SIGNING_HMAC_SHA256, Key: []byte("old-secret")},
+					{Id: "new", Algorithm: config.SIGNING_HMAC_SHA256, Key: []byte("new-secret")},
+				},
+				Required: true,
+				MaxAge:   time.Minute,
+			}
+			sub = mbus.NewSubscriber(natsClient, registry, reporter, cfg, reconnected, l)
+			process = ifrit.Invoke(sub)
+			Eventually(process.Ready()).Should(BeClosed())
+
+			msg = mbus.RegistryMessage{
+				Host: "host",
+				App:  "app",
+				Port: 1111,
+				Uris: []route.Uri{"test.example.com"},
+				Tags: map[string]string{"component": "route-emitter"},
+			}
+		})
+
+		It("registers and unregisters routes of messages signed with any of the keys", func() {
+			data, err := json.Marshal(msg)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(natsClient.Publish("router.register", sign("router.register", data, "old", "old-secret", time.Now()))).To(Succeed())
+			Eventually(registry.RegisterCallCount).Should(Equal(1))
+
+			Expect(natsClient.Publish("router.unregister", sign("router.unregister", data, "new", "new-secret", time.Now()))).To(Succeed())
+			Eventually(registry.UnregisterCallCount).Should(Equal(1))
+			Expect(reporter.CaptureRejectedRegistryMessageCallCount()).To(Equal(0))
+		})
+
+		It("rejects unsigned messages", func() {
+			data, err := json.Marshal(msg)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(natsClient.Publish("router.register", data)).To(Succeed())
+			Expect(natsClient.Publish("router.unregister", data)).To(Succeed())
+			Eventually(reporter.CaptureRejectedRegistryMessageCallCount).Should(Equal(2))
+			Expect(registry.RegisterCallCount()).To(BeZero())
+			Expect(registry.UnregisterCallCount()).To(BeZero())
+		})
+
+		It("rejects messages with an invalid signature", func() {
+			data, err := json.Marshal(msg)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(natsClient.Publish("router.register", sign("router.register", data, "new", "old-secret", time.Now()))).To(Succeed())
+			Expect(natsClient.Publish("router.register", sign("router.register", data, "unknown", "new-secret", time.Now()))).To(Succeed())
+			Eventually(reporter.CaptureRejectedRegistryMessageCallCount).Should(Equal(2))
+			Expect(registry.RegisterCallCount()).To(BeZero())
+			Eventually(l).Should(gbytes.Say("signature-verification-error"))
+		})
+
+		It("rejects registrations replayed as unregistrations", func() {
+			data, err := json.Marshal(msg)
+			Expect(err).NotTo(HaveOccurred())
+
+			signed := sign("router.register", data, "new", "new-secret", time.Now())
+			Expect(natsClient.Publish("router.register", signed)).To(Succeed())
+			Eventually(registry.RegisterCallCount).Should(Equal(1))
+
+			Expect(natsClient.Publish("router.unregister", signed)).To(Succeed())
+			Eventually(reporter.CaptureRejectedRegistryMessageCallCount).Should(Equal(1))
+			Expect(registry.UnregisterCallCount()).To(BeZero())
+		})
+
+		It("rejects expired messages", func() {
+			data, err := json.Marshal(msg)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(natsClient.Publish("router.register", sign("router.register", data, "new", "new-secret", time.Now().Add(-2*time.Minute)))).To(Succeed())
+			Eventually(reporter.CaptureRejectedRegistryMessageCallCount).Should(Equal(1))
+			Expect(registry.RegisterCallCount()).To(BeZero())
+			Eventually(l).Should(gbytes.Say("message is older than 1m0s"))
+		})
+	})
+
 	It("converts endpoint_updated_at_ns", func() {
 		process = ifrit.Invoke(sub)
 		Eventually(process.Ready()).Should(BeClosed())
@@ -394,6 +618,7 @@ var _ = Describe("Subscriber", func() {
 			Host:      "host",
 			Port:      1111,
 			UpdatedAt: time.Unix(0, 1234).UTC(),
+			Source:    route.SourceNATS,
 		})
 
 		Expect(originalEndpoint.UpdatedAt).To(Equal(expectedEndpoint.UpdatedAt))
@@ -469,6 +694,7 @@ var _ = Describe("Subscriber", func() {
 					PrivateInstanceIndex:    "index",
 					StaleThresholdInSeconds: 120,
 					Tags: map[string]string{"key": "value"},
+					Source: route.SourceNATS,
 				})
 
 				Expect(originalEndpoint).To(Equal(expectedEndpoint))
@@ -514,6 +740,7 @@ var _ = Describe("Subscriber", func() {
 					PrivateInstanceIndex:    "index",
 					StaleThresholdInSeconds: 120,
 					Tags: map[string]string{"key": "value"},
+					Source: route.SourceNATS,
 				})
 
 				Expect(originalEndpoint).To(Equal(expectedEndpoint))
@@ -530,7 +757,7 @@ var _ = Describe("Subscriber", func() {
 
 	Context("when the message contains an http url for route services", func() {
 		BeforeEach(func() {
-			sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, l)
+			sub = mbus.NewSubscriber(natsClient, registry, reporter, cfg, reconnected, l)
 			process = ifrit.Invoke(sub)
 			Eventually(process.Ready()).Should(BeClosed())
 		})
@@ -558,9 +785,178 @@ var _ = Describe("Subscriber", func() {
 		})
 	})
 
+	Context("when the message contains header rules", func() {
+		BeforeEach(func() {
+			process = ifrit.Invoke(sub)
+			Eventually(process.Ready()).Should(BeClosed())
+		})
+
+		It("constructs the endpoint with the header rules", func() {
+			msg := mbus.RegistryMessage{
+				Host: "host",
+				Port: 1111,
+				Uris: []route.Uri{"test.example.com"},
+				HeaderRules: &route.HeaderRules{
+					Host: "internal.example.com",
+					Request: route.HeaderRuleSet{
+						Set:    map[string]string{"X-Foo": "bar"},
+						Remove: []string{"X-Secret"},
+					},
+					Response: route.HeaderRuleSet{
+						Append: map[string]string{"Cache-Control": "no-store"},
+					},
+				},
+			}
+
+			data, err := json.Marshal(msg)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = natsClient.Publish("router.register", data)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(registry.RegisterCallCount).Should(Equal(1))
+			_, endpoint := registry.RegisterArgsForCall(0)
+			Expect(endpoint.HeaderRules).To(Equal(msg.HeaderRules))
+		})
+
+		Context("when the header rules are invalid", func() {
+			It("does not update the registry", func() {
+				msg := mbus.RegistryMessage{
+					Host: "host",
+					Port: 1111,
+					Uris: []route.Uri{"test.example.com"},
+					HeaderRules: &route.HeaderRules{
+						Request: route.HeaderRuleSet{
+							Set: map[string]string{"X-Foo": "bar\r\nX-Injected: true"},
+						},
+					},
+				}
+
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Consistently(registry.RegisterCallCount).Should(BeZero())
+			})
+		})
+	})
+
+	Context("when the message describes a redirect route", func() {
+		BeforeEach(func() {
+			process = ifrit.Invoke(sub)
+			Eventually(process.Ready()).Should(BeClosed())
+		})
+
+		It("registers the route without a backend port", func() {
+			msg := mbus.RegistryMessage{
+				Uris: []route.Uri{"old.example.com"},
+				Redirect: &route.Redirect{
+					URL:          "https://new.example.com",
+					StatusCode:   301,
+					PreservePath: true,
+				},
+			}
+
+			data, err := json.Marshal(msg)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = natsClient.Publish("router.register", data)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(registry.RegisterCallCount).Should(Equal(1))
+			_, endpoint := registry.RegisterArgsForCall(0)
+			Expect(endpoint.Redirect).To(Equal(msg.Redirect))
+		})
+
+		Context("when the redirect url is not absolute", func() {
+			It("does not update the registry", func() {
+				msg := mbus.RegistryMessage{
+					Uris:     []route.Uri{"old.example.com"},
+					Redirect: &route.Redirect{URL: "/somewhere"},
+				}
+
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Consistently(registry.RegisterCallCount).Should(BeZero())
+			})
+		})
+	})
+
+	It("converts https_only", func() {
+		process = ifrit.Invoke(sub)
+		Eventually(process.Ready()).Should(BeClosed())
+		msg := mbus.RegistryMessage{
+			Host:      "host",
+			Port:      1111,
+			Uris:      []route.Uri{"test.example.com"},
+			HTTPSOnly: true,
+		}
+
+		data, err := json.Marshal(msg)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = natsClient.Publish("router.register", data)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(registry.RegisterCallCount).Should(Equal(1))
+		_, endpoint := registry.RegisterArgsForCall(0)
+		Expect(endpoint.HTTPSOnly).To(BeTrue())
+	})
+
+	Context("when the message carries a rate limit", func() {
+		BeforeEach(func() {
+			process = ifrit.Invoke(sub)
+			Eventually(process.Ready()).Should(BeClosed())
+		})
+
+		It("converts the rate limit", func() {
+			msg := mbus.RegistryMessage{
+				Host:      "host",
+				Port:      1111,
+				Uris:      []route.Uri{"test.example.com"},
+				RateLimit: &config.RateLimit{RequestsPerSecond: 10, Burst: 20, Key: config.RATE_LIMIT_HEADER, Header: "X-Api-Key"},
+			}
+
+			data, err := json.Marshal(msg)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = natsClient.Publish("router.register", data)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(registry.RegisterCallCount).Should(Equal(1))
+			_, endpoint := registry.RegisterArgsForCall(0)
+			Expect(endpoint.RateLimit).To(Equal(msg.RateLimit))
+		})
+
+		Context("when the rate limit is invalid", func() {
+			It("does not update the registry", func() {
+				msg := mbus.RegistryMessage{
+					Host:      "host",
+					Port:      1111,
+					Uris:      []route.Uri{"test.example.com"},
+					RateLimit: &config.RateLimit{RequestsPerSecond: 10, Key: config.RATE_LIMIT_HEADER},
+				}
+
+				data, err := json.Marshal(msg)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = natsClient.Publish("router.register", data)
+				Expect(err).ToNot(HaveOccurred())
+
+				Consistently(registry.RegisterCallCount).Should(BeZero())
+			})
+		})
+	})
+
 	Context("when a route is unregistered", func() {
 		BeforeEach(func() {
-			sub = mbus.NewSubscriber(natsClient, registry, cfg, reconnected, l)
+			sub = mbus.NewSubscriber(natsClient, registry, reporter, cfg, reconnected, l)
 			process = ifrit.Invoke(sub)
 			Eventually(process.Ready()).Should(BeClosed())
 		})
@@ -667,6 +1063,17 @@ var _ = Describe("Subscriber", func() {
 				Expect(endpoint.IsolationSegment).To(Equal("abc-iso-seg"))
 			}
 		})
+
+		It("passes the drain timeout of the message on", func() {
+			data := []byte(`{"host": "host", "port": 1111, "uris": ["test.example.com"], "drain_timeout_in_seconds": 30}`)
+
+			err := natsClient.Publish("router.unregister", data)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(registry.UnregisterCallCount).Should(Equal(1))
+			_, endpoint := registry.UnregisterArgsForCall(0)
+			Expect(endpoint.DrainTimeout).To(Equal(30 * time.Second))
+		})
 	})
 
 })
@@ -20,6 +20,7 @@ type VarzReporter interface {
 //go:generate counterfeiter -o fakes/fake_proxyreporter.go . ProxyReporter
 type ProxyReporter interface {
 	CaptureBackendExhaustedConns()
+	CaptureRateLimited()
 	CaptureBackendInvalidID()
 	CaptureBackendInvalidTLSCert()
 	CaptureBackendTLSHandshakeFailed()
@@ -41,6 +42,11 @@ type ComponentTagged interface {
 type RouteRegistryReporter interface {
 	CaptureRouteStats(totalRoutes int, msSinceLastUpdate uint64)
 	CaptureRoutesPruned(prunedRoutes uint64)
+	CapturePruneProtection(engaged bool, staleEndpoints int)
+	CaptureDomainOwnershipViolation()
+	CaptureEndpointDraining()
+	CaptureEndpointDrained()
+	CaptureRejectedRegistryMessage()
 	CaptureLookupTime(t time.Duration)
 	CaptureRegistryMessage(msg ComponentTagged)
 	CaptureRouteRegistrationLatency(t time.Duration)
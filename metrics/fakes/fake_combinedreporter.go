@@ -14,6 +14,9 @@ type FakeCombinedReporter struct {
 	CaptureBackendExhaustedConnsStub            func()
 	captureBackendExhaustedConnsMutex           sync.RWMutex
 	captureBackendExhaustedConnsArgsForCall     []struct{}
+	CaptureRateLimitedStub                      func()
+	captureRateLimitedMutex                     sync.RWMutex
+	captureRateLimitedArgsForCall               []struct{}
 	CaptureBackendInvalidIDStub                 func()
 	captureBackendInvalidIDMutex                sync.RWMutex
 	captureBackendInvalidIDArgsForCall          []struct{}
@@ -78,6 +81,22 @@ func (fake *FakeCombinedReporter) CaptureBackendExhaustedConnsCallCount() int {
 	return len(fake.captureBackendExhaustedConnsArgsForCall)
 }
 
+func (fake *FakeCombinedReporter) CaptureRateLimited() {
+	fake.captureRateLimitedMutex.Lock()
+	fake.captureRateLimitedArgsForCall = append(fake.captureRateLimitedArgsForCall, struct{}{})
+	fake.recordInvocation("CaptureRateLimited", []interface{}{})
+	fake.captureRateLimitedMutex.Unlock()
+	if fake.CaptureRateLimitedStub != nil {
+		fake.CaptureRateLimitedStub()
+	}
+}
+
+func (fake *FakeCombinedReporter) CaptureRateLimitedCallCount() int {
+	fake.captureRateLimitedMutex.RLock()
+	defer fake.captureRateLimitedMutex.RUnlock()
+	return len(fake.captureRateLimitedArgsForCall)
+}
+
 func (fake *FakeCombinedReporter) CaptureBackendInvalidID() {
 	fake.captureBackendInvalidIDMutex.Lock()
 	fake.captureBackendInvalidIDArgsForCall = append(fake.captureBackendInvalidIDArgsForCall, struct{}{})
@@ -294,6 +313,8 @@ func (fake *FakeCombinedReporter) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.captureBackendExhaustedConnsMutex.RLock()
 	defer fake.captureBackendExhaustedConnsMutex.RUnlock()
+	fake.captureRateLimitedMutex.RLock()
+	defer fake.captureRateLimitedMutex.RUnlock()
 	fake.captureBackendInvalidIDMutex.RLock()
 	defer fake.captureBackendInvalidIDMutex.RUnlock()
 	fake.captureBackendInvalidTLSCertMutex.RLock()
@@ -14,6 +14,9 @@ type FakeProxyReporter struct {
 	CaptureBackendExhaustedConnsStub            func()
 	captureBackendExhaustedConnsMutex           sync.RWMutex
 	captureBackendExhaustedConnsArgsForCall     []struct{}
+	CaptureRateLimitedStub                      func()
+	captureRateLimitedMutex                     sync.RWMutex
+	captureRateLimitedArgsForCall               []struct{}
 	CaptureBackendInvalidIDStub                 func()
 	captureBackendInvalidIDMutex                sync.RWMutex
 	captureBackendInvalidIDArgsForCall          []struct{}
@@ -78,6 +81,22 @@ func (fake *FakeProxyReporter) CaptureBackendExhaustedConnsCallCount() int {
 	return len(fake.captureBackendExhaustedConnsArgsForCall)
 }
 
+func (fake *FakeProxyReporter) CaptureRateLimited() {
+	fake.captureRateLimitedMutex.Lock()
+	fake.captureRateLimitedArgsForCall = append(fake.captureRateLimitedArgsForCall, struct{}{})
+	fake.recordInvocation("CaptureRateLimited", []interface{}{})
+	fake.captureRateLimitedMutex.Unlock()
+	if fake.CaptureRateLimitedStub != nil {
+		fake.CaptureRateLimitedStub()
+	}
+}
+
+func (fake *FakeProxyReporter) CaptureRateLimitedCallCount() int {
+	fake.captureRateLimitedMutex.RLock()
+	defer fake.captureRateLimitedMutex.RUnlock()
+	return len(fake.captureRateLimitedArgsForCall)
+}
+
 func (fake *FakeProxyReporter) CaptureBackendInvalidID() {
 	fake.captureBackendInvalidIDMutex.Lock()
 	fake.captureBackendInvalidIDArgsForCall = append(fake.captureBackendInvalidIDArgsForCall, struct{}{})
@@ -294,6 +313,8 @@ func (fake *FakeProxyReporter) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.captureBackendExhaustedConnsMutex.RLock()
 	defer fake.captureBackendExhaustedConnsMutex.RUnlock()
+	fake.captureRateLimitedMutex.RLock()
+	defer fake.captureRateLimitedMutex.RUnlock()
 	fake.captureBackendInvalidIDMutex.RLock()
 	defer fake.captureBackendInvalidIDMutex.RUnlock()
 	fake.captureBackendInvalidTLSCertMutex.RLock()
@@ -20,9 +20,27 @@ type FakeRouteRegistryReporter struct {
 	captureRoutesPrunedArgsForCall []struct {
 		prunedRoutes uint64
 	}
-	CaptureLookupTimeStub        func(t time.Duration)
-	captureLookupTimeMutex       sync.RWMutex
-	captureLookupTimeArgsForCall []struct {
+	CapturePruneProtectionStub        func(engaged bool, staleEndpoints int)
+	capturePruneProtectionMutex       sync.RWMutex
+	capturePruneProtectionArgsForCall []struct {
+		engaged        bool
+		staleEndpoints int
+	}
+	CaptureDomainOwnershipViolationStub        func()
+	captureDomainOwnershipViolationMutex       sync.RWMutex
+	captureDomainOwnershipViolationArgsForCall []struct{}
+	CaptureEndpointDrainingStub                func()
+	captureEndpointDrainingMutex               sync.RWMutex
+	captureEndpointDrainingArgsForCall         []struct{}
+	CaptureEndpointDrainedStub                 func()
+	captureEndpointDrainedMutex                sync.RWMutex
+	captureEndpointDrainedArgsForCall          []struct{}
+	CaptureRejectedRegistryMessageStub         func()
+	captureRejectedRegistryMessageMutex        sync.RWMutex
+	captureRejectedRegistryMessageArgsForCall  []struct{}
+	CaptureLookupTimeStub                      func(t time.Duration)
+	captureLookupTimeMutex                     sync.RWMutex
+	captureLookupTimeArgsForCall               []struct {
 		t time.Duration
 	}
 	CaptureRegistryMessageStub        func(msg metrics.ComponentTagged)
@@ -96,6 +114,95 @@ func (fake *FakeRouteRegistryReporter) CaptureRoutesPrunedArgsForCall(i int) uin
 	return fake.captureRoutesPrunedArgsForCall[i].prunedRoutes
 }
 
+func (fake *FakeRouteRegistryReporter) CapturePruneProtection(engaged bool, staleEndpoints int) {
+	fake.capturePruneProtectionMutex.Lock()
+	fake.capturePruneProtectionArgsForCall = append(fake.capturePruneProtectionArgsForCall, struct {
+		engaged        bool
+		staleEndpoints int
+	}{engaged, staleEndpoints})
+	fake.recordInvocation("CapturePruneProtection", []interface{}{engaged, staleEndpoints})
+	fake.capturePruneProtectionMutex.Unlock()
+	if fake.CapturePruneProtectionStub != nil {
+		fake.CapturePruneProtectionStub(engaged, staleEndpoints)
+	}
+}
+
+func (fake *FakeRouteRegistryReporter) CapturePruneProtectionCallCount() int {
+	fake.capturePruneProtectionMutex.RLock()
+	defer fake.capturePruneProtectionMutex.RUnlock()
+	return len(fake.capturePruneProtectionArgsForCall)
+}
+
+func (fake *FakeRouteRegistryReporter) CapturePruneProtectionArgsForCall(i int) (bool, int) {
+	fake.capturePruneProtectionMutex.RLock()
+	defer fake.capturePruneProtectionMutex.RUnlock()
+	return fake.capturePruneProtectionArgsForCall[i].engaged, fake.capturePruneProtectionArgsForCall[i].staleEndpoints
+}
+
+func (fake *FakeRouteRegistryReporter) CaptureDomainOwnershipViolation() {
+	fake.captureDomainOwnershipViolationMutex.Lock()
+	fake.captureDomainOwnershipViolationArgsForCall = append(fake.captureDomainOwnershipViolationArgsForCall, struct{}{})
+	fake.recordInvocation("CaptureDomainOwnershipViolation", []interface{}{})
+	fake.captureDomainOwnershipViolationMutex.Unlock()
+	if fake.CaptureDomainOwnershipViolationStub != nil {
+		fake.CaptureDomainOwnershipViolationStub()
+	}
+}
+
+func (fake *FakeRouteRegistryReporter) CaptureDomainOwnershipViolationCallCount() int {
+	fake.captureDomainOwnershipViolationMutex.RLock()
+	defer fake.captureDomainOwnershipViolationMutex.RUnlock()
+	return len(fake.captureDomainOwnershipViolationArgsForCall)
+}
+
+func (fake *FakeRouteRegistryReporter) CaptureEndpointDraining() {
+	fake.captureEndpointDrainingMutex.Lock()
+	fake.captureEndpointDrainingArgsForCall = append(fake.captureEndpointDrainingArgsForCall, struct{}{})
+	fake.recordInvocation("CaptureEndpointDraining", []interface{}{})
+	fake.captureEndpointDrainingMutex.Unlock()
+	if fake.CaptureEndpointDrainingStub != nil {
+		fake.CaptureEndpointDrainingStub()
+	}
+}
+
+func (fake *FakeRouteRegistryReporter) CaptureEndpointDrainingCallCount() int {
+	fake.captureEndpointDrainingMutex.RLock()
+	defer fake.captureEndpointDrainingMutex.RUnlock()
+	return len(fake.captureEndpointDrainingArgsForCall)
+}
+
+func (fake *FakeRouteRegistryReporter) CaptureEndpointDrained() {
+	fake.captureEndpointDrainedMutex.Lock()
+	fake.captureEndpointDrainedArgsForCall = append(fake.captureEndpointDrainedArgsForCall, struct{}{})
+	fake.recordInvocation("CaptureEndpointDrained", []interface{}{})
+	fake.captureEndpointDrainedMutex.Unlock()
+	if fake.CaptureEndpointDrainedStub != nil {
+		fake.CaptureEndpointDrainedStub()
+	}
+}
+
+func (fake *FakeRouteRegistryReporter) CaptureEndpointDrainedCallCount() int {
+	fake.captureEndpointDrainedMutex.RLock()
+	defer fake.captureEndpointDrainedMutex.RUnlock()
+	return len(fake.captureEndpointDrainedArgsForCall)
+}
+
+func (fake *FakeRouteRegistryReporter) CaptureRejectedRegistryMessage() {
+	fake.captureRejectedRegistryMessageMutex.Lock()
+	fake.captureRejectedRegistryMessageArgsForCall = append(fake.captureRejectedRegistryMessageArgsForCall, struct{}{})
+	fake.recordInvocation("CaptureRejectedRegistryMessage", []interface{}{})
+	fake.captureRejectedRegistryMessageMutex.Unlock()
+	if fake.CaptureRejectedRegistryMessageStub != nil {
+		fake.CaptureRejectedRegistryMessageStub()
+	}
+}
+
+func (fake *FakeRouteRegistryReporter) CaptureRejectedRegistryMessageCallCount() int {
+	fake.captureRejectedRegistryMessageMutex.RLock()
+	defer fake.captureRejectedRegistryMessageMutex.RUnlock()
+	return len(fake.captureRejectedRegistryMessageArgsForCall)
+}
+
 func (fake *FakeRouteRegistryReporter) CaptureLookupTime(t time.Duration) {
 	fake.captureLookupTimeMutex.Lock()
 	fake.captureLookupTimeArgsForCall = append(fake.captureLookupTimeArgsForCall, struct {
@@ -215,6 +322,16 @@ func (fake *FakeRouteRegistryReporter) Invocations() map[string][][]interface{}
 	defer fake.captureRouteStatsMutex.RUnlock()
 	fake.captureRoutesPrunedMutex.RLock()
 	defer fake.captureRoutesPrunedMutex.RUnlock()
+	fake.capturePruneProtectionMutex.RLock()
+	defer fake.capturePruneProtectionMutex.RUnlock()
+	fake.captureDomainOwnershipViolationMutex.RLock()
+	defer fake.captureDomainOwnershipViolationMutex.RUnlock()
+	fake.captureEndpointDrainingMutex.RLock()
+	defer fake.captureEndpointDrainingMutex.RUnlock()
+	fake.captureEndpointDrainedMutex.RLock()
+	defer fake.captureEndpointDrainedMutex.RUnlock()
+	fake.captureRejectedRegistryMessageMutex.RLock()
+	defer fake.captureRejectedRegistryMessageMutex.RUnlock()
 	fake.captureLookupTimeMutex.RLock()
 	defer fake.captureLookupTimeMutex.RUnlock()
 	fake.captureRegistryMessageMutex.RLock()
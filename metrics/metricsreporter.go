@@ -21,6 +21,10 @@ func (m *MetricsReporter) CaptureBackendExhaustedConns() {
 	m.Batcher.BatchIncrementCounter("backend_exhausted_conns")
 }
 
+func (m *MetricsReporter) CaptureRateLimited() {
+	m.Batcher.BatchIncrementCounter("rate_limited_requests")
+}
+
 func (m *MetricsReporter) CaptureBackendTLSHandshakeFailed() {
 	m.Batcher.BatchIncrementCounter("backend_tls_handshake_failed")
 }
@@ -103,6 +107,31 @@ func (m *MetricsReporter) CaptureRoutesPruned(routesPruned uint64) {
 	m.Batcher.BatchAddCounter("routes_pruned", routesPruned)
 }
 
+func (m *MetricsReporter) CapturePruneProtection(engaged bool, staleEndpoints int) {
+	var value float64
+	if engaged {
+		value = 1
+	}
+	m.Sender.SendValue("prune_protection_engaged", value, "")
+	m.Sender.SendValue("stale_endpoints", float64(staleEndpoints), "")
+}
+
+func (m *MetricsReporter) CaptureDomainOwnershipViolation() {
+	m.Batcher.BatchIncrementCounter("domain_ownership_violations")
+}
+
+func (m *MetricsReporter) CaptureEndpointDraining() {
+	m.Batcher.BatchIncrementCounter("endpoints_draining")
+}
+
+func (m *MetricsReporter) CaptureEndpointDrained() {
+	m.Batcher.BatchIncrementCounter("endpoints_drained")
+}
+
+func (m *MetricsReporter) CaptureRejectedRegistryMessage() {
+	m.Batcher.BatchIncrementCounter("rejected_registry_messages")
+}
+
 func (m *MetricsReporter) CaptureRegistryMessage(msg ComponentTagged) {
 	var componentName string
 	if msg.Component() == "" {
@@ -63,6 +63,13 @@ var _ = Describe("MetricsReporter", func() {
 		Expect(batcher.BatchIncrementCounterArgsForCall(1)).To(Equal("backend_exhausted_conns"))
 	})
 
+	It("increments the rate_limited_requests metric", func() {
+		metricReporter.CaptureRateLimited()
+
+		Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(1))
+		Expect(batcher.BatchIncrementCounterArgsForCall(0)).To(Equal("rate_limited_requests"))
+	})
+
 	It("increments the backend_invalid_id metric", func() {
 		metricReporter.CaptureBackendInvalidID()
 
@@ -403,6 +410,42 @@ var _ = Describe("MetricsReporter", func() {
 		Expect(count).To(Equal(uint64(5)))
 	})
 
+	It("sends the prune protection metrics", func() {
+		metricReporter.CapturePruneProtection(true, 12)
+
+		Expect(sender.SendValueCallCount()).To(Equal(2))
+		name, value, _ := sender.SendValueArgsForCall(0)
+		Expect(name).To(Equal("prune_protection_engaged"))
+		Expect(value).To(BeEquivalentTo(1))
+		name, value, _ = sender.SendValueArgsForCall(1)
+		Expect(name).To(Equal("stale_endpoints"))
+		Expect(value).To(BeEquivalentTo(12))
+	})
+
+	It("increments the domain_ownership_violations metric", func() {
+		metricReporter.CaptureDomainOwnershipViolation()
+		Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(1))
+		Expect(batcher.BatchIncrementCounterArgsForCall(0)).To(Equal("domain_ownership_violations"))
+	})
+
+	It("increments the endpoints_draining metric", func() {
+		metricReporter.CaptureEndpointDraining()
+		Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(1))
+		Expect(batcher.BatchIncrementCounterArgsForCall(0)).To(Equal("endpoints_draining"))
+	})
+
+	It("increments the endpoints_drained metric", func() {
+		metricReporter.CaptureEndpointDrained()
+		Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(1))
+		Expect(batcher.BatchIncrementCounterArgsForCall(0)).To(Equal("endpoints_drained"))
+	})
+
+	It("increments the rejected_registry_messages metric", func() {
+		metricReporter.CaptureRejectedRegistryMessage()
+		Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(1))
+		Expect(batcher.BatchIncrementCounterArgsForCall(0)).To(Equal("rejected_registry_messages"))
+	})
+
 	It("increments the backend_tls_handshake_failed metric", func() {
 		metricReporter.CaptureBackendTLSHandshakeFailed()
 		Expect(batcher.BatchIncrementCounterCallCount()).To(Equal(1))
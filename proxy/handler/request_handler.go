@@ -14,6 +14,7 @@ import (
 	"time"
 
 	router_http "code.cloudfoundry.org/gorouter/common/http"
+	"code.cloudfoundry.org/gorouter/errorpage"
 	"code.cloudfoundry.org/gorouter/logger"
 	"code.cloudfoundry.org/gorouter/metrics"
 	"code.cloudfoundry.org/gorouter/proxy/utils"
@@ -39,9 +40,11 @@ type RequestHandler struct {
 	tlsConfigTemplate *tls.Config
 
 	forwarder *Forwarder
+
+	errorPages *errorpage.Pages
 }
 
-func NewRequestHandler(request *http.Request, response utils.ProxyResponseWriter, r metrics.ProxyReporter, logger logger.Logger, endpointDialTimeout time.Duration, tlsConfig *tls.Config) *RequestHandler {
+func NewRequestHandler(request *http.Request, response utils.ProxyResponseWriter, r metrics.ProxyReporter, logger logger.Logger, endpointDialTimeout time.Duration, tlsConfig *tls.Config, errorPages *errorpage.Pages) *RequestHandler {
 	requestLogger := setupLogger(request, logger)
 	return &RequestHandler{
 		logger:              requestLogger,
@@ -54,6 +57,7 @@ func NewRequestHandler(request *http.Request, response utils.ProxyResponseWriter
 			BackendReadTimeout: endpointDialTimeout, // TODO: different values?
 			Logger:             requestLogger,
 		},
+		errorPages: errorPages,
 	}
 }
 
@@ -123,7 +127,9 @@ func (h *RequestHandler) writeStatus(code int, message string) {
 
 	h.logger.Info("status", zap.String("body", body))
 
-	http.Error(h.response, body, code)
+	if !h.errorPages.Write(h.response, h.request, code, message) {
+		http.Error(h.response, body, code)
+	}
 	if code > 299 {
 		h.response.Header().Del("Connection")
 	}
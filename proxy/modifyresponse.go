@@ -31,6 +31,11 @@ func (p *proxy) modifyResponse(res *http.Response) error {
 		return errors.New("reqInfo.RoutePool is empty on a successful response")
 	}
 
+	// responses relayed by a route service have already had the rules applied
+	if rules := routePool.HeaderRules(); rules != nil && reqInfo.RouteServiceURL == nil {
+		rules.Response.Apply(res.Header)
+	}
+
 	if p.traceKey != "" && req.Header.Get(router_http.VcapTraceHeader) == p.traceKey {
 		res.Header.Set(router_http.VcapRouterHeader, p.ip)
 		res.Header.Set(router_http.VcapBackendHeader, endpoint.CanonicalAddr())
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 
 	router_http "code.cloudfoundry.org/gorouter/common/http"
 	"code.cloudfoundry.org/gorouter/handlers"
@@ -92,6 +93,41 @@ var _ = Describe("modifyResponse", func() {
 			})
 		})
 	})
+	Describe("header rules", func() {
+		BeforeEach(func() {
+			resp.Header.Set("Server", "my-backend")
+			reqInfo.RoutePool.Put(route.NewEndpoint(&route.EndpointOpts{
+				Host: "1.2.3.4",
+				Port: 5678,
+				HeaderRules: &route.HeaderRules{
+					Response: route.HeaderRuleSet{
+						Set:    map[string]string{"Strict-Transport-Security": "max-age=31536000"},
+						Remove: []string{"Server"},
+					},
+				},
+			}))
+		})
+
+		It("applies the response rules of the route", func() {
+			err := p.modifyResponse(resp)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.Header.Get("Strict-Transport-Security")).To(Equal("max-age=31536000"))
+			Expect(resp.Header).ToNot(HaveKey("Server"))
+		})
+
+		Context("when the response comes from a route service", func() {
+			BeforeEach(func() {
+				reqInfo.RouteServiceURL = &url.URL{Scheme: "https", Host: "rs.example.com"}
+			})
+
+			It("does not apply the rules again", func() {
+				err := p.modifyResponse(resp)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.Header.Get("Strict-Transport-Security")).To(BeEmpty())
+				Expect(resp.Header.Get("Server")).To(Equal("my-backend"))
+			})
+		})
+	})
 	Describe("Vcap Trace Headers", func() {
 		It("does not add any headers when trace key is empty", func() {
 			err := p.modifyResponse(resp)
@@ -13,6 +13,7 @@ import (
 	"code.cloudfoundry.org/gorouter/access_log"
 	router_http "code.cloudfoundry.org/gorouter/common/http"
 	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/errorpage"
 	"code.cloudfoundry.org/gorouter/handlers"
 	"code.cloudfoundry.org/gorouter/logger"
 	"code.cloudfoundry.org/gorouter/metrics"
@@ -51,6 +52,7 @@ type proxy struct {
 	bufferPool               httputil.BufferPool
 	backendTLSConfig         *tls.Config
 	skipSanitization         func(req *http.Request) bool
+	errorPages               *errorpage.Pages
 }
 
 func NewProxy(
@@ -86,6 +88,13 @@ func NewProxy(
 		skipSanitization:         skipSanitization,
 	}
 
+	// the templates have been validated by config.Process
+	errorPages, err := errorpage.NewPages(logger, c.ErrorPages)
+	if err != nil {
+		logger.Error("error-pages-err", zap.Error(err))
+	}
+	p.errorPages = errorPages
+
 	roundTripperFactory := &round_tripper.FactoryImpl{
 		Template: &http.Transport{
 			Dial:                (&net.Dialer{Timeout: c.EndpointDialTimeout}).Dial,
@@ -104,6 +113,7 @@ func NewProxy(
 		&round_tripper.ErrorHandler{
 			MetricReporter: p.reporter,
 			ErrorSpecs:     round_tripper.DefaultErrorSpecs,
+			ErrorPages:     errorPages,
 		},
 		routeServicesTransport,
 		p.endpointTimeout,
@@ -131,9 +141,13 @@ func NewProxy(
 
 	n.Use(handlers.NewProxyHealthcheck(c.HealthCheckUserAgent, p.heartbeatOK, logger))
 	n.Use(zipkinHandler)
-	n.Use(handlers.NewProtocolCheck(logger))
-	n.Use(handlers.NewLookup(registry, reporter, logger, c.Backends.MaxConns))
-	n.Use(handlers.NewRouteService(routeServiceConfig, logger, registry))
+	n.Use(handlers.NewProtocolCheck(logger, errorPages))
+	n.Use(handlers.NewLookup(registry, reporter, logger, errorPages))
+	n.Use(handlers.NewRateLimit(c.RateLimiting, routeServiceConfig, registry, reporter, logger, errorPages))
+	n.Use(handlers.NewConnectionLimit(reporter, logger, errorPages))
+	n.Use(handlers.NewRedirect(logger, c.ForceForwardedProtoHttps, c.SanitizeForwardedProto))
+	n.Use(handlers.NewRouteService(routeServiceConfig, logger, registry, errorPages))
+	n.Use(handlers.NewHeaderRewrite(logger))
 	n.Use(p)
 	n.Use(&handlers.XForwardedProto{
 		SkipSanitization:         p.skipSanitization,
@@ -174,7 +188,7 @@ func (p *proxy) ServeHTTP(responseWriter http.ResponseWriter, request *http.Requ
 	if err != nil {
 		p.logger.Fatal("request-info-err", zap.Error(err))
 	}
-	handler := handler.NewRequestHandler(request, proxyWriter, p.reporter, p.logger, p.endpointDialTimeout, p.backendTLSConfig)
+	handler := handler.NewRequestHandler(request, proxyWriter, p.reporter, p.logger, p.endpointDialTimeout, p.backendTLSConfig, p.errorPages)
 
 	if reqInfo.RoutePool == nil {
 		p.logger.Fatal("request-info-err", zap.Error(errors.New("failed-to-access-RoutePool")))
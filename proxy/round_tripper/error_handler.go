@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	router_http "code.cloudfoundry.org/gorouter/common/http"
+	"code.cloudfoundry.org/gorouter/errorpage"
 	"code.cloudfoundry.org/gorouter/metrics"
 	"code.cloudfoundry.org/gorouter/proxy/fails"
 	"code.cloudfoundry.org/gorouter/proxy/utils"
@@ -40,28 +41,35 @@ var DefaultErrorSpecs = []ErrorSpec{
 type ErrorHandler struct {
 	MetricReporter metrics.ProxyReporter
 	ErrorSpecs     []ErrorSpec
+	ErrorPages     *errorpage.Pages
 }
 
-func (eh *ErrorHandler) HandleError(responseWriter utils.ProxyResponseWriter, err error) {
+func (eh *ErrorHandler) HandleError(responseWriter utils.ProxyResponseWriter, request *http.Request, err error) {
 	responseWriter.Header().Set(router_http.CfRouterError, "endpoint_failure")
 
-	eh.writeErrorCode(err, responseWriter)
+	eh.writeErrorCode(err, responseWriter, request)
 	responseWriter.Header().Del("Connection")
 	responseWriter.Done()
 }
 
-func (eh *ErrorHandler) writeErrorCode(err error, responseWriter http.ResponseWriter) {
+func (eh *ErrorHandler) writeErrorCode(err error, responseWriter http.ResponseWriter, request *http.Request) {
 	for _, spec := range eh.ErrorSpecs {
 		if spec.Classifier.Classify(err) {
 			if spec.HandleError != nil {
 				spec.HandleError(eh.MetricReporter)
 			}
-			http.Error(responseWriter, spec.Message, spec.Code)
+			eh.writeError(responseWriter, request, spec.Message, spec.Code)
 			return
 		}
 	}
 
 	// default case
-	http.Error(responseWriter, BadGatewayMessage, http.StatusBadGateway)
+	eh.writeError(responseWriter, request, BadGatewayMessage, http.StatusBadGateway)
 	eh.MetricReporter.CaptureBadGateway()
 }
+
+func (eh *ErrorHandler) writeError(responseWriter http.ResponseWriter, request *http.Request, message string, code int) {
+	if !eh.ErrorPages.Write(responseWriter, request, code, message) {
+		http.Error(responseWriter, message, code)
+	}
+}
@@ -3,9 +3,12 @@ package round_tripper_test
 import (
 	"errors"
 	"net"
+	"net/http"
 	"net/http/httptest"
 
 	router_http "code.cloudfoundry.org/gorouter/common/http"
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/errorpage"
 	"code.cloudfoundry.org/gorouter/metrics/fakes"
 	"code.cloudfoundry.org/gorouter/proxy/round_tripper"
 	"code.cloudfoundry.org/gorouter/proxy/utils"
@@ -18,6 +21,7 @@ import (
 
 	"code.cloudfoundry.org/gorouter/metrics"
 	"code.cloudfoundry.org/gorouter/proxy/fails"
+	"code.cloudfoundry.org/gorouter/test_util"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -28,6 +32,7 @@ var _ = Describe("HandleError", func() {
 		errorHandler     *round_tripper.ErrorHandler
 		responseWriter   utils.ProxyResponseWriter
 		responseRecorder *httptest.ResponseRecorder
+		request          *http.Request
 		errorHandled     bool
 	)
 
@@ -58,43 +63,44 @@ var _ = Describe("HandleError", func() {
 		}
 		responseRecorder = httptest.NewRecorder()
 		responseWriter = utils.NewProxyResponseWriter(responseRecorder)
+		request = httptest.NewRequest("GET", "http://example.com/", nil)
 	})
 
 	It("Sets a header to describe the endpoint_failure", func() {
-		errorHandler.HandleError(responseWriter, errors.New("potato"))
+		errorHandler.HandleError(responseWriter, request, errors.New("potato"))
 		Expect(responseWriter.Header().Get(router_http.CfRouterError)).To(Equal("endpoint_failure"))
 	})
 
 	Context("when the error does not match any of the classifiers", func() {
 		It("sets the http response code to 502", func() {
-			errorHandler.HandleError(responseWriter, errors.New("potato"))
+			errorHandler.HandleError(responseWriter, request, errors.New("potato"))
 			Expect(responseWriter.Status()).To(Equal(502))
 		})
 
 		It("emits a BadGateway metric", func() {
-			errorHandler.HandleError(responseWriter, errors.New("potato"))
+			errorHandler.HandleError(responseWriter, request, errors.New("potato"))
 			Expect(metricReporter.CaptureBadGatewayCallCount()).To(Equal(1))
 		})
 	})
 
 	Context("when the error does match one of the classifiers", func() {
 		It("sets the http response code and message appropriately", func() {
-			errorHandler.HandleError(responseWriter, errors.New("i'm a tomato"))
+			errorHandler.HandleError(responseWriter, request, errors.New("i'm a tomato"))
 			Expect(responseWriter.Status()).To(Equal(419))
 			Expect(responseRecorder.Body.String()).To(Equal("you say tomato\n"))
 		})
 
 		It("does not emit a metric", func() {
-			errorHandler.HandleError(responseWriter, errors.New("i'm a tomato"))
+			errorHandler.HandleError(responseWriter, request, errors.New("i'm a tomato"))
 			Expect(metricReporter.CaptureBadGatewayCallCount()).To(Equal(0))
 		})
 
 		It("calls the handleError callback if it exists", func() {
 			firstResponseWriter := utils.NewProxyResponseWriter(httptest.NewRecorder())
-			errorHandler.HandleError(firstResponseWriter, errors.New("i'm a teapot"))
+			errorHandler.HandleError(firstResponseWriter, request, errors.New("i'm a teapot"))
 			Expect(errorHandled).To(BeFalse())
 
-			errorHandler.HandleError(responseWriter, errors.New("i'm a tomato"))
+			errorHandler.HandleError(responseWriter, request, errors.New("i'm a tomato"))
 			Expect(responseWriter.Status()).To(Equal(419))
 			Expect(errorHandled).To(BeTrue())
 		})
@@ -102,17 +108,40 @@ var _ = Describe("HandleError", func() {
 
 	It("removes any headers named 'Connection'", func() {
 		responseWriter.Header().Add("Connection", "foo")
-		errorHandler.HandleError(responseWriter, errors.New("potato"))
+		errorHandler.HandleError(responseWriter, request, errors.New("potato"))
 		Expect(responseWriter.Header().Get("Connection")).To(BeEmpty())
 	})
 
 	It("calls Done on the responseWriter, preventing further writes from going through", func() {
-		errorHandler.HandleError(responseWriter, errors.New("potato"))
+		errorHandler.HandleError(responseWriter, request, errors.New("potato"))
 		nBytesWritten, err := responseWriter.Write([]byte("foo"))
 		Expect(err).NotTo(HaveOccurred())
 		Expect(nBytesWritten).To(Equal(0))
 	})
 
+	Context("when an error page is configured", func() {
+		BeforeEach(func() {
+			var err error
+			errorHandler.ErrorPages, err = errorpage.NewPages(test_util.NewTestZapLogger("error-pages"), []config.ErrorPage{
+				{StatusCode: 502, JSON: `{"code":{{.StatusCode}},"error":{{json .RouterError}}}`},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("renders the error page", func() {
+			errorHandler.HandleError(responseWriter, request, errors.New("potato"))
+			Expect(responseWriter.Status()).To(Equal(502))
+			Expect(responseRecorder.Header().Get("Content-Type")).To(Equal("application/json"))
+			Expect(responseRecorder.Body.String()).To(Equal(`{"code":502,"error":"endpoint_failure"}`))
+		})
+
+		It("writes the plain text message for other status codes", func() {
+			errorHandler.HandleError(responseWriter, request, errors.New("i'm a tomato"))
+			Expect(responseWriter.Status()).To(Equal(419))
+			Expect(responseRecorder.Body.String()).To(Equal("you say tomato\n"))
+		})
+	})
+
 	Context("DefaultErrorSpecs", func() {
 		var err error
 
@@ -126,7 +155,7 @@ var _ = Describe("HandleError", func() {
 		Context("HostnameMismatch", func() {
 			BeforeEach(func() {
 				err = x509.HostnameError{Host: "the wrong one"}
-				errorHandler.HandleError(responseWriter, err)
+				errorHandler.HandleError(responseWriter, request, err)
 			})
 
 			It("Has a 503 Status Code", func() {
@@ -141,7 +170,7 @@ var _ = Describe("HandleError", func() {
 		Context("Untrusted Cert", func() {
 			BeforeEach(func() {
 				err = x509.UnknownAuthorityError{}
-				errorHandler.HandleError(responseWriter, err)
+				errorHandler.HandleError(responseWriter, request, err)
 			})
 
 			It("Has a 526 Status Code", func() {
@@ -156,7 +185,7 @@ var _ = Describe("HandleError", func() {
 		Context("Attempted TLS with non-TLS backend error", func() {
 			BeforeEach(func() {
 				err = tls.RecordHeaderError{Msg: "bad handshake"}
-				errorHandler.HandleError(responseWriter, err)
+				errorHandler.HandleError(responseWriter, request, err)
 			})
 
 			It("Has a 525 Status Code", func() {
@@ -171,7 +200,7 @@ var _ = Describe("HandleError", func() {
 		Context("Remote handshake failure", func() {
 			BeforeEach(func() {
 				err = &net.OpError{Op: "remote error", Err: errors.New("tls: handshake failure")}
-				errorHandler.HandleError(responseWriter, err)
+				errorHandler.HandleError(responseWriter, request, err)
 			})
 
 			It("Has a 525 Status Code", func() {
@@ -186,7 +215,7 @@ var _ = Describe("HandleError", func() {
 		Context("Context Cancelled Error", func() {
 			BeforeEach(func() {
 				err = context.Canceled
-				errorHandler.HandleError(responseWriter, err)
+				errorHandler.HandleError(responseWriter, request, err)
 			})
 
 			It("Has a 499 Status Code", func() {
@@ -54,7 +54,7 @@ func GetRoundTripper(e *route.Endpoint, roundTripperFactory RoundTripperFactory)
 
 //go:generate counterfeiter -o fakes/fake_error_handler.go --fake-name ErrorHandler . errorHandler
 type errorHandler interface {
-	HandleError(utils.ProxyResponseWriter, error)
+	HandleError(utils.ProxyResponseWriter, *http.Request, error)
 }
 
 func NewProxyRoundTripper(
@@ -208,7 +208,7 @@ func (rt *roundTripper) RoundTrip(request *http.Request) (*http.Response, error)
 	}
 
 	if finalErr != nil {
-		rt.errorHandler.HandleError(reqInfo.ProxyResponseWriter, finalErr)
+		rt.errorHandler.HandleError(reqInfo.ProxyResponseWriter, request, finalErr)
 		return nil, finalErr
 	}
 
@@ -285,7 +285,7 @@ var _ = Describe("ProxyRoundTripper", func() {
 					_, err := proxyRoundTripper.RoundTrip(req)
 					Expect(err).To(HaveOccurred())
 					Expect(errorHandler.HandleErrorCallCount()).To(Equal(1))
-					_, err = errorHandler.HandleErrorArgsForCall(0)
+					_, _, err = errorHandler.HandleErrorArgsForCall(0)
 					Expect(err).To(MatchError(ContainSubstring("tls: handshake failure")))
 				})
 
@@ -340,7 +340,7 @@ var _ = Describe("ProxyRoundTripper", func() {
 				It("calls the error handler", func() {
 					proxyRoundTripper.RoundTrip(req)
 					Expect(errorHandler.HandleErrorCallCount()).To(Equal(1))
-					_, err := errorHandler.HandleErrorArgsForCall(0)
+					_, _, err := errorHandler.HandleErrorArgsForCall(0)
 					Expect(err).To(Equal(handler.NoEndpointsAvailable))
 				})
 
@@ -609,7 +609,7 @@ var _ = Describe("ProxyRoundTripper", func() {
 						proxyRoundTripper.RoundTrip(req)
 						Expect(errorHandler.HandleErrorCallCount()).To(Equal(1))
 
-						_, err := errorHandler.HandleErrorArgsForCall(0)
+						_, _, err := errorHandler.HandleErrorArgsForCall(0)
 						Expect(err).To(Equal(dialError))
 					})
 
@@ -640,7 +640,7 @@ var _ = Describe("ProxyRoundTripper", func() {
 						It("calls the error handler", func() {
 							proxyRoundTripper.RoundTrip(req)
 							Expect(errorHandler.HandleErrorCallCount()).To(Equal(1))
-							_, err := errorHandler.HandleErrorArgsForCall(0)
+							_, _, err := errorHandler.HandleErrorArgsForCall(0)
 							Expect(err).To(MatchError("banana"))
 						})
 
@@ -0,0 +1,305 @@
+package container
+
+import (
+	"strings"
+
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+const (
+	tableBits  = 5
+	tableWidth = 1 << tableBits
+	tableMask  = tableWidth - 1
+)
+
+// Table is an immutable map of routes to pools. Updates produce a new Table
+// that shares every unchanged node with the previous one, so a Table can be
+// read from any number of goroutines without locking while a writer prepares
+// the next version.
+//
+// It is a hash array mapped trie keyed by the full route. MatchUri matches
+// path segments by looking up the shorter routes in turn.
+type Table struct {
+	root *tableNode
+	size int
+}
+
+// tableNode is either a branch with children, or a leaf holding the entries
+// whose keys share the same hash.
+type tableNode struct {
+	edit     *int
+	children *[tableWidth]*tableNode
+	hash     uint64
+	entries  []tableEntry
+}
+
+type tableEntry struct {
+	key  string
+	pool *route.Pool
+}
+
+func NewTable() *Table {
+	return &Table{}
+}
+
+// Find returns the pool for exactly the uri, nil if there is none.
+func (t *Table) Find(uri route.Uri) *route.Pool {
+	return t.root.find(hashKey(tableKey(uri)), tableKey(uri))
+}
+
+// MatchUri returns the pool of the longest route that matches the uri, nil if
+// nothing matches.
+func (t *Table) MatchUri(uri route.Uri) *route.Pool {
+	key := tableKey(uri)
+	for {
+		if pool := t.root.find(hashKey(key), key); pool != nil {
+			return pool
+		}
+
+		idx := strings.LastIndex(key, "/")
+		if idx < 0 {
+			return nil
+		}
+		key = key[:idx]
+	}
+}
+
+// Insert returns a Table in which uri maps to pool.
+func (t *Table) Insert(uri route.Uri, pool *route.Pool) *Table {
+	txn := t.Txn()
+	txn.Insert(uri, pool)
+	return txn.Commit()
+}
+
+// Delete returns a Table without uri.
+func (t *Table) Delete(uri route.Uri) *Table {
+	txn := t.Txn()
+	txn.Delete(uri)
+	return txn.Commit()
+}
+
+// PoolCount returns the number of routes.
+func (t *Table) PoolCount() int {
+	return t.size
+}
+
+// EndpointCount returns the number of unique endpoints of all the pools.
+func (t *Table) EndpointCount() int {
+	m := make(map[string]struct{})
+	t.Each(func(_ route.Uri, pool *route.Pool) {
+		pool.Each(func(e *route.Endpoint) {
+			m[e.CanonicalAddr()] = struct{}{}
+		})
+	})
+	return len(m)
+}
+
+// Each calls f with every route and its pool, in no particular order.
+func (t *Table) Each(f func(uri route.Uri, pool *route.Pool)) {
+	t.root.each(f)
+}
+
+func (t *Table) ToMap() map[route.Uri]*route.Pool {
+	m := make(map[route.Uri]*route.Pool, t.size)
+	t.Each(func(uri route.Uri, pool *route.Pool) {
+		m[uri] = pool
+	})
+	return m
+}
+
+// Txn starts a batch of updates to the table.
+func (t *Table) Txn() *Txn {
+	return &Txn{
+		edit:     new(int),
+		root:     t.root,
+		size:     t.size,
+		original: t,
+	}
+}
+
+// Txn applies a batch of updates, copying each node of the original Table at
+// most once. The Table it was started from is not modified.
+type Txn struct {
+	edit     *int
+	root     *tableNode
+	size     int
+	original *Table
+}
+
+func (txn *Txn) Find(uri route.Uri) *route.Pool {
+	return txn.root.find(hashKey(tableKey(uri)), tableKey(uri))
+}
+
+func (txn *Txn) Insert(uri route.Uri, pool *route.Pool) {
+	key := tableKey(uri)
+	var added bool
+	txn.root, added = txn.insert(txn.root, hashKey(key), 0, key, pool)
+	if added {
+		txn.size++
+	}
+}
+
+func (txn *Txn) Delete(uri route.Uri) {
+	key := tableKey(uri)
+	var removed bool
+	txn.root, removed = txn.delete(txn.root, hashKey(key), 0, key)
+	if removed {
+		txn.size--
+	}
+}
+
+// Commit returns the updated Table, or the original one if nothing changed.
+// The Txn can be used for further updates afterwards without affecting the
+// returned Table.
+func (txn *Txn) Commit() *Table {
+	if txn.root == txn.original.root {
+		return txn.original
+	}
+	txn.edit = new(int)
+	txn.original = &Table{root: txn.root, size: txn.size}
+	return txn.original
+}
+
+func (txn *Txn) insert(n *tableNode, hash uint64, shift uint, key string, pool *route.Pool) (*tableNode, bool) {
+	if n == nil {
+		return &tableNode{edit: txn.edit, hash: hash, entries: []tableEntry{{key: key, pool: pool}}}, true
+	}
+
+	if n.children == nil {
+		if n.hash == hash {
+			n = txn.editable(n)
+			for i := range n.entries {
+				if n.entries[i].key == key {
+					n.entries[i].pool = pool
+					return n, false
+				}
+			}
+			n.entries = append(n.entries, tableEntry{key: key, pool: pool})
+			return n, true
+		}
+
+		// split the leaf into a branch and insert into that
+		branch := &tableNode{edit: txn.edit, children: new([tableWidth]*tableNode)}
+		branch.children[(n.hash>>shift)&tableMask] = n
+		return txn.insert(branch, hash, shift, key, pool)
+	}
+
+	idx := (hash >> shift) & tableMask
+	child, added := txn.insert(n.children[idx], hash, shift+tableBits, key, pool)
+	n = txn.editable(n)
+	n.children[idx] = child
+	return n, added
+}
+
+func (txn *Txn) delete(n *tableNode, hash uint64, shift uint, key string) (*tableNode, bool) {
+	if n == nil {
+		return nil, false
+	}
+
+	if n.children == nil {
+		if n.hash != hash {
+			return n, false
+		}
+		for i := range n.entries {
+			if n.entries[i].key == key {
+				if len(n.entries) == 1 {
+					return nil, true
+				}
+				n = txn.editable(n)
+				n.entries = append(n.entries[:i], n.entries[i+1:]...)
+				return n, true
+			}
+		}
+		return n, false
+	}
+
+	idx := (hash >> shift) & tableMask
+	child, removed := txn.delete(n.children[idx], hash, shift+tableBits, key)
+	if !removed {
+		return n, false
+	}
+
+	n = txn.editable(n)
+	n.children[idx] = child
+
+	// collapse branches that are left with a single leaf
+	var last *tableNode
+	count := 0
+	for _, c := range n.children {
+		if c != nil {
+			last = c
+			count++
+		}
+	}
+	switch {
+	case count == 0:
+		return nil, true
+	case count == 1 && last.children == nil:
+		return last, true
+	}
+	return n, true
+}
+
+// editable returns n if it was created by this Txn, a copy of it otherwise.
+func (txn *Txn) editable(n *tableNode) *tableNode {
+	if n.edit == txn.edit {
+		return n
+	}
+
+	c := &tableNode{edit: txn.edit, hash: n.hash}
+	if n.children != nil {
+		children := *n.children
+		c.children = &children
+	}
+	if n.entries != nil {
+		c.entries = make([]tableEntry, len(n.entries))
+		copy(c.entries, n.entries)
+	}
+	return c
+}
+
+func (n *tableNode) find(hash uint64, key string) *route.Pool {
+	for shift := uint(0); n != nil; shift += tableBits {
+		if n.children == nil {
+			if n.hash != hash {
+				return nil
+			}
+			for _, e := range n.entries {
+				if e.key == key {
+					return e.pool
+				}
+			}
+			return nil
+		}
+		n = n.children[(hash>>shift)&tableMask]
+	}
+	return nil
+}
+
+func (n *tableNode) each(f func(uri route.Uri, pool *route.Pool)) {
+	if n == nil {
+		return
+	}
+	for _, e := range n.entries {
+		f(route.Uri(e.key), e.pool)
+	}
+	if n.children != nil {
+		for _, c := range n.children {
+			c.each(f)
+		}
+	}
+}
+
+func tableKey(uri route.Uri) string {
+	return strings.TrimPrefix(uri.String(), "/")
+}
+
+// hashKey is the 64-bit FNV-1a hash of the key.
+func hashKey(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
@@ -0,0 +1,199 @@
+package container_test
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/gorouter/route"
+
+	"code.cloudfoundry.org/gorouter/registry/container"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Table", func() {
+
+	var (
+		t *container.Table
+	)
+
+	BeforeEach(func() {
+		t = container.NewTable()
+	})
+
+	Describe(".Find", func() {
+		It("works for the root node", func() {
+			p := route.NewPool(42, "", "")
+			t = t.Insert("/", p)
+			Expect(t.Find("/")).To(Equal(p))
+		})
+
+		It("finds an exact match to an existing key", func() {
+			p := route.NewPool(42, "", "")
+			t = t.Insert("/foo/bar", p)
+			Expect(t.Find("/foo/bar")).To(Equal(p))
+			Expect(t.Find("foo/bar")).To(Equal(p))
+		})
+
+		It("returns nil when no exact match is found", func() {
+			t = t.Insert("/foo/bar/baz", route.NewPool(42, "", ""))
+			Expect(t.Find("/foo/bar")).To(BeNil())
+		})
+
+		It("returns nil if a shorter path exists", func() {
+			t = t.Insert("/foo/bar", route.NewPool(42, "", ""))
+			Expect(t.Find("/foo/bar/baz")).To(BeNil())
+		})
+	})
+
+	Describe(".MatchUri", func() {
+		It("works for the root node", func() {
+			p := route.NewPool(42, "", "")
+			t = t.Insert("/", p)
+			Expect(t.MatchUri("/")).To(Equal(p))
+		})
+
+		It("finds a matching shorter key", func() {
+			p := route.NewPool(42, "", "")
+			t = t.Insert("/foo/bar", p)
+			Expect(t.MatchUri("/foo/bar")).To(Equal(p))
+			Expect(t.MatchUri("/foo/bar/baz")).To(Equal(p))
+		})
+
+		It("does not match a partial segment", func() {
+			t = t.Insert("/foo/bar", route.NewPool(42, "", ""))
+			Expect(t.MatchUri("/foo/barbaz")).To(BeNil())
+		})
+
+		It("returns nil when no match found", func() {
+			t = t.Insert("/foo/bar/baz", route.NewPool(42, "", ""))
+			Expect(t.MatchUri("/foo/bar")).To(BeNil())
+		})
+
+		It("returns the longest found match when routes overlap", func() {
+			p1 := route.NewPool(42, "", "")
+			p2 := route.NewPool(42, "", "")
+			t = t.Insert("/foo", p1)
+			t = t.Insert("/foo/bar/baz", p2)
+			Expect(t.MatchUri("/foo/bar/baz")).To(Equal(p2))
+			Expect(t.MatchUri("/foo/bar")).To(Equal(p1))
+		})
+	})
+
+	Describe(".Insert", func() {
+		It("does not modify the original table", func() {
+			p1 := route.NewPool(42, "", "")
+			p2 := route.NewPool(42, "", "")
+			t = t.Insert("/foo", p1)
+
+			updated := t.Insert("/foo", p2).Insert("/bar", p2)
+
+			Expect(t.Find("/foo")).To(Equal(p1))
+			Expect(t.Find("/bar")).To(BeNil())
+			Expect(t.PoolCount()).To(Equal(1))
+			Expect(updated.Find("/foo")).To(Equal(p2))
+			Expect(updated.Find("/bar")).To(Equal(p2))
+			Expect(updated.PoolCount()).To(Equal(2))
+		})
+	})
+
+	Describe(".Delete", func() {
+		It("removes a pool", func() {
+			t = t.Insert("/foo", route.NewPool(42, "", ""))
+			t = t.Insert("/foo/bar", route.NewPool(42, "", ""))
+
+			deleted := t.Delete("/foo")
+
+			Expect(deleted.Find("/foo")).To(BeNil())
+			Expect(deleted.Find("/foo/bar")).ToNot(BeNil())
+			Expect(deleted.PoolCount()).To(Equal(1))
+			Expect(t.Find("/foo")).ToNot(BeNil())
+		})
+
+		It("ignores missing keys", func() {
+			t = t.Insert("/foo", route.NewPool(42, "", ""))
+			Expect(t.Delete("/bar").PoolCount()).To(Equal(1))
+		})
+	})
+
+	It("holds many routes", func() {
+		pools := make(map[route.Uri]*route.Pool)
+		for i := 0; i < 5000; i++ {
+			uri := route.Uri(fmt.Sprintf("app-%d.example.com", i))
+			pools[uri] = route.NewPool(42, "", "")
+			t = t.Insert(uri, pools[uri])
+		}
+		Expect(t.PoolCount()).To(Equal(5000))
+		Expect(t.ToMap()).To(Equal(pools))
+
+		for i := 0; i < 5000; i += 2 {
+			t = t.Delete(route.Uri(fmt.Sprintf("app-%d.example.com", i)))
+		}
+		Expect(t.PoolCount()).To(Equal(2500))
+		for uri, pool := range pools {
+			if t.Find(uri) != nil {
+				Expect(t.Find(uri)).To(Equal(pool))
+			}
+		}
+		Expect(t.Find("app-1.example.com")).ToNot(BeNil())
+		Expect(t.Find("app-2.example.com")).To(BeNil())
+	})
+
+	Describe(".Txn", func() {
+		It("applies a batch of updates", func() {
+			p1 := route.NewPool(42, "", "")
+			p2 := route.NewPool(42, "", "")
+			t = t.Insert("/foo", p1)
+
+			txn := t.Txn()
+			txn.Insert("/bar", p2)
+			txn.Delete("/foo")
+			Expect(txn.Find("/bar")).To(Equal(p2))
+			committed := txn.Commit()
+
+			Expect(committed.Find("/foo")).To(BeNil())
+			Expect(committed.Find("/bar")).To(Equal(p2))
+			Expect(t.Find("/foo")).To(Equal(p1))
+			Expect(t.Find("/bar")).To(BeNil())
+		})
+
+		It("does not modify a committed table", func() {
+			txn := t.Txn()
+			txn.Insert("/foo", route.NewPool(42, "", ""))
+			committed := txn.Commit()
+
+			txn.Insert("/bar", route.NewPool(42, "", ""))
+
+			Expect(committed.Find("/bar")).To(BeNil())
+			Expect(committed.PoolCount()).To(Equal(1))
+			Expect(txn.Commit().PoolCount()).To(Equal(2))
+		})
+	})
+
+	Describe(".EndpointCount", func() {
+		It("counts the unique endpoints", func() {
+			p1 := route.NewPool(42, "", "")
+			p2 := route.NewPool(42, "", "")
+			e1 := route.NewEndpoint(&route.EndpointOpts{Port: 1234})
+			e2 := route.NewEndpoint(&route.EndpointOpts{Port: 4321})
+			p1.Put(e1)
+			p2.Put(e1)
+			p2.Put(e2)
+			t = t.Insert("/foo", p1).Insert("/bar", p2)
+
+			Expect(t.EndpointCount()).To(Equal(2))
+		})
+	})
+
+	It("applies a function to each route", func() {
+		p1 := route.NewPool(42, "", "")
+		p2 := route.NewPool(42, "", "")
+		t = t.Insert("/foo", p1).Insert("/foo/bar/baz", p2)
+
+		uris := []route.Uri{}
+		t.Each(func(uri route.Uri, pool *route.Pool) {
+			uris = append(uris, uri)
+		})
+
+		Expect(uris).To(ConsistOf(route.Uri("foo"), route.Uri("foo/bar/baz")))
+	})
+})
@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+// DomainPolicy decides which endpoints may be registered for the routes of a
+// domain. The rule of the most specific domain applies; a rule for a domain
+// also covers its subdomains. Routes of domains without a rule can be
+// registered by any endpoint.
+type DomainPolicy struct {
+	rules map[string]*domainRule
+}
+
+type domainRule struct {
+	domain            string
+	isolationSegments map[string]struct{}
+	appIds            map[string]struct{}
+}
+
+type domainPolicyFile struct {
+	Rules []config.DomainOwnershipRule `yaml:"rules"`
+}
+
+func NewDomainPolicy(rules []config.DomainOwnershipRule) (*DomainPolicy, error) {
+	p := &DomainPolicy{rules: make(map[string]*domainRule)}
+
+	for _, r := range rules {
+		domain := strings.TrimPrefix(strings.ToLower(r.Domain), "*.")
+		if domain == "" {
+			return nil, fmt.Errorf("domain ownership rule without a domain")
+		}
+		if len(r.IsolationSegments) == 0 && len(r.AppIds) == 0 {
+			return nil, fmt.Errorf("domain ownership rule for %s must list isolation segments or app ids", domain)
+		}
+		if _, ok := p.rules[domain]; ok {
+			return nil, fmt.Errorf("duplicate domain ownership rule for %s", domain)
+		}
+
+		rule := &domainRule{domain: domain}
+		if len(r.IsolationSegments) > 0 {
+			rule.isolationSegments = toSet(r.IsolationSegments)
+		}
+		if len(r.AppIds) > 0 {
+			rule.appIds = toSet(r.AppIds)
+		}
+		p.rules[domain] = rule
+	}
+
+	return p, nil
+}
+
+// LoadDomainPolicy builds the policy from the rules and those of the YAML
+// file at path, if one is given.
+func LoadDomainPolicy(rules []config.DomainOwnershipRule, path string) (*DomainPolicy, error) {
+	if path == "" {
+		return NewDomainPolicy(rules)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseDomainPolicy(rules, b)
+}
+
+func parseDomainPolicy(rules []config.DomainOwnershipRule, b []byte) (*DomainPolicy, error) {
+	var f domainPolicyFile
+	err := yaml.Unmarshal(b, &f)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]config.DomainOwnershipRule, 0, len(rules)+len(f.Rules))
+	all = append(all, rules...)
+	all = append(all, f.Rules...)
+	return NewDomainPolicy(all)
+}
+
+// Check returns an error if the endpoint may not be registered for the uri.
+func (p *DomainPolicy) Check(uri route.Uri, endpoint *route.Endpoint) error {
+	if p == nil || len(p.rules) == 0 {
+		return nil
+	}
+
+	host, _ := splitHostAndContextPath(uri.RouteKey())
+	domain := strings.TrimPrefix(host, "*.")
+	for {
+		if rule, ok := p.rules[domain]; ok {
+			return rule.check(endpoint)
+		}
+
+		idx := strings.Index(domain, ".")
+		if idx < 0 {
+			return nil
+		}
+		domain = domain[idx+1:]
+	}
+}
+
+func (r *domainRule) check(endpoint *route.Endpoint) error {
+	if r.isolationSegments != nil {
+		if _, ok := r.isolationSegments[endpoint.IsolationSegment]; !ok {
+			return fmt.Errorf("isolation segment %q does not own domain %s", endpoint.IsolationSegment, r.domain)
+		}
+	}
+	if r.appIds != nil {
+		if _, ok := r.appIds[endpoint.ApplicationId]; !ok {
+			return fmt.Errorf("app %q does not own domain %s", endpoint.ApplicationId, r.domain)
+		}
+	}
+	return nil
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/uber-go/zap"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/logger"
+)
+
+// DomainPolicyReloader reads the domain ownership policy file on every tick
+// and applies it to the registry when it has changed. A file that cannot be
+// loaded leaves the current policy in place.
+type DomainPolicyReloader struct {
+	registry *RouteRegistry
+	rules    []config.DomainOwnershipRule
+	path     string
+	interval time.Duration
+	clock    clock.Clock
+	logger   logger.Logger
+
+	contents []byte
+}
+
+func NewDomainPolicyReloader(registry *RouteRegistry, rules []config.DomainOwnershipRule, path string, interval time.Duration, clock clock.Clock, logger logger.Logger) *DomainPolicyReloader {
+	return &DomainPolicyReloader{
+		registry: registry,
+		rules:    rules,
+		path:     path,
+		interval: interval,
+		clock:    clock,
+		logger:   logger,
+	}
+}
+
+func (d *DomainPolicyReloader) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ticker := d.clock.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	d.contents, _ = ioutil.ReadFile(d.path)
+	close(ready)
+	for {
+		select {
+		case <-ticker.C():
+			d.reload()
+		case <-signals:
+			d.logger.Info("exited")
+			return nil
+		}
+	}
+}
+
+func (d *DomainPolicyReloader) reload() {
+	b, err := ioutil.ReadFile(d.path)
+	if err != nil {
+		d.logger.Error("error-reading-domain-policy", zap.String("path", d.path), zap.Error(err))
+		return
+	}
+	if bytes.Equal(b, d.contents) {
+		return
+	}
+
+	policy, err := parseDomainPolicy(d.rules, b)
+	if err != nil {
+		d.logger.Error("error-loading-domain-policy", zap.String("path", d.path), zap.Error(err))
+		return
+	}
+
+	d.contents = b
+	d.registry.SetDomainPolicy(policy)
+	d.logger.Info("domain-policy-reloaded", zap.String("path", d.path))
+}
@@ -0,0 +1,184 @@
+package registry_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	. "code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("DomainPolicy", func() {
+	var (
+		policy    *DomainPolicy
+		sharedEp  *route.Endpoint
+		isoSegEp  *route.Endpoint
+		billingEp *route.Endpoint
+	)
+
+	BeforeEach(func() {
+		var err error
+		policy, err = NewDomainPolicy([]config.DomainOwnershipRule{
+			{Domain: "*.tenant.example.com", IsolationSegments: []string{"tenant"}},
+			{Domain: "billing.tenant.example.com", IsolationSegments: []string{"tenant"}, AppIds: []string{"billing-app"}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		sharedEp = route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 80, AppId: "other-app"})
+		isoSegEp = route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.2", Port: 80, AppId: "other-app", IsolationSegment: "tenant"})
+		billingEp = route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.3", Port: 80, AppId: "billing-app", IsolationSegment: "tenant"})
+	})
+
+	It("allows routes of domains without a rule", func() {
+		Expect(policy.Check("foo.example.com", sharedEp)).To(Succeed())
+		Expect(policy.Check("example.com/tenant.example.com", sharedEp)).To(Succeed())
+	})
+
+	It("applies a rule to the domain and its subdomains", func() {
+		Expect(policy.Check("tenant.example.com", isoSegEp)).To(Succeed())
+		Expect(policy.Check("foo.tenant.example.com/path", isoSegEp)).To(Succeed())
+		Expect(policy.Check("*.tenant.example.com", isoSegEp)).To(Succeed())
+
+		Expect(policy.Check("tenant.example.com", sharedEp)).ToNot(Succeed())
+		Expect(policy.Check("Foo.Tenant.Example.com", sharedEp)).ToNot(Succeed())
+		Expect(policy.Check("*.tenant.example.com", sharedEp)).ToNot(Succeed())
+	})
+
+	It("applies the rule of the most specific domain", func() {
+		Expect(policy.Check("billing.tenant.example.com", billingEp)).To(Succeed())
+		Expect(policy.Check("api.billing.tenant.example.com", billingEp)).To(Succeed())
+
+		err := policy.Check("billing.tenant.example.com", isoSegEp)
+		Expect(err).To(MatchError(`app "other-app" does not own domain billing.tenant.example.com`))
+	})
+
+	It("allows everything without a policy", func() {
+		var p *DomainPolicy
+		Expect(p.Check("tenant.example.com", sharedEp)).To(Succeed())
+	})
+
+	Context("when the rules are invalid", func() {
+		It("rejects rules without a domain", func() {
+			_, err := NewDomainPolicy([]config.DomainOwnershipRule{{AppIds: []string{"app"}}})
+			Expect(err).To(MatchError("domain ownership rule without a domain"))
+		})
+
+		It("rejects rules without owners", func() {
+			_, err := NewDomainPolicy([]config.DomainOwnershipRule{{Domain: "example.com"}})
+			Expect(err).To(MatchError("domain ownership rule for example.com must list isolation segments or app ids"))
+		})
+
+		It("rejects duplicate rules", func() {
+			_, err := NewDomainPolicy([]config.DomainOwnershipRule{
+				{Domain: "example.com", AppIds: []string{"app"}},
+				{Domain: "*.Example.com", AppIds: []string{"other-app"}},
+			})
+			Expect(err).To(MatchError("duplicate domain ownership rule for example.com"))
+		})
+	})
+
+	Context("files", func() {
+		var (
+			dir, path string
+			rules     []config.DomainOwnershipRule
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "domain-policy")
+			Expect(err).ToNot(HaveOccurred())
+			path = filepath.Join(dir, "rules.yml")
+
+			rules = []config.DomainOwnershipRule{{Domain: "tenant.example.com", IsolationSegments: []string{"tenant"}}}
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("combines the rules of the file with the given rules", func() {
+			Expect(ioutil.WriteFile(path, []byte("rules:\n- domain: other.example.com\n  app_ids: [billing-app]\n"), 0644)).To(Succeed())
+
+			p, err := LoadDomainPolicy(rules, path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Check("tenant.example.com", sharedEp)).ToNot(Succeed())
+			Expect(p.Check("other.example.com", sharedEp)).ToNot(Succeed())
+			Expect(p.Check("other.example.com", billingEp)).To(Succeed())
+		})
+
+		It("returns an error when the file cannot be read", func() {
+			_, err := LoadDomainPolicy(rules, filepath.Join(dir, "missing.yml"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		Describe("DomainPolicyReloader", func() {
+			var (
+				r        *RouteRegistry
+				clock    *fakeclock.FakeClock
+				logger   *test_util.TestZapLogger
+				process  ifrit.Process
+				reporter *fakes.FakeRouteRegistryReporter
+			)
+
+			tick := func() {
+				clock.WaitForWatcherAndIncrement(time.Second)
+			}
+
+			BeforeEach(func() {
+				configObj, err := config.DefaultConfig()
+				Expect(err).ToNot(HaveOccurred())
+				reporter = new(fakes.FakeRouteRegistryReporter)
+				r = NewRouteRegistry(test_util.NewTestZapLogger("test"), configObj, reporter)
+
+				Expect(ioutil.WriteFile(path, []byte("rules: []\n"), 0644)).To(Succeed())
+				clock = fakeclock.NewFakeClock(time.Now())
+				logger = test_util.NewTestZapLogger("test")
+				process = ifrit.Invoke(NewDomainPolicyReloader(r, nil, path, time.Second, clock, logger))
+			})
+
+			AfterEach(func() {
+				process.Signal(os.Interrupt)
+				Eventually(process.Wait()).Should(Receive())
+				Expect(clock.WatcherCount()).To(BeZero())
+			})
+
+			It("applies the policy when the file changes", func() {
+				r.Register("tenant.example.com", sharedEp)
+				Expect(r.NumEndpoints()).To(Equal(1))
+
+				Expect(ioutil.WriteFile(path, []byte("rules:\n- domain: tenant.example.com\n  isolation_segments: [tenant]\n"), 0644)).To(Succeed())
+				tick()
+				Eventually(logger).Should(gbytes.Say("domain-policy-reloaded"))
+				Expect(r.NumEndpoints()).To(Equal(0))
+				Expect(reporter.CaptureDomainOwnershipViolationCallCount()).To(Equal(1))
+
+				r.Register("tenant.example.com", route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.4", Port: 80}))
+				Expect(r.NumEndpoints()).To(Equal(0))
+				Expect(reporter.CaptureDomainOwnershipViolationCallCount()).To(Equal(2))
+			})
+
+			It("keeps the current policy when the file is invalid", func() {
+				Expect(ioutil.WriteFile(path, []byte("rules:\n- domain: tenant.example.com\n  isolation_segments: [tenant]\n"), 0644)).To(Succeed())
+				tick()
+				Eventually(logger).Should(gbytes.Say("domain-policy-reloaded"))
+
+				Expect(ioutil.WriteFile(path, []byte("rules:\n- domain: tenant.example.com\n"), 0644)).To(Succeed())
+				tick()
+				Eventually(logger).Should(gbytes.Say("error-loading-domain-policy"))
+
+				r.Register("tenant.example.com", sharedEp)
+				Expect(r.NumEndpoints()).To(Equal(0))
+			})
+		})
+	})
+})
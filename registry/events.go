@@ -0,0 +1,104 @@
+package registry
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+type EventType string
+
+const (
+	RouteAdded       EventType = "route-added"
+	RouteRemoved     EventType = "route-removed"
+	EndpointAdded    EventType = "endpoint-added"
+	EndpointUpdated  EventType = "endpoint-updated"
+	EndpointRemoved  EventType = "endpoint-removed"
+	EndpointPruned   EventType = "endpoint-pruned"
+	EndpointDraining EventType = "endpoint-draining"
+)
+
+// Event describes a change to the routing table. Endpoint is nil for the
+// route events.
+type Event struct {
+	Type     EventType       `json:"type"`
+	Uri      route.Uri       `json:"uri"`
+	Endpoint *route.Endpoint `json:"endpoint,omitempty"`
+	Time     time.Time       `json:"time"`
+}
+
+// Subscription receives the events of the registry on C, in the order the
+// changes were made. The registry never waits for a subscriber: when the
+// buffer of C is full the subscription is ended and C is closed, with
+// Overflowed returning true.
+type Subscription struct {
+	C <-chan Event
+
+	c          chan Event
+	events     *events
+	overflowed bool
+}
+
+// Overflowed reports whether the subscription was ended because the
+// subscriber did not keep up.
+func (s *Subscription) Overflowed() bool {
+	s.events.lock.Lock()
+	defer s.events.lock.Unlock()
+	return s.overflowed
+}
+
+// Close ends the subscription and closes C.
+func (s *Subscription) Close() {
+	s.events.lock.Lock()
+	defer s.events.lock.Unlock()
+	s.events.remove(s)
+}
+
+type events struct {
+	lock        sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+func newEvents() *events {
+	return &events{subscribers: make(map[*Subscription]struct{})}
+}
+
+func (e *events) subscribe(bufferSize int) *Subscription {
+	c := make(chan Event, bufferSize)
+	s := &Subscription{C: c, c: c, events: e}
+
+	e.lock.Lock()
+	e.subscribers[s] = struct{}{}
+	e.lock.Unlock()
+
+	return s
+}
+
+// emit sends the event to every subscriber. Callers hold the registry lock so
+// that events are emitted in the order of the changes.
+func (e *events) emit(t EventType, uri route.Uri, endpoint *route.Endpoint) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if len(e.subscribers) == 0 {
+		return
+	}
+
+	event := Event{Type: t, Uri: uri, Endpoint: endpoint, Time: time.Now()}
+	for s := range e.subscribers {
+		select {
+		case s.c <- event:
+		default:
+			s.overflowed = true
+			e.remove(s)
+		}
+	}
+}
+
+func (e *events) remove(s *Subscription) {
+	if _, ok := e.subscribers[s]; ok {
+		delete(e.subscribers, s)
+		close(s.c)
+	}
+}
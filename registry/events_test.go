@@ -0,0 +1,117 @@
+package registry_test
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	. "code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+	"code.cloudfoundry.org/routing-api/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Events", func() {
+	var (
+		r        *RouteRegistry
+		sub      *Subscription
+		endpoint *route.Endpoint
+	)
+
+	BeforeEach(func() {
+		configObj, err := config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		configObj.PruneStaleDropletsInterval = 50 * time.Millisecond
+		configObj.DropletStaleThreshold = 100 * time.Millisecond
+
+		r = NewRouteRegistry(test_util.NewTestZapLogger("test"), configObj, new(fakes.FakeRouteRegistryReporter))
+		sub = r.Subscribe(10)
+
+		endpoint = route.NewEndpoint(&route.EndpointOpts{
+			Host:            "192.168.1.1",
+			Port:            1234,
+			ModificationTag: models.ModificationTag{Guid: "abc", Index: 1},
+		})
+	})
+
+	AfterEach(func() {
+		sub.Close()
+	})
+
+	receive := func() Event {
+		var event Event
+		Eventually(sub.C).Should(Receive(&event))
+		return event
+	}
+
+	expectEvent := func(t EventType, uri route.Uri, e *route.Endpoint) {
+		event := receive()
+		Expect(event.Type).To(Equal(t))
+		Expect(event.Uri).To(Equal(uri))
+		if e == nil {
+			Expect(event.Endpoint).To(BeNil())
+		} else {
+			Expect(event.Endpoint).To(BeIdenticalTo(e))
+		}
+	}
+
+	It("publishes registrations and unregistrations", func() {
+		r.Register("foo.example.com", endpoint)
+		r.Register("foo.example.com", route.NewEndpoint(&route.EndpointOpts{
+			Host:            "192.168.1.1",
+			Port:            1234,
+			ModificationTag: models.ModificationTag{Guid: "abc", Index: 2},
+		}))
+		updated := route.NewEndpoint(&route.EndpointOpts{
+			Host:            "192.168.1.1",
+			Port:            1234,
+			ModificationTag: models.ModificationTag{Guid: "abc", Index: 3},
+			Tags:            map[string]string{"component": "app"},
+		})
+		r.Register("foo.example.com", updated)
+		r.Unregister("foo.example.com", updated)
+
+		expectEvent(RouteAdded, "foo.example.com", nil)
+		expectEvent(EndpointAdded, "foo.example.com", endpoint)
+		expectEvent(EndpointUpdated, "foo.example.com", updated)
+		expectEvent(EndpointRemoved, "foo.example.com", updated)
+		expectEvent(RouteRemoved, "foo.example.com", nil)
+		Consistently(sub.C).ShouldNot(Receive())
+	})
+
+	It("publishes pruned endpoints", func() {
+		r.Register("foo.example.com", endpoint)
+		receive()
+		receive()
+
+		r.StartPruningCycle()
+		defer r.StopPruningCycle()
+
+		expectEvent(EndpointPruned, "foo.example.com", endpoint)
+		expectEvent(RouteRemoved, "foo.example.com", nil)
+	})
+
+	It("ends the subscription of a subscriber that does not keep up", func() {
+		for i := 0; i < 11; i++ {
+			r.Register(route.Uri(fmt.Sprintf("foo.example.com/%d", i)), endpoint)
+		}
+
+		received := 0
+		for range sub.C {
+			received++
+		}
+		Expect(received).To(Equal(10))
+		Expect(sub.Overflowed()).To(BeTrue())
+	})
+
+	It("closes the channel when the subscription is closed", func() {
+		sub.Close()
+		Expect(sub.C).To(BeClosed())
+		Expect(sub.Overflowed()).To(BeFalse())
+
+		r.Register("foo.example.com", endpoint)
+	})
+})
@@ -21,6 +21,11 @@ type FakeRegistry struct {
 		uri      route.Uri
 		endpoint *route.Endpoint
 	}
+	UpdateBatchStub        func(updates []registry.RouteUpdate)
+	updateBatchMutex       sync.RWMutex
+	updateBatchArgsForCall []struct {
+		updates []registry.RouteUpdate
+	}
 	LookupStub        func(uri route.Uri) *route.Pool
 	lookupMutex       sync.RWMutex
 	lookupArgsForCall []struct {
@@ -45,6 +50,17 @@ type FakeRegistry struct {
 	lookupWithInstanceReturnsOnCall map[int]struct {
 		result1 *route.Pool
 	}
+	LookupMaintenanceStub        func(uri route.Uri) *route.Maintenance
+	lookupMaintenanceMutex       sync.RWMutex
+	lookupMaintenanceArgsForCall []struct {
+		uri route.Uri
+	}
+	lookupMaintenanceReturns struct {
+		result1 *route.Maintenance
+	}
+	lookupMaintenanceReturnsOnCall map[int]struct {
+		result1 *route.Maintenance
+	}
 	StartPruningCycleStub        func()
 	startPruningCycleMutex       sync.RWMutex
 	startPruningCycleArgsForCall []struct{}
@@ -134,6 +150,35 @@ func (fake *FakeRegistry) UnregisterArgsForCall(i int) (route.Uri, *route.Endpoi
 	return fake.unregisterArgsForCall[i].uri, fake.unregisterArgsForCall[i].endpoint
 }
 
+func (fake *FakeRegistry) UpdateBatch(updates []registry.RouteUpdate) {
+	var updatesCopy []registry.RouteUpdate
+	if updates != nil {
+		updatesCopy = make([]registry.RouteUpdate, len(updates))
+		copy(updatesCopy, updates)
+	}
+	fake.updateBatchMutex.Lock()
+	fake.updateBatchArgsForCall = append(fake.updateBatchArgsForCall, struct {
+		updates []registry.RouteUpdate
+	}{updatesCopy})
+	fake.recordInvocation("UpdateBatch", []interface{}{updatesCopy})
+	fake.updateBatchMutex.Unlock()
+	if fake.UpdateBatchStub != nil {
+		fake.UpdateBatchStub(updates)
+	}
+}
+
+func (fake *FakeRegistry) UpdateBatchCallCount() int {
+	fake.updateBatchMutex.RLock()
+	defer fake.updateBatchMutex.RUnlock()
+	return len(fake.updateBatchArgsForCall)
+}
+
+func (fake *FakeRegistry) UpdateBatchArgsForCall(i int) []registry.RouteUpdate {
+	fake.updateBatchMutex.RLock()
+	defer fake.updateBatchMutex.RUnlock()
+	return fake.updateBatchArgsForCall[i].updates
+}
+
 func (fake *FakeRegistry) Lookup(uri route.Uri) *route.Pool {
 	fake.lookupMutex.Lock()
 	ret, specificReturn := fake.lookupReturnsOnCall[len(fake.lookupArgsForCall)]
@@ -232,6 +277,54 @@ func (fake *FakeRegistry) LookupWithInstanceReturnsOnCall(i int, result1 *route.
 	}{result1}
 }
 
+func (fake *FakeRegistry) LookupMaintenance(uri route.Uri) *route.Maintenance {
+	fake.lookupMaintenanceMutex.Lock()
+	ret, specificReturn := fake.lookupMaintenanceReturnsOnCall[len(fake.lookupMaintenanceArgsForCall)]
+	fake.lookupMaintenanceArgsForCall = append(fake.lookupMaintenanceArgsForCall, struct {
+		uri route.Uri
+	}{uri})
+	fake.recordInvocation("LookupMaintenance", []interface{}{uri})
+	fake.lookupMaintenanceMutex.Unlock()
+	if fake.LookupMaintenanceStub != nil {
+		return fake.LookupMaintenanceStub(uri)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.lookupMaintenanceReturns.result1
+}
+
+func (fake *FakeRegistry) LookupMaintenanceCallCount() int {
+	fake.lookupMaintenanceMutex.RLock()
+	defer fake.lookupMaintenanceMutex.RUnlock()
+	return len(fake.lookupMaintenanceArgsForCall)
+}
+
+func (fake *FakeRegistry) LookupMaintenanceArgsForCall(i int) route.Uri {
+	fake.lookupMaintenanceMutex.RLock()
+	defer fake.lookupMaintenanceMutex.RUnlock()
+	return fake.lookupMaintenanceArgsForCall[i].uri
+}
+
+func (fake *FakeRegistry) LookupMaintenanceReturns(result1 *route.Maintenance) {
+	fake.LookupMaintenanceStub = nil
+	fake.lookupMaintenanceReturns = struct {
+		result1 *route.Maintenance
+	}{result1}
+}
+
+func (fake *FakeRegistry) LookupMaintenanceReturnsOnCall(i int, result1 *route.Maintenance) {
+	fake.LookupMaintenanceStub = nil
+	if fake.lookupMaintenanceReturnsOnCall == nil {
+		fake.lookupMaintenanceReturnsOnCall = make(map[int]struct {
+			result1 *route.Maintenance
+		})
+	}
+	fake.lookupMaintenanceReturnsOnCall[i] = struct {
+		result1 *route.Maintenance
+	}{result1}
+}
+
 func (fake *FakeRegistry) StartPruningCycle() {
 	fake.startPruningCycleMutex.Lock()
 	fake.startPruningCycleArgsForCall = append(fake.startPruningCycleArgsForCall, struct{}{})
@@ -394,10 +487,14 @@ func (fake *FakeRegistry) Invocations() map[string][][]interface{} {
 	defer fake.registerMutex.RUnlock()
 	fake.unregisterMutex.RLock()
 	defer fake.unregisterMutex.RUnlock()
+	fake.updateBatchMutex.RLock()
+	defer fake.updateBatchMutex.RUnlock()
 	fake.lookupMutex.RLock()
 	defer fake.lookupMutex.RUnlock()
 	fake.lookupWithInstanceMutex.RLock()
 	defer fake.lookupWithInstanceMutex.RUnlock()
+	fake.lookupMaintenanceMutex.RLock()
+	defer fake.lookupMaintenanceMutex.RUnlock()
 	fake.startPruningCycleMutex.RLock()
 	defer fake.startPruningCycleMutex.RUnlock()
 	fake.stopPruningCycleMutex.RLock()
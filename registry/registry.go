@@ -2,8 +2,10 @@ package registry
 
 import (
 	"encoding/json"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/uber-go/zap"
@@ -19,8 +21,10 @@ import (
 type Registry interface {
 	Register(uri route.Uri, endpoint *route.Endpoint)
 	Unregister(uri route.Uri, endpoint *route.Endpoint)
+	UpdateBatch(updates []RouteUpdate)
 	Lookup(uri route.Uri) *route.Pool
 	LookupWithInstance(uri route.Uri, appID, appIndex string) *route.Pool
+	LookupMaintenance(uri route.Uri) *route.Maintenance
 	StartPruningCycle()
 	StopPruningCycle()
 	NumUris() int
@@ -35,13 +39,22 @@ const (
 	DISCONNECTED
 )
 
+// RouteUpdate registers, or unregisters, an endpoint as part of a batch.
+type RouteUpdate struct {
+	Uri        route.Uri
+	Endpoint   *route.Endpoint
+	Unregister bool
+}
+
 type RouteRegistry struct {
+	// Writers hold the lock while they build the next routing table. Readers
+	// load the current table without locking.
 	sync.RWMutex
 
 	logger logger.Logger
 
-	// Access to the Trie datastructure should be governed by the RWMutex of RouteRegistry
-	byURI *container.Trie
+	// holds the current *container.Table
+	routes atomic.Value
 
 	// used for ability to suspend pruning
 	suspendPruning func() bool
@@ -50,6 +63,12 @@ type RouteRegistry struct {
 	pruneStaleDropletsInterval time.Duration
 	dropletStaleThreshold      time.Duration
 
+	// pruning is held back while a cycle would remove more endpoints than
+	// allowed, and until registrations arrive again
+	pruneProtection     config.PruneProtectionConfig
+	pruneProtected      bool
+	pruneProtectedSince time.Time
+
 	reporter metrics.RouteRegistryReporter
 
 	ticker           *time.Ticker
@@ -57,53 +76,169 @@ type RouteRegistry struct {
 
 	routingTableShardingMode string
 	isolationSegments        []string
+
+	// kept apart from the routes so that it survives re-registration and
+	// pruning. Holds a map[route.Uri]*route.Maintenance that is replaced, never
+	// modified, under the lock.
+	maintenance atomic.Value
+
+	events *events
+
+	// holds the current *DomainPolicy
+	domainPolicy atomic.Value
+
+	sourcePrecedence     map[string]int
+	slowStart            config.SlowStartConfig
+	endpointDrainTimeout time.Duration
+	zone                 string
+	zoneAware            config.ZoneAwareRoutingConfig
+	backends             config.BackendConfig
 }
 
 func NewRouteRegistry(logger logger.Logger, c *config.Config, reporter metrics.RouteRegistryReporter) *RouteRegistry {
 	r := &RouteRegistry{}
 	r.logger = logger
-	r.byURI = container.NewTrie()
+	r.routes.Store(container.NewTable())
+	r.maintenance.Store(make(map[route.Uri]*route.Maintenance))
+	r.events = newEvents()
+	r.domainPolicy.Store((*DomainPolicy)(nil))
 
 	r.pruneStaleDropletsInterval = c.PruneStaleDropletsInterval
 	r.dropletStaleThreshold = c.DropletStaleThreshold
+	r.pruneProtection = c.PruneProtection
 	r.suspendPruning = func() bool { return false }
 
 	r.reporter = reporter
 
 	r.routingTableShardingMode = c.RoutingTableShardingMode
 	r.isolationSegments = c.IsolationSegments
+	r.sourcePrecedence = c.SourcePrecedence
+	r.slowStart = c.SlowStart
+	r.endpointDrainTimeout = c.EndpointDrainTimeout
+	r.zoneAware = c.ZoneAwareRouting
+	if c.ZoneAwareRouting.Enabled {
+		r.zone = c.Zone
+	}
+	r.backends = c.Backends
 
 	return r
 }
 
 func (r *RouteRegistry) Register(uri route.Uri, endpoint *route.Endpoint) {
+	if !r.endpointInRouterShard(endpoint) || !r.endpointOwnsDomain(uri, endpoint) {
+		return
+	}
+
+	t := time.Now()
+
+	r.Lock()
+
+	txn := r.table().Txn()
+	endpointAdded := r.register(txn, uri, endpoint)
+	r.publish(txn.Commit())
+
+	r.timeOfLastUpdate = t
+	r.Unlock()
+
+	r.registered(uri, endpoint, endpointAdded)
+}
+
+func (r *RouteRegistry) Unregister(uri route.Uri, endpoint *route.Endpoint) {
 	if !r.endpointInRouterShard(endpoint) {
 		return
 	}
 
+	r.Lock()
+
+	txn := r.table().Txn()
+	r.unregister(txn, uri, endpoint)
+	r.publish(txn.Commit())
+
+	r.Unlock()
+	r.reporter.CaptureUnregistryMessage(endpoint)
+}
+
+// UpdateBatch applies the registrations and unregistrations in order while
+// holding the lock once, and publishes a single new routing table.
+func (r *RouteRegistry) UpdateBatch(updates []RouteUpdate) {
+	results := make([]route.PoolPutResult, len(updates))
+	skipped := make([]bool, len(updates))
+	for i, u := range updates {
+		skipped[i] = !r.endpointInRouterShard(u.Endpoint) || (!u.Unregister && !r.endpointOwnsDomain(u.Uri, u.Endpoint))
+	}
 	t := time.Now()
 
 	r.Lock()
 
+	txn := r.table().Txn()
+	for i, u := range updates {
+		if skipped[i] {
+			continue
+		}
+		if u.Unregister {
+			r.unregister(txn, u.Uri, u.Endpoint)
+		} else {
+			results[i] = r.register(txn, u.Uri, u.Endpoint)
+			r.timeOfLastUpdate = t
+		}
+	}
+	r.publish(txn.Commit())
+
+	r.Unlock()
+
+	for i, u := range updates {
+		if skipped[i] {
+			continue
+		}
+		if u.Unregister {
+			r.reporter.CaptureUnregistryMessage(u.Endpoint)
+		} else {
+			r.registered(u.Uri, u.Endpoint, results[i])
+		}
+	}
+}
+
+func (r *RouteRegistry) register(txn *container.Txn, uri route.Uri, endpoint *route.Endpoint) route.PoolPutResult {
 	routekey := uri.RouteKey()
 
-	pool := r.byURI.Find(routekey)
+	pool := txn.Find(routekey)
 	if pool == nil {
 		host, contextPath := splitHostAndContextPath(uri)
-		pool = route.NewPool(r.dropletStaleThreshold/4, host, contextPath)
-		r.byURI.Insert(routekey, pool)
+		pool = r.newPool(host, contextPath)
+		txn.Insert(routekey, pool)
 		r.logger.Debug("uri-added", zap.Stringer("uri", routekey))
+		r.events.emit(RouteAdded, routekey, nil)
 	}
 
 	if endpoint.StaleThreshold > r.dropletStaleThreshold || endpoint.StaleThreshold == 0 {
 		endpoint.StaleThreshold = r.dropletStaleThreshold
 	}
+	if endpoint.Zone == "" {
+		endpoint.Zone = endpoint.Tags[r.zoneAware.Tag]
+	}
 
+	previous := pool.FindByAddr(endpoint.CanonicalAddr())
 	endpointAdded := pool.Put(endpoint)
+	switch {
+	case endpointAdded == route.ADDED:
+		r.events.emit(EndpointAdded, routekey, endpoint)
+	case endpointAdded == route.UPDATED && !previous.Equivalent(endpoint):
+		// re-registrations that change nothing are not worth an event
+		r.events.emit(EndpointUpdated, routekey, endpoint)
+	}
+	return endpointAdded
+}
 
-	r.timeOfLastUpdate = t
-	r.Unlock()
+func (r *RouteRegistry) newPool(host, contextPath string) *route.Pool {
+	pool := route.NewPool(r.dropletStaleThreshold/4, host, contextPath)
+	pool.SetSourcePrecedence(r.sourcePrecedence)
+	pool.SetSlowStart(r.slowStart)
+	pool.SetZone(r.zone, r.zoneAware)
+	pool.SetConnectionLimits(r.backends.Limits(host + contextPath))
+	return pool
+}
 
+func (r *RouteRegistry) registered(uri route.Uri, endpoint *route.Endpoint, endpointAdded route.PoolPutResult) {
 	r.reporter.CaptureRegistryMessage(endpoint)
 
 	if endpointAdded == route.ADDED && !endpoint.UpdatedAt.IsZero() {
@@ -117,47 +252,95 @@ func (r *RouteRegistry) Register(uri route.Uri, endpoint *route.Endpoint) {
 	}
 }
 
-func (r *RouteRegistry) Unregister(uri route.Uri, endpoint *route.Endpoint) {
-	if !r.endpointInRouterShard(endpoint) {
-		return
-	}
-
-	r.Lock()
-
+func (r *RouteRegistry) unregister(txn *container.Txn, uri route.Uri, endpoint *route.Endpoint) {
 	uri = uri.RouteKey()
 
-	pool := r.byURI.Find(uri)
+	pool := txn.Find(uri)
 	if pool != nil {
+		if timeout := r.drainTimeout(endpoint); timeout > 0 {
+			r.drain(pool, uri, endpoint, timeout)
+			return
+		}
+
 		endpointRemoved := pool.Remove(endpoint)
 		if endpointRemoved {
 			r.logger.Debug("endpoint-unregistered", zapData(uri, endpoint)...)
+			r.events.emit(EndpointRemoved, uri, endpoint)
 		} else {
 			r.logger.Debug("endpoint-not-unregistered", zapData(uri, endpoint)...)
 		}
 
 		if pool.IsEmpty() {
-			r.byURI.Delete(uri)
+			txn.Delete(uri)
+			r.events.emit(RouteRemoved, uri, nil)
+		}
+	}
+}
+
+func (r *RouteRegistry) drainTimeout(endpoint *route.Endpoint) time.Duration {
+	if endpoint.DrainTimeout > 0 {
+		return endpoint.DrainTimeout
+	}
+	return r.endpointDrainTimeout
+}
+
+// drain stops routing new requests to the endpoint and removes it once the
+// timeout has passed, so that requests in flight can complete.
+func (r *RouteRegistry) drain(pool *route.Pool, uri route.Uri, endpoint *route.Endpoint, timeout time.Duration) {
+	if !pool.Drain(endpoint, time.Now().Add(timeout)) {
+		r.logger.Debug("endpoint-not-unregistered", zapData(uri, endpoint)...)
+		return
+	}
+
+	r.logger.Debug("endpoint-draining", append(zapData(uri, endpoint), zap.Duration("timeout", timeout))...)
+	r.events.emit(EndpointDraining, uri, endpoint)
+	r.reporter.CaptureEndpointDraining()
+	time.AfterFunc(timeout, func() { r.removeDrained(uri, endpoint) })
+}
+
+func (r *RouteRegistry) removeDrained(uri route.Uri, endpoint *route.Endpoint) {
+	r.Lock()
+
+	txn := r.table().Txn()
+	pool := txn.Find(uri)
+	removed := pool != nil && pool.RemoveDrained(endpoint)
+	if removed {
+		r.logger.Debug("endpoint-drained", zapData(uri, endpoint)...)
+		r.events.emit(EndpointRemoved, uri, endpoint)
+
+		if pool.IsEmpty() {
+			txn.Delete(uri)
+			r.events.emit(RouteRemoved, uri, nil)
 		}
 	}
+	r.publish(txn.Commit())
 
 	r.Unlock()
-	r.reporter.CaptureUnregistryMessage(endpoint)
+
+	if removed {
+		r.reporter.CaptureEndpointDrained()
+	}
+}
+
+// Subscribe returns a subscription to the changes of the routing table,
+// buffering up to bufferSize events.
+func (r *RouteRegistry) Subscribe(bufferSize int) *Subscription {
+	return r.events.subscribe(bufferSize)
 }
 
 func (r *RouteRegistry) Lookup(uri route.Uri) *route.Pool {
 	started := time.Now()
 
-	r.RLock()
+	table := r.table()
 
 	uri = uri.RouteKey()
 	var err error
-	pool := r.byURI.MatchUri(uri)
+	pool := table.MatchUri(uri)
 	for pool == nil && err == nil {
 		uri, err = uri.NextWildcard()
-		pool = r.byURI.MatchUri(uri)
+		pool = table.MatchUri(uri)
 	}
 
-	r.RUnlock()
 	endLookup := time.Now()
 	r.reporter.CaptureLookupTime(endLookup.Sub(started))
 
@@ -182,6 +365,50 @@ func (r *RouteRegistry) endpointInRouterShard(endpoint *route.Endpoint) bool {
 	return false
 }
 
+// SetDomainPolicy replaces the policy that registrations are checked against
+// and removes the endpoints that violate it. A nil policy allows everything.
+func (r *RouteRegistry) SetDomainPolicy(p *DomainPolicy) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.domainPolicy.Store(p)
+	if p == nil {
+		return
+	}
+
+	table := r.table()
+	txn := table.Txn()
+	table.Each(func(uri route.Uri, pool *route.Pool) {
+		var violating []*route.Endpoint
+		pool.Each(func(e *route.Endpoint) {
+			if !r.endpointOwnsDomain(uri, e) {
+				violating = append(violating, e)
+			}
+		})
+		for _, e := range violating {
+			if pool.Remove(e) {
+				r.logger.Info("endpoint-evicted-by-domain-policy", zapData(uri, e)...)
+				r.events.emit(EndpointRemoved, uri, e)
+			}
+		}
+		if len(violating) > 0 && pool.IsEmpty() {
+			txn.Delete(uri)
+			r.events.emit(RouteRemoved, uri, nil)
+		}
+	})
+	r.publish(txn.Commit())
+}
+
+func (r *RouteRegistry) endpointOwnsDomain(uri route.Uri, endpoint *route.Endpoint) bool {
+	err := r.domainPolicy.Load().(*DomainPolicy).Check(uri, endpoint)
+	if err != nil {
+		r.logger.Info("domain-ownership-violation", append(zapData(uri, endpoint), zap.String("app_id", endpoint.ApplicationId), zap.Error(err))...)
+		r.reporter.CaptureDomainOwnershipViolation()
+		return false
+	}
+	return true
+}
+
 func (r *RouteRegistry) LookupWithInstance(uri route.Uri, appID string, appIndex string) *route.Pool {
 	uri = uri.RouteKey()
 	p := r.Lookup(uri)
@@ -195,6 +422,11 @@ func (r *RouteRegistry) LookupWithInstance(uri route.Uri, appID string, appIndex
 	p.Each(func(e *route.Endpoint) {
 		if (e.ApplicationId == appID) && (e.PrivateInstanceIndex == appIndex) {
 			surgicalPool = route.NewPool(0, p.Host(), p.ContextPath())
+			// requests to an instance do not queue: the requests it finishes
+			// for the route are not seen by this pool
+			limits := r.backends.Limits(p.Host() + p.ContextPath())
+			limits.MaxPendingRequests = 0
+			surgicalPool.SetConnectionLimits(limits)
 			surgicalPool.Put(e)
 		}
 	})
@@ -202,6 +434,98 @@ func (r *RouteRegistry) LookupWithInstance(uri route.Uri, appID string, appIndex
 	return surgicalPool
 }
 
+// SetMaintenance puts the route, or every route of the domain for a wildcard
+// uri, into maintenance mode, replacing any previous settings.
+func (r *RouteRegistry) SetMaintenance(m *route.Maintenance) {
+	m.Route = maintenanceKey(m.Route)
+
+	r.Lock()
+	maintenance := r.maintenanceRoutes()
+	updated := make(map[route.Uri]*route.Maintenance, len(maintenance)+1)
+	for k, v := range maintenance {
+		updated[k] = v
+	}
+	updated[m.Route] = m
+	r.maintenance.Store(updated)
+	r.Unlock()
+
+	r.logger.Info("maintenance-mode-set", zap.Stringer("uri", m.Route), zap.Int("status_code", m.Code()))
+}
+
+// RemoveMaintenance takes the route out of maintenance mode. It returns false
+// if the route was not in maintenance mode.
+func (r *RouteRegistry) RemoveMaintenance(uri route.Uri) bool {
+	uri = maintenanceKey(uri)
+
+	r.Lock()
+	maintenance := r.maintenanceRoutes()
+	_, ok := maintenance[uri]
+	if ok {
+		updated := make(map[route.Uri]*route.Maintenance, len(maintenance))
+		for k, v := range maintenance {
+			if k != uri {
+				updated[k] = v
+			}
+		}
+		r.maintenance.Store(updated)
+	}
+	r.Unlock()
+
+	if ok {
+		r.logger.Info("maintenance-mode-removed", zap.Stringer("uri", uri))
+	}
+	return ok
+}
+
+// MaintenanceRoutes returns the routes in maintenance mode sorted by uri.
+func (r *RouteRegistry) MaintenanceRoutes() []route.Maintenance {
+	maintenance := r.maintenanceRoutes()
+	routes := make([]route.Maintenance, 0, len(maintenance))
+	for _, m := range maintenance {
+		routes = append(routes, *m)
+	}
+
+	sort.Sort(byRoute(routes))
+	return routes
+}
+
+type byRoute []route.Maintenance
+
+func (s byRoute) Len() int           { return len(s) }
+func (s byRoute) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byRoute) Less(i, j int) bool { return s[i].Route < s[j].Route }
+
+// LookupMaintenance returns the maintenance settings that apply to the uri.
+// The most specific match wins: the uri and its parent paths are checked
+// first, then the host, then the wildcard domains of the host.
+func (r *RouteRegistry) LookupMaintenance(uri route.Uri) *route.Maintenance {
+	maintenance := r.maintenanceRoutes()
+	if len(maintenance) == 0 {
+		return nil
+	}
+
+	host, contextPath := splitHostAndContextPath(uri.RouteKey())
+	hostUri := route.Uri(host)
+	for {
+		path := strings.TrimSuffix(contextPath, "/")
+		for {
+			if m, ok := maintenance[route.Uri(hostUri.String()+path)]; ok {
+				return m
+			}
+			if path == "" {
+				break
+			}
+			path = path[:strings.LastIndex(path, "/")]
+		}
+
+		var err error
+		hostUri, err = hostUri.NextWildcard()
+		if err != nil {
+			return nil
+		}
+	}
+}
+
 func (r *RouteRegistry) StartPruningCycle() {
 	if r.pruneStaleDropletsInterval > 0 {
 		r.Lock()
@@ -232,11 +556,7 @@ func (r *RouteRegistry) StopPruningCycle() {
 }
 
 func (registry *RouteRegistry) NumUris() int {
-	registry.RLock()
-	uriCount := registry.byURI.PoolCount()
-	registry.RUnlock()
-
-	return uriCount
+	return registry.table().PoolCount()
 }
 
 func (r *RouteRegistry) TimeOfLastUpdate() time.Time {
@@ -248,18 +568,16 @@ func (r *RouteRegistry) TimeOfLastUpdate() time.Time {
 }
 
 func (r *RouteRegistry) NumEndpoints() int {
-	r.RLock()
-	count := r.byURI.EndpointCount()
-	r.RUnlock()
+	return r.table().EndpointCount()
+}
 
-	return count
+// EachRoute calls f with every route and its pool, in no particular order.
+func (r *RouteRegistry) EachRoute(f func(uri route.Uri, pool *route.Pool)) {
+	r.table().Each(f)
 }
 
 func (r *RouteRegistry) MarshalJSON() ([]byte, error) {
-	r.RLock()
-	defer r.RUnlock()
-
-	return json.Marshal(r.byURI.ToMap())
+	return json.Marshal(r.table().ToMap())
 }
 
 func (r *RouteRegistry) pruneStaleDroplets() {
@@ -281,9 +599,21 @@ func (r *RouteRegistry) pruneStaleDroplets() {
 	}
 	r.pruningStatus = CONNECTED
 
-	r.byURI.EachNodeWithPool(func(t *container.Trie) {
-		endpoints := t.Pool.PruneEndpoints()
-		t.Snip()
+	if r.holdPruning() {
+		return
+	}
+
+	table := r.table()
+	txn := table.Txn()
+	table.Each(func(uri route.Uri, pool *route.Pool) {
+		endpoints := pool.PruneEndpoints()
+		for _, e := range endpoints {
+			r.events.emit(EndpointPruned, uri, e)
+		}
+		if pool.IsEmpty() {
+			txn.Delete(uri)
+			r.events.emit(RouteRemoved, uri, nil)
+		}
 		if len(endpoints) > 0 {
 			addresses := []string{}
 			for _, e := range endpoints {
@@ -294,13 +624,56 @@ func (r *RouteRegistry) pruneStaleDroplets() {
 				isolationSegment = "-"
 			}
 			r.logger.Info("pruned-route",
-				zap.String("uri", t.ToPath()),
+				zap.Stringer("uri", uri),
 				zap.Object("endpoints", addresses),
 				zap.Object("isolation_segment", isolationSegment),
 			)
 			r.reporter.CaptureRoutesPruned(uint64(len(endpoints)))
 		}
 	})
+	r.publish(txn.Commit())
+}
+
+// holdPruning reports whether the prune protection holds back this pruning
+// cycle. Once engaged, it holds until a registration arrives, which shows that
+// registrations are coming through and the stale endpoints are really gone.
+// Callers must hold the lock.
+func (r *RouteRegistry) holdPruning() bool {
+	if r.pruneProtection.MaxPercent == 0 && r.pruneProtection.MaxEndpoints == 0 {
+		return false
+	}
+
+	stale, total := 0, 0
+	r.table().Each(func(_ route.Uri, pool *route.Pool) {
+		s, t := pool.StaleCount()
+		stale += s
+		total += t
+	})
+
+	if r.pruneProtected {
+		if !r.timeOfLastUpdate.After(r.pruneProtectedSince) {
+			r.logger.Info("prune-protection-holding", zap.Int("stale_endpoints", stale), zap.Int("total_endpoints", total))
+			r.reporter.CapturePruneProtection(true, stale)
+			return true
+		}
+		r.pruneProtected = false
+		r.logger.Info("prune-protection-released", zap.Int("stale_endpoints", stale), zap.Int("total_endpoints", total))
+		r.reporter.CapturePruneProtection(false, stale)
+		return false
+	}
+
+	exceeded := (r.pruneProtection.MaxEndpoints > 0 && stale > r.pruneProtection.MaxEndpoints) ||
+		(r.pruneProtection.MaxPercent > 0 && stale*100 > total*r.pruneProtection.MaxPercent)
+	if exceeded {
+		r.pruneProtected = true
+		r.pruneProtectedSince = time.Now()
+		r.logger.Error("prune-protection-engaged", zap.Int("stale_endpoints", stale), zap.Int("total_endpoints", total))
+		r.reporter.CapturePruneProtection(true, stale)
+		return true
+	}
+
+	r.reporter.CapturePruneProtection(false, stale)
+	return false
 }
 
 func (r *RouteRegistry) SuspendPruning(f func() bool) {
@@ -312,11 +685,29 @@ func (r *RouteRegistry) SuspendPruning(f func() bool) {
 // bulk update to mark pool / endpoints as updated
 func (r *RouteRegistry) freshenRoutes() {
 	now := time.Now()
-	r.byURI.EachNodeWithPool(func(t *container.Trie) {
-		t.Pool.MarkUpdated(now)
+	r.table().Each(func(_ route.Uri, pool *route.Pool) {
+		pool.MarkUpdated(now)
 	})
 }
 
+// table returns the current routing table. It must not be modified.
+func (r *RouteRegistry) table() *container.Table {
+	return r.routes.Load().(*container.Table)
+}
+
+// publish makes the table visible to readers. Callers must hold the lock.
+func (r *RouteRegistry) publish(t *container.Table) {
+	r.routes.Store(t)
+}
+
+func (r *RouteRegistry) maintenanceRoutes() map[route.Uri]*route.Maintenance {
+	return r.maintenance.Load().(map[route.Uri]*route.Maintenance)
+}
+
+func maintenanceKey(uri route.Uri) route.Uri {
+	return route.Uri(uri.RouteKey().String())
+}
+
 func splitHostAndContextPath(uri route.Uri) (string, string) {
 	contextPath := "/"
 	split := strings.SplitN(strings.TrimPrefix(uri.String(), "/"), "/", 2)
@@ -81,3 +81,35 @@ func BenchmarkRegisterWithOneRoute(b *testing.B) {
 		r.Register("foo.example.com", fooEndpoint)
 	}
 }
+
+func BenchmarkLookupWithConcurrentRegister(b *testing.B) {
+	r := registry.NewRouteRegistry(testLogger, configObj, reporter)
+
+	for i := 0; i < 100000; i++ {
+		r.Register(route.Uri(fmt.Sprintf("foo%d.example.com", i)), fooEndpoint)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			uri := route.Uri(fmt.Sprintf("bar%d.example.com", i%1000))
+			r.Register(uri, fooEndpoint)
+			r.Register(route.Uri(fmt.Sprintf("foo%d.example.com", i%100000)), fooEndpoint)
+			r.Unregister(uri, fooEndpoint)
+		}
+	}()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			r.Lookup("foo50000.example.com/some/path")
+		}
+	})
+}
@@ -701,6 +701,253 @@ var _ = Describe("RouteRegistry", func() {
 		})
 	})
 
+	Context("UpdateBatch", func() {
+		It("applies the updates in order", func() {
+			r.Register("bar", barEndpoint)
+
+			r.UpdateBatch([]RouteUpdate{
+				{Uri: "foo", Endpoint: fooEndpoint},
+				{Uri: "foo/path", Endpoint: bar2Endpoint},
+				{Uri: "bar", Endpoint: barEndpoint, Unregister: true},
+				{Uri: "baz", Endpoint: fooEndpoint},
+				{Uri: "baz", Endpoint: fooEndpoint, Unregister: true},
+			})
+
+			Expect(r.NumUris()).To(Equal(2))
+			Expect(r.Lookup("foo")).ToNot(BeNil())
+			Expect(r.Lookup("foo/path")).ToNot(BeNil())
+			Expect(r.Lookup("bar")).To(BeNil())
+			Expect(r.Lookup("baz")).To(BeNil())
+
+			Expect(reporter.CaptureRegistryMessageCallCount()).To(Equal(4))
+			Expect(reporter.CaptureUnregistryMessageCallCount()).To(Equal(2))
+		})
+
+		It("skips endpoints outside of the router's shard", func() {
+			configObj.RoutingTableShardingMode = config.SHARD_SEGMENTS
+			r = NewRouteRegistry(logger, configObj, reporter)
+
+			r.UpdateBatch([]RouteUpdate{
+				{Uri: "foo", Endpoint: fooEndpoint},
+				{Uri: "bar", Endpoint: route.NewEndpoint(&route.EndpointOpts{IsolationSegment: "foo"})},
+			})
+
+			Expect(r.NumUris()).To(Equal(1))
+			Expect(r.Lookup("bar")).ToNot(BeNil())
+		})
+	})
+
+	Context("Source precedence", func() {
+		var natsEndpoint, apiEndpoint *route.Endpoint
+
+		BeforeEach(func() {
+			configObj.SourcePrecedence = map[string]int{route.SourceRoutingAPI: 1}
+			r = NewRouteRegistry(logger, configObj, reporter)
+
+			natsEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 8080, AppId: "nats", Source: route.SourceNATS})
+			apiEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 8080, AppId: "api", Source: route.SourceRoutingAPI})
+		})
+
+		appId := func() string {
+			var id string
+			r.Lookup("foo").Each(func(e *route.Endpoint) { id = e.ApplicationId })
+			return id
+		}
+
+		It("routes to the endpoint of the source with the highest precedence", func() {
+			r.Register("foo", apiEndpoint)
+			r.Register("foo", natsEndpoint)
+
+			Expect(r.NumEndpoints()).To(Equal(1))
+			Expect(appId()).To(Equal("api"))
+		})
+
+		It("keeps the endpoint until every source has unregistered it", func() {
+			r.Register("foo", apiEndpoint)
+			r.Register("foo", natsEndpoint)
+
+			r.Unregister("foo", apiEndpoint)
+			Expect(appId()).To(Equal("nats"))
+
+			r.Unregister("foo", natsEndpoint)
+			Expect(r.Lookup("foo")).To(BeNil())
+		})
+
+		It("shows the sources of the endpoints", func() {
+			r.Register("foo", apiEndpoint)
+			r.Register("foo", natsEndpoint)
+
+			marshalled, err := json.Marshal(r)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(marshalled)).To(ContainSubstring(`"source":"routing-api","sources":["nats","routing-api"]`))
+		})
+	})
+
+	Context("Zone aware routing", func() {
+		BeforeEach(func() {
+			configObj.Zone = "z1"
+			configObj.ZoneAwareRouting = config.ZoneAwareRoutingConfig{Enabled: true, Tag: "az", MinHealthyEndpoints: 1}
+			r = NewRouteRegistry(logger, configObj, reporter)
+		})
+
+		It("takes the zone of endpoints from their tags", func() {
+			r.Register("foo", route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 8080, Tags: map[string]string{"az": "z2"}}))
+
+			r.Lookup("foo").Each(func(e *route.Endpoint) {
+				Expect(e.Zone).To(Equal("z2"))
+			})
+		})
+
+		It("prefers endpoints in the zone of the router", func() {
+			local := route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 8080, Tags: map[string]string{"az": "z1"}})
+			r.Register("foo", local)
+			r.Register("foo", route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.2", Port: 8080, Tags: map[string]string{"az": "z2"}}))
+
+			iter := r.Lookup("foo").Endpoints(config.LOAD_BALANCE_RR, "")
+			for i := 0; i < 10; i++ {
+				Expect(iter.Next()).To(Equal(local))
+			}
+		})
+	})
+
+	Context("Connection limits", func() {
+		BeforeEach(func() {
+			configObj.Backends.MaxConns = 2
+			configObj.Backends.RouteLimits = []config.RouteLimit{
+				{Route: "slow.com/api", ConnectionLimits: config.ConnectionLimits{MaxConns: 1}},
+				{Route: "fast.com", ConnectionLimits: config.ConnectionLimits{MaxConns: 3}},
+			}
+			r = NewRouteRegistry(logger, configObj, reporter)
+		})
+
+		limitOf := func(uri route.Uri) int {
+			endpoint := route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 8080})
+			r.Register(uri, endpoint)
+			iter := r.Lookup(uri).Endpoints("", "")
+			n := 0
+			for iter.Next() != nil {
+				iter.PreRequest(endpoint)
+				n++
+			}
+			return n
+		}
+
+		It("applies the limits of the route", func() {
+			Expect(limitOf("slow.com/api")).To(Equal(1))
+			Expect(limitOf("fast.com")).To(Equal(3))
+			Expect(limitOf("slow.com")).To(Equal(2))
+		})
+	})
+
+	Context("Draining", func() {
+		var endpoint *route.Endpoint
+
+		BeforeEach(func() {
+			endpoint = route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 8080})
+			r.Register("foo", endpoint)
+		})
+
+		It("removes the endpoint after its drain timeout", func() {
+			sub := r.Subscribe(10)
+			defer sub.Close()
+
+			r.Unregister("foo", route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 8080, DrainTimeout: 100 * time.Millisecond}))
+
+			Expect(r.NumEndpoints()).To(Equal(1))
+			Expect(r.Lookup("foo").NumDraining()).To(Equal(1))
+			Expect(reporter.CaptureEndpointDrainingCallCount()).To(Equal(1))
+			var event Event
+			Expect(sub.C).To(Receive(&event))
+			Expect(event.Type).To(Equal(EndpointDraining))
+
+			Eventually(func() *route.Pool { return r.Lookup("foo") }).Should(BeNil())
+			Expect(reporter.CaptureEndpointDrainedCallCount()).To(Equal(1))
+		})
+
+		It("drains with the configured timeout", func() {
+			configObj.EndpointDrainTimeout = time.Hour
+			r = NewRouteRegistry(logger, configObj, reporter)
+			r.Register("foo", endpoint)
+
+			r.Unregister("foo", endpoint)
+			Consistently(func() int { return r.Lookup("foo").NumDraining() }, 100*time.Millisecond).Should(Equal(1))
+		})
+
+		It("keeps the endpoint when it is registered again", func() {
+			r.Unregister("foo", route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 8080, DrainTimeout: 50 * time.Millisecond}))
+			r.Register("foo", endpoint)
+
+			Consistently(r.NumEndpoints, 150*time.Millisecond).Should(Equal(1))
+			Expect(reporter.CaptureEndpointDrainedCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("Domain ownership", func() {
+		var ownerEndpoint *route.Endpoint
+
+		BeforeEach(func() {
+			policy, err := NewDomainPolicy([]config.DomainOwnershipRule{
+				{Domain: "tenant.example.com", IsolationSegments: []string{"foo"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			r.SetDomainPolicy(policy)
+
+			ownerEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.4", IsolationSegment: "foo"})
+		})
+
+		It("rejects registrations for domains owned by others", func() {
+			r.Register("app.tenant.example.com", fooEndpoint)
+			Expect(r.Lookup("app.tenant.example.com")).To(BeNil())
+			Expect(reporter.CaptureRegistryMessageCallCount()).To(Equal(0))
+			Expect(reporter.CaptureDomainOwnershipViolationCallCount()).To(Equal(1))
+
+			r.Register("app.tenant.example.com", ownerEndpoint)
+			r.Register("app.example.com", fooEndpoint)
+			Expect(r.NumUris()).To(Equal(2))
+			Expect(reporter.CaptureDomainOwnershipViolationCallCount()).To(Equal(1))
+		})
+
+		It("does not check unregistrations", func() {
+			r.Register("app.tenant.example.com", ownerEndpoint)
+			r.Unregister("app.tenant.example.com", route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.4"}))
+
+			Expect(r.NumEndpoints()).To(Equal(0))
+			Expect(reporter.CaptureUnregistryMessageCallCount()).To(Equal(1))
+			Expect(reporter.CaptureDomainOwnershipViolationCallCount()).To(Equal(0))
+		})
+
+		It("removes the endpoints that violate a new policy", func() {
+			r.Register("app.tenant.example.com", ownerEndpoint)
+			r.Register("app.other.example.com", fooEndpoint)
+			r.Register("app.other.example.com", ownerEndpoint)
+			r.Register("app.example.com", fooEndpoint)
+
+			policy, err := NewDomainPolicy([]config.DomainOwnershipRule{
+				{Domain: "other.example.com", IsolationSegments: []string{"foo"}},
+				{Domain: "tenant.example.com", IsolationSegments: []string{"bar"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			r.SetDomainPolicy(policy)
+
+			Expect(r.Lookup("app.tenant.example.com")).To(BeNil())
+			Expect(r.Lookup("app.other.example.com").Endpoints("", "").Next()).To(Equal(ownerEndpoint))
+			Expect(r.Lookup("app.example.com")).ToNot(BeNil())
+			Expect(r.NumEndpoints()).To(Equal(2))
+			Expect(logger).To(gbytes.Say("endpoint-evicted-by-domain-policy"))
+		})
+
+		It("skips batched updates for domains owned by others", func() {
+			r.UpdateBatch([]RouteUpdate{
+				{Uri: "app.tenant.example.com", Endpoint: fooEndpoint},
+				{Uri: "app.tenant.example.com", Endpoint: ownerEndpoint},
+			})
+
+			Expect(r.NumEndpoints()).To(Equal(1))
+			Expect(reporter.CaptureRegistryMessageCallCount()).To(Equal(1))
+			Expect(reporter.CaptureDomainOwnershipViolationCallCount()).To(Equal(1))
+		})
+	})
+
 	Context("Lookup", func() {
 		It("case insensitive lookup", func() {
 			m := route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 1234})
@@ -936,6 +1183,70 @@ var _ = Describe("RouteRegistry", func() {
 		})
 	})
 
+	Context("Maintenance", func() {
+		It("matches the route and its sub paths", func() {
+			r.SetMaintenance(&route.Maintenance{Route: "foo.com/api"})
+
+			Expect(r.LookupMaintenance("foo.com/api")).ToNot(BeNil())
+			Expect(r.LookupMaintenance("FOO.com/api/v1/apps?q=1")).ToNot(BeNil())
+			Expect(r.LookupMaintenance("foo.com/apis")).To(BeNil())
+			Expect(r.LookupMaintenance("foo.com")).To(BeNil())
+		})
+
+		It("matches every route of a host", func() {
+			r.SetMaintenance(&route.Maintenance{Route: "foo.com/"})
+
+			Expect(r.LookupMaintenance("foo.com")).ToNot(BeNil())
+			Expect(r.LookupMaintenance("foo.com/bar")).ToNot(BeNil())
+			Expect(r.LookupMaintenance("bar.foo.com")).To(BeNil())
+		})
+
+		It("matches wildcard domains", func() {
+			r.SetMaintenance(&route.Maintenance{Route: "*.foo.com"})
+
+			Expect(r.LookupMaintenance("bar.foo.com/baz")).ToNot(BeNil())
+			Expect(r.LookupMaintenance("baz.bar.foo.com")).ToNot(BeNil())
+			Expect(r.LookupMaintenance("foo.com")).To(BeNil())
+		})
+
+		It("prefers the most specific match", func() {
+			r.SetMaintenance(&route.Maintenance{Route: "*.foo.com", StatusCode: 503})
+			r.SetMaintenance(&route.Maintenance{Route: "bar.foo.com", StatusCode: 502})
+			r.SetMaintenance(&route.Maintenance{Route: "bar.foo.com/baz", StatusCode: 500})
+
+			Expect(r.LookupMaintenance("bar.foo.com/baz/qux").StatusCode).To(Equal(500))
+			Expect(r.LookupMaintenance("bar.foo.com/other").StatusCode).To(Equal(502))
+			Expect(r.LookupMaintenance("qux.foo.com").StatusCode).To(Equal(503))
+		})
+
+		It("survives the route being registered, unregistered and registered again", func() {
+			r.Register("foo.com", fooEndpoint)
+			r.SetMaintenance(&route.Maintenance{Route: "foo.com"})
+			r.Unregister("foo.com", fooEndpoint)
+			r.Register("foo.com", fooEndpoint)
+
+			Expect(r.LookupMaintenance("foo.com")).ToNot(BeNil())
+		})
+
+		It("can be removed", func() {
+			r.SetMaintenance(&route.Maintenance{Route: "foo.com"})
+
+			Expect(r.RemoveMaintenance("FOO.com/")).To(BeTrue())
+			Expect(r.LookupMaintenance("foo.com")).To(BeNil())
+			Expect(r.RemoveMaintenance("foo.com")).To(BeFalse())
+		})
+
+		It("lists the routes in maintenance mode", func() {
+			r.SetMaintenance(&route.Maintenance{Route: "foo.com"})
+			r.SetMaintenance(&route.Maintenance{Route: "bar.com", RetryAfter: 30})
+
+			Expect(r.MaintenanceRoutes()).To(Equal([]route.Maintenance{
+				{Route: "bar.com", RetryAfter: 30},
+				{Route: "foo.com"},
+			}))
+		})
+	})
+
 	Context("Prunes Stale Droplets", func() {
 		AfterEach(func() {
 			r.StopPruningCycle()
@@ -1167,6 +1478,96 @@ var _ = Describe("RouteRegistry", func() {
 			})
 		})
 
+		Context("when prune protection is configured", func() {
+			var (
+				endpoints []*route.Endpoint
+				doneChan  chan struct{}
+			)
+
+			keepRegistering := func(n int) {
+				go func(r *RouteRegistry, endpoints []*route.Endpoint, done chan struct{}) {
+					for {
+						select {
+						case <-done:
+							return
+						default:
+							for i := 0; i < n; i++ {
+								r.Register(route.Uri(fmt.Sprintf("foo-%d", i)), endpoints[i])
+							}
+							time.Sleep(5 * time.Millisecond)
+						}
+					}
+				}(r, endpoints, doneChan)
+			}
+
+			BeforeEach(func() {
+				configObj.PruneProtection.MaxPercent = 50
+				r = NewRouteRegistry(logger, configObj, reporter)
+
+				doneChan = make(chan struct{})
+				endpoints = nil
+				for i := 0; i < 4; i++ {
+					e := route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: uint16(1024 + i)})
+					endpoints = append(endpoints, e)
+					r.Register(route.Uri(fmt.Sprintf("foo-%d", i)), e)
+				}
+			})
+
+			AfterEach(func() {
+				close(doneChan)
+			})
+
+			It("does not prune more than the allowed percentage of endpoints", func() {
+				r.StartPruningCycle()
+
+				Eventually(logger).Should(gbytes.Say("prune-protection-engaged"))
+				Consistently(r.NumEndpoints, 4*configObj.PruneStaleDropletsInterval).Should(Equal(4))
+
+				Expect(reporter.CapturePruneProtectionCallCount()).To(BeNumerically(">", 0))
+				engaged, stale := reporter.CapturePruneProtectionArgsForCall(0)
+				Expect(engaged).To(BeTrue())
+				Expect(stale).To(Equal(4))
+			})
+
+			It("resumes pruning once registrations arrive again", func() {
+				r.StartPruningCycle()
+				Eventually(logger).Should(gbytes.Say("prune-protection-engaged"))
+
+				keepRegistering(3)
+
+				Eventually(logger).Should(gbytes.Say("prune-protection-released"))
+				Eventually(r.NumEndpoints).Should(Equal(3))
+			})
+
+			It("prunes the stale endpoints when they never return but other registrations arrive", func() {
+				r.StartPruningCycle()
+				Eventually(logger).Should(gbytes.Say("prune-protection-engaged"))
+
+				keepRegistering(1)
+
+				Eventually(logger).Should(gbytes.Say("prune-protection-released"))
+				Eventually(r.NumEndpoints).Should(Equal(1))
+				Consistently(r.NumEndpoints, 4*configObj.PruneStaleDropletsInterval).Should(Equal(1))
+			})
+
+			Context("with a maximum number of endpoints", func() {
+				BeforeEach(func() {
+					configObj.PruneProtection.MaxPercent = 0
+					configObj.PruneProtection.MaxEndpoints = 2
+					r = NewRouteRegistry(logger, configObj, reporter)
+					for i, e := range endpoints {
+						r.Register(route.Uri(fmt.Sprintf("foo-%d", i)), e)
+					}
+				})
+
+				It("does not prune more endpoints than allowed", func() {
+					r.StartPruningCycle()
+
+					Eventually(logger).Should(gbytes.Say("prune-protection-engaged"))
+					Consistently(r.NumEndpoints, 4*configObj.PruneStaleDropletsInterval).Should(Equal(4))
+				})
+			})
+		})
 	})
 
 	Context("Varz data", func() {
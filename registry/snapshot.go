@@ -0,0 +1,235 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/uber-go/zap"
+
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/routing-api/models"
+)
+
+const snapshotVersion = 1
+
+type snapshot struct {
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Routes    []snapshotRoute `json:"routes"`
+}
+
+type snapshotRoute struct {
+	Uri       route.Uri          `json:"uri"`
+	Endpoints []snapshotEndpoint `json:"endpoints"`
+}
+
+type snapshotEndpoint struct {
+	Host                    string                 `json:"host"`
+	Port                    uint16                 `json:"port"`
+	TLS                     bool                   `json:"tls,omitempty"`
+	AppId                   string                 `json:"app_id,omitempty"`
+	PrivateInstanceId       string                 `json:"private_instance_id,omitempty"`
+	PrivateInstanceIndex    string                 `json:"private_instance_index,omitempty"`
+	ServerCertDomainSAN     string                 `json:"server_cert_domain_san,omitempty"`
+	Tags                    map[string]string      `json:"tags,omitempty"`
+	StaleThresholdInSeconds int                    `json:"stale_threshold_in_seconds"`
+	RouteServiceUrl         string                 `json:"route_service_url,omitempty"`
+	IsolationSegment        string                 `json:"isolation_segment,omitempty"`
+	ModificationTag         models.ModificationTag `json:"modification_tag"`
+	HeaderRules             *route.HeaderRules     `json:"header_rules,omitempty"`
+	Redirect                *route.Redirect        `json:"redirect,omitempty"`
+	HTTPSOnly               bool                   `json:"https_only,omitempty"`
+	UpdatedAt               time.Time              `json:"updated_at"`
+}
+
+// WriteSnapshot writes the routing table, including the modification tag and
+// the time of the last registration of every endpoint, as JSON.
+func (r *RouteRegistry) WriteSnapshot(w io.Writer) error {
+	s := snapshot{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now(),
+		Routes:    []snapshotRoute{},
+	}
+
+	pools := r.table().ToMap()
+	uris := make([]string, 0, len(pools))
+	for uri := range pools {
+		uris = append(uris, uri.String())
+	}
+	sort.Strings(uris)
+
+	for _, uri := range uris {
+		sr := snapshotRoute{Uri: route.Uri(uri)}
+		pools[route.Uri(uri)].EachUpdated(func(e *route.Endpoint, updated time.Time) {
+			se, err := newSnapshotEndpoint(e, updated)
+			if err != nil {
+				r.logger.Error("snapshot-endpoint-skipped", zap.String("uri", uri), zap.Error(err))
+				return
+			}
+			sr.Endpoints = append(sr.Endpoints, se)
+		})
+		if len(sr.Endpoints) > 0 {
+			s.Routes = append(s.Routes, sr)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(s)
+}
+
+// RestoreSnapshot loads a snapshot written by WriteSnapshot. The restored
+// endpoints are provisional: they are replaced by the next registration of
+// the same endpoint and are otherwise pruned once their stale threshold has
+// passed since the registration recorded in the snapshot. Endpoints that are
+// already stale or that are already in the registry are skipped. It returns
+// the number of endpoints restored.
+func (r *RouteRegistry) RestoreSnapshot(rd io.Reader) (int, error) {
+	var s snapshot
+	err := json.NewDecoder(rd).Decode(&s)
+	if err != nil {
+		return 0, err
+	}
+	if s.Version != snapshotVersion {
+		return 0, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+
+	now := time.Now()
+	restored := 0
+
+	r.Lock()
+	txn := r.table().Txn()
+	for _, sr := range s.Routes {
+		routekey := sr.Uri.RouteKey()
+		for _, se := range sr.Endpoints {
+			endpoint := se.toEndpoint()
+			if endpoint.StaleThreshold > r.dropletStaleThreshold || endpoint.StaleThreshold == 0 {
+				endpoint.StaleThreshold = r.dropletStaleThreshold
+			}
+			endpoint.Zone = endpoint.Tags[r.zoneAware.Tag]
+			if !r.endpointInRouterShard(endpoint) || !r.endpointOwnsDomain(sr.Uri, endpoint) || se.UpdatedAt.Add(endpoint.StaleThreshold).Before(now) {
+				continue
+			}
+
+			pool := txn.Find(routekey)
+			if pool == nil {
+				host, contextPath := splitHostAndContextPath(routekey)
+				pool = r.newPool(host, contextPath)
+				txn.Insert(routekey, pool)
+				r.events.emit(RouteAdded, routekey, nil)
+			}
+
+			if pool.Restore(endpoint, se.UpdatedAt) {
+				r.events.emit(EndpointAdded, routekey, endpoint)
+				restored++
+			}
+		}
+	}
+	r.publish(txn.Commit())
+	if restored > 0 {
+		r.timeOfLastUpdate = now
+	}
+	r.Unlock()
+
+	r.logger.Info("snapshot-restored", zap.Int("endpoints", restored), zap.String("created_at", s.CreatedAt.Format(time.RFC3339)))
+	return restored, nil
+}
+
+// SaveSnapshot writes the snapshot to the file at path. The file is replaced
+// atomically so that a crash never leaves a partial snapshot behind.
+func (r *RouteRegistry) SaveSnapshot(path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	err = r.WriteSnapshot(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// LoadSnapshot restores the snapshot from the file at path. A missing file is
+// not an error.
+func (r *RouteRegistry) LoadSnapshot(path string) (int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		r.logger.Info("snapshot-not-found", zap.String("path", path))
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return r.RestoreSnapshot(f)
+}
+
+func newSnapshotEndpoint(e *route.Endpoint, updated time.Time) (snapshotEndpoint, error) {
+	host, portStr, err := net.SplitHostPort(e.CanonicalAddr())
+	if err != nil {
+		return snapshotEndpoint{}, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return snapshotEndpoint{}, err
+	}
+
+	return snapshotEndpoint{
+		Host:                    host,
+		Port:                    uint16(port),
+		TLS:                     e.IsTLS(),
+		AppId:                   e.ApplicationId,
+		PrivateInstanceId:       e.PrivateInstanceId,
+		PrivateInstanceIndex:    e.PrivateInstanceIndex,
+		ServerCertDomainSAN:     e.ServerCertDomainSAN,
+		Tags:                    e.Tags,
+		StaleThresholdInSeconds: int(e.StaleThreshold.Seconds()),
+		RouteServiceUrl:         e.RouteServiceUrl,
+		IsolationSegment:        e.IsolationSegment,
+		ModificationTag:         e.ModificationTag,
+		HeaderRules:             e.HeaderRules,
+		Redirect:                e.Redirect,
+		HTTPSOnly:               e.HTTPSOnly,
+		UpdatedAt:               updated,
+	}, nil
+}
+
+func (se snapshotEndpoint) toEndpoint() *route.Endpoint {
+	endpoint := route.NewEndpoint(&route.EndpointOpts{
+		AppId:                   se.AppId,
+		Host:                    se.Host,
+		Port:                    se.Port,
+		ServerCertDomainSAN:     se.ServerCertDomainSAN,
+		PrivateInstanceId:       se.PrivateInstanceId,
+		PrivateInstanceIndex:    se.PrivateInstanceIndex,
+		Tags:                    se.Tags,
+		StaleThresholdInSeconds: se.StaleThresholdInSeconds,
+		RouteServiceUrl:         se.RouteServiceUrl,
+		ModificationTag:         se.ModificationTag,
+		IsolationSegment:        se.IsolationSegment,
+		UseTLS:                  se.TLS,
+		HeaderRules:             se.HeaderRules,
+		Redirect:                se.Redirect,
+		HTTPSOnly:               se.HTTPSOnly,
+	})
+	endpoint.Provisional = true
+	return endpoint
+}
@@ -0,0 +1,243 @@
+package registry_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	. "code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/test_util"
+	"code.cloudfoundry.org/routing-api/models"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Snapshot", func() {
+	var (
+		r, restored *RouteRegistry
+		configObj   *config.Config
+		endpoint    *route.Endpoint
+	)
+
+	BeforeEach(func() {
+		var err error
+		configObj, err = config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		configObj.PruneStaleDropletsInterval = 50 * time.Millisecond
+		configObj.DropletStaleThreshold = time.Minute
+
+		r = NewRouteRegistry(test_util.NewTestZapLogger("test"), configObj, new(fakes.FakeRouteRegistryReporter))
+		restored = NewRouteRegistry(test_util.NewTestZapLogger("test"), configObj, new(fakes.FakeRouteRegistryReporter))
+
+		endpoint = route.NewEndpoint(&route.EndpointOpts{
+			AppId:             "app-guid",
+			Host:              "192.168.1.1",
+			Port:              1234,
+			PrivateInstanceId: "instance-id",
+			Tags:              map[string]string{"component": "app"},
+			ModificationTag:   models.ModificationTag{Guid: "abc", Index: 3},
+			HTTPSOnly:         true,
+		})
+	})
+
+	It("restores the routes and endpoints of the snapshot", func() {
+		r.Register("foo.example.com/bar", endpoint)
+		r.Register("*.example.com", route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.2", Port: 80, UseTLS: true}))
+
+		var buf bytes.Buffer
+		Expect(r.WriteSnapshot(&buf)).To(Succeed())
+
+		n, err := restored.RestoreSnapshot(&buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(2))
+		Expect(restored.NumUris()).To(Equal(2))
+
+		pool := restored.Lookup("foo.example.com/bar")
+		Expect(pool).ToNot(BeNil())
+		Expect(pool.ContextPath()).To(Equal("/bar"))
+
+		var e *route.Endpoint
+		pool.Each(func(ep *route.Endpoint) { e = ep })
+		Expect(e.CanonicalAddr()).To(Equal("192.168.1.1:1234"))
+		Expect(e.ApplicationId).To(Equal("app-guid"))
+		Expect(e.PrivateInstanceId).To(Equal("instance-id"))
+		Expect(e.Tags).To(Equal(map[string]string{"component": "app"}))
+		Expect(e.ModificationTag).To(Equal(models.ModificationTag{Guid: "abc", Index: 3}))
+		Expect(e.HTTPSOnly).To(BeTrue())
+		Expect(e.Provisional).To(BeTrue())
+
+		wildcardPool := restored.Lookup("bar.example.com")
+		Expect(wildcardPool).ToNot(BeNil())
+		wildcardPool.Each(func(ep *route.Endpoint) { e = ep })
+		Expect(e.IsTLS()).To(BeTrue())
+	})
+
+	It("confirms a provisional endpoint on registration", func() {
+		r.Register("foo.example.com", endpoint)
+
+		var buf bytes.Buffer
+		Expect(r.WriteSnapshot(&buf)).To(Succeed())
+		_, err := restored.RestoreSnapshot(&buf)
+		Expect(err).ToNot(HaveOccurred())
+
+		// an unchanged modification tag would not normally replace the endpoint
+		fresh := route.NewEndpoint(&route.EndpointOpts{
+			Host:            "192.168.1.1",
+			Port:            1234,
+			ModificationTag: models.ModificationTag{Guid: "abc", Index: 3},
+		})
+		restored.Register("foo.example.com", fresh)
+
+		var e *route.Endpoint
+		restored.Lookup("foo.example.com").Each(func(ep *route.Endpoint) { e = ep })
+		Expect(e).To(BeIdenticalTo(fresh))
+		Expect(e.Provisional).To(BeFalse())
+	})
+
+	It("prunes provisional endpoints that are not registered again", func() {
+		configObj.DropletStaleThreshold = 100 * time.Millisecond
+		r = NewRouteRegistry(test_util.NewTestZapLogger("test"), configObj, new(fakes.FakeRouteRegistryReporter))
+		restored = NewRouteRegistry(test_util.NewTestZapLogger("test"), configObj, new(fakes.FakeRouteRegistryReporter))
+		r.Register("foo.example.com", endpoint)
+		r.Register("tls.example.com", route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.2", Port: 80, UseTLS: true}))
+
+		var buf bytes.Buffer
+		Expect(r.WriteSnapshot(&buf)).To(Succeed())
+		_, err := restored.RestoreSnapshot(&buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(restored.NumEndpoints()).To(Equal(2))
+
+		restored.StartPruningCycle()
+		defer restored.StopPruningCycle()
+
+		Eventually(restored.NumEndpoints).Should(Equal(0))
+	})
+
+	It("skips endpoints that are already stale", func() {
+		snapshot := `{"version":1,"routes":[{"uri":"foo.example.com","endpoints":[
+			{"host":"1.2.3.4","port":80,"stale_threshold_in_seconds":60,"updated_at":"` + time.Now().Add(-2*time.Minute).Format(time.RFC3339Nano) + `"},
+			{"host":"1.2.3.5","port":80,"stale_threshold_in_seconds":60,"updated_at":"` + time.Now().Add(-30*time.Second).Format(time.RFC3339Nano) + `"}
+		]}]}`
+
+		n, err := restored.RestoreSnapshot(strings.NewReader(snapshot))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(1))
+		Expect(restored.NumEndpoints()).To(Equal(1))
+	})
+
+	It("skips endpoints for domains owned by others", func() {
+		r.Register("app.tenant.example.com", endpoint)
+		r.Register("app.example.com", endpoint)
+		var buf bytes.Buffer
+		Expect(r.WriteSnapshot(&buf)).To(Succeed())
+
+		policy, err := NewDomainPolicy([]config.DomainOwnershipRule{
+			{Domain: "tenant.example.com", AppIds: []string{"other-app-guid"}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		restored.SetDomainPolicy(policy)
+
+		n, err := restored.RestoreSnapshot(&buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(1))
+		Expect(restored.Lookup("app.tenant.example.com")).To(BeNil())
+		Expect(restored.Lookup("app.example.com")).ToNot(BeNil())
+	})
+
+	It("does not replace endpoints that are already registered", func() {
+		r.Register("foo.example.com", endpoint)
+		var buf bytes.Buffer
+		Expect(r.WriteSnapshot(&buf)).To(Succeed())
+
+		registered := route.NewEndpoint(&route.EndpointOpts{Host: "192.168.1.1", Port: 1234})
+		restored.Register("foo.example.com", registered)
+
+		n, err := restored.RestoreSnapshot(&buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(0))
+
+		var e *route.Endpoint
+		restored.Lookup("foo.example.com").Each(func(ep *route.Endpoint) { e = ep })
+		Expect(e).To(BeIdenticalTo(registered))
+	})
+
+	It("rejects snapshots of an unknown version", func() {
+		_, err := restored.RestoreSnapshot(strings.NewReader(`{"version":2,"routes":[]}`))
+		Expect(err).To(MatchError("unsupported snapshot version 2"))
+	})
+
+	It("shows restored endpoints as provisional in the routing table", func() {
+		r.Register("foo.example.com", endpoint)
+		var buf bytes.Buffer
+		Expect(r.WriteSnapshot(&buf)).To(Succeed())
+		_, err := restored.RestoreSnapshot(&buf)
+		Expect(err).ToNot(HaveOccurred())
+
+		marshalled, err := json.Marshal(restored)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(marshalled)).To(ContainSubstring(`"provisional":true`))
+	})
+
+	Context("files", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "snapshot")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("saves and loads the snapshot", func() {
+			path := filepath.Join(dir, "routes.json")
+			r.Register("foo.example.com", endpoint)
+			Expect(r.SaveSnapshot(path)).To(Succeed())
+
+			n, err := restored.LoadSnapshot(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(1))
+
+			files, err := ioutil.ReadDir(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+		})
+
+		It("ignores a missing snapshot", func() {
+			n, err := restored.LoadSnapshot(filepath.Join(dir, "missing.json"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(0))
+		})
+
+		It("saves the snapshot on every tick and when the snapshotter exits", func() {
+			path := filepath.Join(dir, "routes.json")
+			clock := fakeclock.NewFakeClock(time.Now())
+			process := ifrit.Invoke(NewSnapshotter(r, path, time.Second, clock, test_util.NewTestZapLogger("test")))
+
+			r.Register("foo.example.com", endpoint)
+			clock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(func() (int, error) { return restored.LoadSnapshot(path) }).Should(Equal(1))
+
+			r.Register("bar.example.com", endpoint)
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive())
+			Expect(clock.WatcherCount()).To(BeZero())
+
+			restored = NewRouteRegistry(test_util.NewTestZapLogger("test"), configObj, new(fakes.FakeRouteRegistryReporter))
+			n, err := restored.LoadSnapshot(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(2))
+		})
+	})
+})
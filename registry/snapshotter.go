@@ -0,0 +1,57 @@
+package registry
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/uber-go/zap"
+
+	"code.cloudfoundry.org/gorouter/logger"
+)
+
+// Snapshotter saves the routing table to disk on every tick and once more
+// when it is signalled to exit.
+type Snapshotter struct {
+	registry *RouteRegistry
+	path     string
+	interval time.Duration
+	clock    clock.Clock
+	logger   logger.Logger
+}
+
+func NewSnapshotter(registry *RouteRegistry, path string, interval time.Duration, clock clock.Clock, logger logger.Logger) *Snapshotter {
+	return &Snapshotter{
+		registry: registry,
+		path:     path,
+		interval: interval,
+		clock:    clock,
+		logger:   logger,
+	}
+}
+
+func (s *Snapshotter) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ticker := s.clock.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	close(ready)
+	for {
+		select {
+		case <-ticker.C():
+			s.save()
+		case <-signals:
+			s.save()
+			s.logger.Info("exited")
+			return nil
+		}
+	}
+}
+
+func (s *Snapshotter) save() {
+	err := s.registry.SaveSnapshot(s.path)
+	if err != nil {
+		s.logger.Error("error-saving-snapshot", zap.String("path", s.path), zap.Error(err))
+		return
+	}
+	s.logger.Debug("snapshot-saved", zap.String("path", s.path))
+}
@@ -0,0 +1,83 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HeaderRules describe how the router modifies requests sent to, and
+// responses received from, the backends of a route.
+type HeaderRules struct {
+	Host     string        `json:"host,omitempty"`
+	Request  HeaderRuleSet `json:"request"`
+	Response HeaderRuleSet `json:"response"`
+}
+
+// HeaderRuleSet is applied to a set of headers by first removing, then
+// setting and finally appending the listed headers.
+type HeaderRuleSet struct {
+	Set    map[string]string `json:"set,omitempty"`
+	Append map[string]string `json:"append,omitempty"`
+	Remove []string          `json:"remove,omitempty"`
+}
+
+// Validate returns an error if the rules contain a host or header that
+// cannot be safely written to an HTTP message.
+func (r *HeaderRules) Validate() error {
+	if strings.ContainsAny(r.Host, " \t\r\n/") {
+		return fmt.Errorf("invalid host rewrite: %q", r.Host)
+	}
+
+	err := r.Request.validate()
+	if err != nil {
+		return err
+	}
+	return r.Response.validate()
+}
+
+func (s *HeaderRuleSet) Apply(header http.Header) {
+	for _, name := range s.Remove {
+		header.Del(name)
+	}
+	for name, value := range s.Set {
+		header.Set(name, value)
+	}
+	for name, value := range s.Append {
+		header.Add(name, value)
+	}
+}
+
+func (s *HeaderRuleSet) IsEmpty() bool {
+	return len(s.Set) == 0 && len(s.Append) == 0 && len(s.Remove) == 0
+}
+
+func (s *HeaderRuleSet) validate() error {
+	for _, name := range s.Remove {
+		if err := validateHeaderName(name); err != nil {
+			return err
+		}
+	}
+	for _, values := range []map[string]string{s.Set, s.Append} {
+		for name, value := range values {
+			if err := validateHeaderName(name); err != nil {
+				return err
+			}
+			if strings.ContainsAny(value, "\r\n") {
+				return fmt.Errorf("invalid value for header %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+func validateHeaderName(name string) error {
+	if name == "" {
+		return errors.New("header name must not be empty")
+	}
+	if strings.ContainsAny(name, " \t\r\n:") {
+		return fmt.Errorf("invalid header name: %q", name)
+	}
+	return nil
+}
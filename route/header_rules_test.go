@@ -0,0 +1,84 @@
+package route_test
+
+import (
+	"net/http"
+
+	"code.cloudfoundry.org/gorouter/route"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HeaderRules", func() {
+	Describe("Validate", func() {
+		It("accepts valid rules", func() {
+			rules := &route.HeaderRules{
+				Host: "internal.example.com:8080",
+				Request: route.HeaderRuleSet{
+					Set:    map[string]string{"X-Foo": "bar"},
+					Append: map[string]string{"X-Bar": "baz"},
+					Remove: []string{"X-Secret"},
+				},
+			}
+			Expect(rules.Validate()).To(Succeed())
+		})
+
+		It("rejects a host containing a path", func() {
+			rules := &route.HeaderRules{Host: "example.com/foo"}
+			Expect(rules.Validate()).ToNot(Succeed())
+		})
+
+		It("rejects empty header names", func() {
+			rules := &route.HeaderRules{Response: route.HeaderRuleSet{Remove: []string{""}}}
+			Expect(rules.Validate()).ToNot(Succeed())
+		})
+
+		It("rejects invalid header names", func() {
+			rules := &route.HeaderRules{Request: route.HeaderRuleSet{Set: map[string]string{"X-Foo:": "bar"}}}
+			Expect(rules.Validate()).ToNot(Succeed())
+		})
+
+		It("rejects header values containing line breaks", func() {
+			rules := &route.HeaderRules{Request: route.HeaderRuleSet{Append: map[string]string{"X-Foo": "bar\r\nX-Injected: true"}}}
+			Expect(rules.Validate()).ToNot(Succeed())
+		})
+	})
+
+	Describe("HeaderRuleSet", func() {
+		var header http.Header
+
+		BeforeEach(func() {
+			header = http.Header{}
+			header.Set("X-Remove-Me", "value")
+			header.Set("X-Overwrite", "old")
+			header.Set("X-Append", "first")
+		})
+
+		It("removes, sets and appends headers", func() {
+			ruleSet := route.HeaderRuleSet{
+				Remove: []string{"X-Remove-Me"},
+				Set:    map[string]string{"X-Overwrite": "new"},
+				Append: map[string]string{"X-Append": "second"},
+			}
+			ruleSet.Apply(header)
+
+			Expect(header).ToNot(HaveKey("X-Remove-Me"))
+			Expect(header["X-Overwrite"]).To(Equal([]string{"new"}))
+			Expect(header["X-Append"]).To(Equal([]string{"first", "second"}))
+		})
+
+		It("removes headers before setting them", func() {
+			ruleSet := route.HeaderRuleSet{
+				Remove: []string{"X-Overwrite"},
+				Set:    map[string]string{"X-Overwrite": "new"},
+			}
+			ruleSet.Apply(header)
+
+			Expect(header.Get("X-Overwrite")).To(Equal("new"))
+		})
+
+		It("reports whether it is empty", func() {
+			Expect((&route.HeaderRuleSet{}).IsEmpty()).To(BeTrue())
+			Expect((&route.HeaderRuleSet{Remove: []string{"X-Foo"}}).IsEmpty()).To(BeFalse())
+		})
+	})
+})
@@ -1,12 +1,16 @@
 package route
 
 import (
+	"math"
 	"math/rand"
 	"time"
 )
 
 var randomize = rand.New(rand.NewSource(time.Now().UnixNano()))
 
+// minWeight keeps the load of endpoints with a slow start weight of 0 finite
+const minWeight = 0.001
+
 type LeastConnection struct {
 	pool            *Pool
 	initialEndpoint string
@@ -41,6 +45,7 @@ func (r *LeastConnection) PreRequest(e *Endpoint) {
 
 func (r *LeastConnection) PostRequest(e *Endpoint) {
 	e.Stats.NumberConnections.Decrement()
+	r.pool.released()
 }
 
 func (r *LeastConnection) next() *Endpoint {
@@ -57,6 +62,9 @@ func (r *LeastConnection) next() *Endpoint {
 
 	// single endpoint
 	if total == 1 {
+		if e := r.pool.endpoints[0]; e.isDraining() || r.pool.atLimit(e) {
+			return nil
+		}
 		return r.pool.endpoints[0].endpoint
 	}
 
@@ -64,21 +72,42 @@ func (r *LeastConnection) next() *Endpoint {
 	// select the least connection endpoint OR
 	// random one within the least connection endpoints
 	randIndices := randomize.Perm(total)
+	now := time.Now()
+	zone := r.pool.localZone(now)
+	var selectedLoad float64
+	// the first endpoint that was skipped while warming up
+	var warming *Endpoint
 
 	for i := 0; i < total; i++ {
 		randIdx := randIndices[i]
-		cur := r.pool.endpoints[randIdx].endpoint
-
-		// our first is the least
-		if i == 0 {
-			selected = cur
+		e := r.pool.endpoints[randIdx]
+		cur := e.endpoint
+		if e.isDraining() || zone != "" && cur.Zone != zone || r.pool.atLimit(e) {
 			continue
 		}
 
-		if cur.Stats.NumberConnections.Count() < selected.Stats.NumberConnections.Count() {
+		// endpoints that are warming up are considered with a probability
+		// of their weight and get a share of the connections by their weight
+		load := float64(cur.Stats.NumberConnections.Count())
+		if w := r.pool.weight(e, now); w < 1 {
+			if r.pool.random.Float64() >= w {
+				if warming == nil {
+					warming = cur
+				}
+				continue
+			}
+			load /= math.Max(w, minWeight)
+		}
+
+		// our first is the least
+		if selected == nil || load < selectedLoad {
 			selected = cur
+			selectedLoad = load
 		}
 	}
+	if selected == nil {
+		return warming
+	}
 	return selected
 }
 
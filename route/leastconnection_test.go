@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"code.cloudfoundry.org/gorouter/config"
 	"code.cloudfoundry.org/gorouter/route"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -124,6 +125,39 @@ var _ = Describe("LeastConnection", func() {
 		})
 	})
 
+	Context("when an endpoint is warming up", func() {
+		var warm, warming *route.Endpoint
+
+		BeforeEach(func() {
+			warm = route.NewEndpoint(&route.EndpointOpts{Host: "10.0.1.0", Port: 60000})
+			warming = route.NewEndpoint(&route.EndpointOpts{Host: "10.0.1.1", Port: 60000})
+			pool.SetSlowStart(config.SlowStartConfig{Window: time.Hour, MinWeight: 0.1, Aggression: 1})
+			// restored endpoints do not warm up
+			pool.Restore(warm, time.Now())
+			pool.Put(warming)
+		})
+
+		It("selects it less often", func() {
+			iter := route.NewLeastConnection(pool, "")
+			count := 0
+			for i := 0; i < 1000; i++ {
+				if iter.Next() == warming {
+					count++
+				}
+			}
+			Expect(count).To(BeNumerically("~", 50, 40))
+		})
+
+		It("scales its connections by its weight", func() {
+			setConnectionCount([]*route.Endpoint{warm, warming}, []int{10, 2})
+
+			iter := route.NewLeastConnection(pool, "")
+			for i := 0; i < 100; i++ {
+				Expect(iter.Next()).To(Equal(warm))
+			}
+		})
+	})
+
 	Context("PreRequest", func() {
 		It("increments the NumberConnections counter", func() {
 			endpointFoo := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4"})
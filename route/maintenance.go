@@ -0,0 +1,83 @@
+package route
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	MaintenanceContentTypeHTML = "text/html"
+	MaintenanceContentTypeJSON = "application/json"
+)
+
+// Maintenance describes the static response served by the router for a route
+// (or a wildcard domain) that has been put into maintenance mode.
+type Maintenance struct {
+	Route       Uri    `json:"route"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	RetryAfter  int    `json:"retry_after,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body,omitempty"`
+}
+
+// Validate returns an error if the maintenance response can not be served.
+func (m *Maintenance) Validate() error {
+	if m.Route.String() == "" {
+		return errors.New("route is required")
+	}
+
+	if m.StatusCode != 0 && (m.StatusCode < 400 || m.StatusCode > 599) {
+		return fmt.Errorf("invalid maintenance status code: %d", m.StatusCode)
+	}
+
+	if m.RetryAfter < 0 {
+		return fmt.Errorf("invalid retry_after: %d", m.RetryAfter)
+	}
+
+	switch m.ContentType {
+	case "", MaintenanceContentTypeHTML:
+	case MaintenanceContentTypeJSON:
+		if m.Body != "" {
+			var v interface{}
+			if err := json.Unmarshal([]byte(m.Body), &v); err != nil {
+				return fmt.Errorf("body is not valid json: %s", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported content type: %s", m.ContentType)
+	}
+
+	return nil
+}
+
+// Code returns the status code of the response, defaulting to 503 Service
+// Unavailable.
+func (m *Maintenance) Code() int {
+	if m.StatusCode == 0 {
+		return http.StatusServiceUnavailable
+	}
+	return m.StatusCode
+}
+
+// ResponseContentType returns the Content-Type header of the response.
+func (m *Maintenance) ResponseContentType() string {
+	if m.ContentType == MaintenanceContentTypeJSON {
+		return "application/json"
+	}
+	return "text/html; charset=utf-8"
+}
+
+// ResponseBody returns the body of the response, falling back to a generic
+// message when none was configured.
+func (m *Maintenance) ResponseBody() string {
+	if m.Body != "" {
+		return m.Body
+	}
+
+	if m.ContentType == MaintenanceContentTypeJSON {
+		return `{"error":"maintenance_mode","description":"This route is down for maintenance."}`
+	}
+	return "<html><body><h1>" + http.StatusText(m.Code()) + "</h1><p>This route is down for maintenance.</p></body></html>\n"
+}
@@ -0,0 +1,60 @@
+package route_test
+
+import (
+	"net/http"
+
+	"code.cloudfoundry.org/gorouter/route"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Maintenance", func() {
+	Describe("Validate", func() {
+		It("requires a route", func() {
+			Expect((&route.Maintenance{}).Validate()).ToNot(Succeed())
+			Expect((&route.Maintenance{Route: "example.com"}).Validate()).To(Succeed())
+		})
+
+		It("rejects status codes that are not errors", func() {
+			Expect((&route.Maintenance{Route: "example.com", StatusCode: 200}).Validate()).ToNot(Succeed())
+			Expect((&route.Maintenance{Route: "example.com", StatusCode: 502}).Validate()).To(Succeed())
+		})
+
+		It("rejects a negative retry after", func() {
+			Expect((&route.Maintenance{Route: "example.com", RetryAfter: -1}).Validate()).ToNot(Succeed())
+		})
+
+		It("rejects unsupported content types", func() {
+			Expect((&route.Maintenance{Route: "example.com", ContentType: "text/plain"}).Validate()).ToNot(Succeed())
+		})
+
+		It("rejects json bodies that are not valid json", func() {
+			m := &route.Maintenance{Route: "example.com", ContentType: "application/json", Body: "{nope"}
+			Expect(m.Validate()).ToNot(Succeed())
+		})
+	})
+
+	Describe("Code", func() {
+		It("defaults to 503", func() {
+			Expect((&route.Maintenance{}).Code()).To(Equal(http.StatusServiceUnavailable))
+		})
+
+		It("returns the configured status code", func() {
+			Expect((&route.Maintenance{StatusCode: 502}).Code()).To(Equal(http.StatusBadGateway))
+		})
+	})
+
+	Describe("ResponseBody", func() {
+		It("returns the configured body", func() {
+			m := &route.Maintenance{Body: "<p>back soon</p>"}
+			Expect(m.ResponseBody()).To(Equal("<p>back soon</p>"))
+			Expect(m.ResponseContentType()).To(Equal("text/html; charset=utf-8"))
+		})
+
+		It("defaults to a json body for json responses", func() {
+			m := &route.Maintenance{ContentType: "application/json"}
+			Expect(m.ResponseBody()).To(ContainSubstring(`"error":"maintenance_mode"`))
+			Expect(m.ResponseContentType()).To(Equal("application/json"))
+		})
+	})
+})
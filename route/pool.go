@@ -1,10 +1,15 @@
 package route
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"net/http"
+	"reflect"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -28,6 +33,18 @@ const (
 	ADDED
 )
 
+// Sources that register endpoints again periodically; their endpoints are
+// pruned when the registrations stop.
+const (
+	SourceNATS       = "nats"
+	SourceRoutingAPI = "routing-api"
+)
+
+var (
+	ErrPendingQueueFull = errors.New("too many requests waiting for a backend")
+	ErrPendingTimeout   = errors.New("timed out waiting for a backend")
+)
+
 func NewCounter(initial int64) *Counter {
 	return &Counter{initial}
 }
@@ -73,6 +90,23 @@ type Endpoint struct {
 	useTls               bool
 	RoundTripper         ProxyRoundTripper
 	UpdatedAt            time.Time
+	HeaderRules          *HeaderRules
+	Redirect             *Redirect
+	HTTPSOnly            bool
+	// Provisional is set on endpoints restored from a snapshot until they
+	// are registered again
+	Provisional bool
+	// Source names the source that registered the endpoint. Endpoints of
+	// route sources are removed by their source and never pruned.
+	Source string
+	// Zone is the availability zone of the endpoint, taken from the tags of
+	// its registration
+	Zone string
+	// DrainTimeout is set on unregistered endpoints that should receive no
+	// new requests but stay in the pool for the timeout, so that requests
+	// in flight can complete
+	DrainTimeout time.Duration
+	RateLimit    *config.RateLimit
 }
 
 //go:generate counterfeiter -o fakes/fake_endpoint_iterator.go . EndpointIterator
@@ -88,6 +122,19 @@ type endpointElem struct {
 	index    int
 	updated  time.Time
 	failedAt *time.Time
+	// when the endpoint was added to the pool, zero for endpoints that
+	// do not need to warm up
+	added time.Time
+	// when a draining endpoint is removed, zero unless it is draining
+	drainUntil time.Time
+	// the version of the endpoint registered by each source, endpoint is
+	// the one of the source with the highest precedence
+	registrations map[string]*registration
+}
+
+type registration struct {
+	endpoint *Endpoint
+	updated  time.Time
 }
 
 type Pool struct {
@@ -102,6 +149,16 @@ type Pool struct {
 	retryAfterFailure time.Duration
 	nextIdx           int
 	overloaded        bool
+	precedence        map[string]int
+	slowStart         config.SlowStartConfig
+	zone              string
+	zoneAware         config.ZoneAwareRoutingConfig
+	limits            config.ConnectionLimits
+
+	// requests waiting for a backend below the connection limit, in the
+	// order they arrived; each connection that is released wakes up one
+	pending int32
+	waiters []chan struct{}
 
 	random *rand.Rand
 }
@@ -120,6 +177,12 @@ type EndpointOpts struct {
 	IsolationSegment        string
 	UseTLS                  bool
 	UpdatedAt               time.Time
+	HeaderRules             *HeaderRules
+	Redirect                *Redirect
+	HTTPSOnly               bool
+	Source                  string
+	DrainTimeout            time.Duration
+	RateLimit               *config.RateLimit
 }
 
 func NewEndpoint(opts *EndpointOpts) *Endpoint {
@@ -137,6 +200,12 @@ func NewEndpoint(opts *EndpointOpts) *Endpoint {
 		Stats:                NewStats(),
 		IsolationSegment:     opts.IsolationSegment,
 		UpdatedAt:            opts.UpdatedAt,
+		HeaderRules:          opts.HeaderRules,
+		Redirect:             opts.Redirect,
+		HTTPSOnly:            opts.HTTPSOnly,
+		Source:               opts.Source,
+		DrainTimeout:         opts.DrainTimeout,
+		RateLimit:            opts.RateLimit,
 	}
 }
 
@@ -144,6 +213,26 @@ func (e *Endpoint) IsTLS() bool {
 	return e.useTls
 }
 
+// Equivalent reports whether the endpoints were registered with the same
+// properties, ignoring the modification tag and the time of registration.
+func (e *Endpoint) Equivalent(other *Endpoint) bool {
+	return e.addr == other.addr &&
+		e.useTls == other.useTls &&
+		e.ApplicationId == other.ApplicationId &&
+		e.ServerCertDomainSAN == other.ServerCertDomainSAN &&
+		e.PrivateInstanceId == other.PrivateInstanceId &&
+		e.PrivateInstanceIndex == other.PrivateInstanceIndex &&
+		e.StaleThreshold == other.StaleThreshold &&
+		e.RouteServiceUrl == other.RouteServiceUrl &&
+		e.IsolationSegment == other.IsolationSegment &&
+		e.HTTPSOnly == other.HTTPSOnly &&
+		e.Source == other.Source &&
+		reflect.DeepEqual(e.Tags, other.Tags) &&
+		reflect.DeepEqual(e.HeaderRules, other.HeaderRules) &&
+		reflect.DeepEqual(e.Redirect, other.Redirect) &&
+		reflect.DeepEqual(e.RateLimit, other.RateLimit)
+}
+
 func NewPool(retryAfterFailure time.Duration, host, contextPath string) *Pool {
 	return &Pool{
 		endpoints:         make([]*endpointElem, 0, 1),
@@ -156,6 +245,42 @@ func NewPool(retryAfterFailure time.Duration, host, contextPath string) *Pool {
 	}
 }
 
+// SetSourcePrecedence decides which version of an endpoint registered by
+// several sources is routed to: the one of the source with the highest
+// precedence, or the latest one among sources of the same precedence.
+func (p *Pool) SetSourcePrecedence(precedence map[string]int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.precedence = precedence
+}
+
+// SetSlowStart makes endpoints warm up after they are added to the pool: they
+// receive a growing share of traffic during the slow start window.
+func (p *Pool) SetSlowStart(slowStart config.SlowStartConfig) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.slowStart = slowStart
+}
+
+// SetZone makes iterators prefer the endpoints in the zone, as long as the
+// zone has enough healthy endpoints that are not overloaded.
+func (p *Pool) SetZone(zone string, zoneAware config.ZoneAwareRoutingConfig) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.zone = zone
+	p.zoneAware = zoneAware
+}
+
+// SetConnectionLimits caps the requests in flight to every endpoint. Iterators
+// skip endpoints at the limit and AwaitCapacity queues requests until one of
+// them finishes a request.
+func (p *Pool) SetConnectionLimits(limits config.ConnectionLimits) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.limits = limits
+	p.wake(p.freeConns())
+}
+
 func PoolsMatch(p1, p2 *Pool) bool {
 	return p1.Host() == p2.Host() && p1.ContextPath() == p2.ContextPath()
 }
@@ -168,51 +293,117 @@ func (p *Pool) ContextPath() string {
 	return p.contextPath
 }
 
-// Returns true if endpoint was added or updated, false otherwise
+// Put registers the endpoint for its source. It returns UNMODIFIED when the
+// endpoint is not routed to, because it is older than the registration of the
+// same source or because another source with a higher precedence registered
+// the same address.
 func (p *Pool) Put(endpoint *Endpoint) PoolPutResult {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	var result PoolPutResult
+	now := time.Now()
 	e, found := p.index[endpoint.CanonicalAddr()]
-	if found {
-		result = UPDATED
-		if e.endpoint != endpoint {
-			e.endpoint.Lock()
-			defer e.endpoint.Unlock()
-
-			if !e.endpoint.ModificationTag.SucceededBy(&endpoint.ModificationTag) {
-				return UNMODIFIED
-			}
-
-			oldEndpoint := e.endpoint
-			e.endpoint = endpoint
-
-			if oldEndpoint.PrivateInstanceId != endpoint.PrivateInstanceId {
-				delete(p.index, oldEndpoint.PrivateInstanceId)
-				p.index[endpoint.PrivateInstanceId] = e
-			}
-
-			if oldEndpoint.ServerCertDomainSAN == endpoint.ServerCertDomainSAN {
-				endpoint.RoundTripper = oldEndpoint.RoundTripper
-			}
-		}
-	} else {
-		result = ADDED
+	if !found {
 		e = &endpointElem{
-			endpoint: endpoint,
-			index:    len(p.endpoints),
+			endpoint:      endpoint,
+			index:         len(p.endpoints),
+			updated:       now,
+			added:         now,
+			registrations: map[string]*registration{endpoint.Source: {endpoint: endpoint, updated: now}},
 		}
 
 		p.endpoints = append(p.endpoints, e)
 
 		p.index[endpoint.CanonicalAddr()] = e
 		p.index[endpoint.PrivateInstanceId] = e
+		p.wake(p.freeConns())
+		return ADDED
+	}
+
+	provisional := e.endpoint.Provisional
+	if provisional {
+		e.registrations = make(map[string]*registration)
+	}
+
+	r, registered := e.registrations[endpoint.Source]
+	if registered && r.endpoint != endpoint && !r.endpoint.ModificationTag.SucceededBy(&endpoint.ModificationTag) {
+		return UNMODIFIED
+	}
+
+	e.registrations[endpoint.Source] = &registration{endpoint: endpoint, updated: now}
+	e.updated = now
+	e.drainUntil = time.Time{}
+
+	if !provisional && p.precedence[endpoint.Source] < p.precedence[e.endpoint.Source] {
+		return UNMODIFIED
+	}
+	p.setEndpoint(e, endpoint)
+	return UPDATED
+}
+
+func (p *Pool) setEndpoint(e *endpointElem, endpoint *Endpoint) {
+	oldEndpoint := e.endpoint
+	if oldEndpoint == endpoint {
+		return
+	}
+
+	oldEndpoint.Lock()
+	defer oldEndpoint.Unlock()
+
+	e.endpoint = endpoint
+
+	if oldEndpoint.PrivateInstanceId != endpoint.PrivateInstanceId {
+		delete(p.index, oldEndpoint.PrivateInstanceId)
+		p.index[endpoint.PrivateInstanceId] = e
+	}
+
+	if oldEndpoint.ServerCertDomainSAN == endpoint.ServerCertDomainSAN {
+		endpoint.RoundTripper = oldEndpoint.RoundTripper
+	}
+}
+
+// preferred returns the version of the endpoint of the source with the
+// highest precedence, the latest one among sources of the same precedence.
+func (p *Pool) preferred(e *endpointElem) *Endpoint {
+	best := e.registrations[e.endpoint.Source]
+	if best != nil && best.endpoint != e.endpoint {
+		best = nil
+	}
+	for _, r := range e.registrations {
+		if best == nil {
+			best = r
+			continue
+		}
+		rp, bp := p.precedence[r.endpoint.Source], p.precedence[best.endpoint.Source]
+		if rp > bp || rp == bp && r.updated.After(best.updated) {
+			best = r
+		}
 	}
+	return best.endpoint
+}
+
+// Restore adds an endpoint loaded from a snapshot, keeping the time it was
+// last updated so that it is pruned unless it is registered again. An
+// endpoint that is already in the pool is left alone.
+func (p *Pool) Restore(endpoint *Endpoint, updated time.Time) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
 
-	e.updated = time.Now()
+	if _, found := p.index[endpoint.CanonicalAddr()]; found {
+		return false
+	}
+
+	e := &endpointElem{
+		endpoint:      endpoint,
+		index:         len(p.endpoints),
+		updated:       updated,
+		registrations: map[string]*registration{endpoint.Source: {endpoint: endpoint, updated: updated}},
+	}
+	p.endpoints = append(p.endpoints, e)
+	p.index[endpoint.CanonicalAddr()] = e
+	p.index[endpoint.PrivateInstanceId] = e
 
-	return result
+	return true
 }
 
 func (p *Pool) RouteServiceUrl() string {
@@ -227,15 +418,159 @@ func (p *Pool) RouteServiceUrl() string {
 	}
 }
 
-func (p *Pool) FilteredPool(maxConnsPerBackend int64) *Pool {
-	filteredPool := NewPool(p.retryAfterFailure, p.Host(), p.ContextPath())
-	p.Each(func(endpoint *Endpoint) {
-		if endpoint.Stats.NumberConnections.Count() < maxConnsPerBackend {
-			filteredPool.Put(endpoint)
+func (p *Pool) HeaderRules() *HeaderRules {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.endpoints) > 0 {
+		return p.endpoints[0].endpoint.HeaderRules
+	}
+	return nil
+}
+
+func (p *Pool) Redirect() *Redirect {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.endpoints) > 0 {
+		return p.endpoints[0].endpoint.Redirect
+	}
+	return nil
+}
+
+func (p *Pool) RateLimit() *config.RateLimit {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.endpoints) > 0 {
+		return p.endpoints[0].endpoint.RateLimit
+	}
+	return nil
+}
+
+func (p *Pool) HTTPSOnly() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.endpoints) > 0 {
+		return p.endpoints[0].endpoint.HTTPSOnly
+	}
+	return false
+}
+
+// AwaitCapacity returns once an endpoint of the pool is below the connection
+// limit. While all endpoints are at the limit, or other requests are waiting,
+// it waits up to the pending timeout for one of them to finish a request,
+// unless the maximum number of requests are waiting already. Waiting requests
+// are let through one per finished request, in the order they arrived.
+func (p *Pool) AwaitCapacity(ctx context.Context) error {
+	p.lock.Lock()
+	if len(p.waiters) == 0 && p.hasCapacity() {
+		p.lock.Unlock()
+		return nil
+	}
+	if int(atomic.LoadInt32(&p.pending)) >= p.limits.MaxPendingRequests {
+		p.lock.Unlock()
+		return ErrPendingQueueFull
+	}
+	atomic.AddInt32(&p.pending, 1)
+	defer atomic.AddInt32(&p.pending, -1)
+	timeout := time.NewTimer(p.limits.PendingTimeout)
+	defer timeout.Stop()
+
+	wait := make(chan struct{})
+	p.waiters = append(p.waiters, wait)
+	for {
+		p.lock.Unlock()
+
+		var err error
+		select {
+		case <-wait:
+		case <-timeout.C:
+			err = ErrPendingTimeout
+		case <-ctx.Done():
+			err = ctx.Err()
 		}
-	})
 
-	return filteredPool
+		p.lock.Lock()
+		if err != nil {
+			p.stopWaiting(wait)
+			p.lock.Unlock()
+			return err
+		}
+		if p.hasCapacity() {
+			p.lock.Unlock()
+			return nil
+		}
+
+		// another request took the connection, wait at the front of the queue
+		wait = make(chan struct{})
+		p.waiters = append([]chan struct{}{wait}, p.waiters...)
+	}
+}
+
+// stopWaiting removes a request that gives up from the queue. When it has
+// been woken up already, the next request is woken up instead.
+func (p *Pool) stopWaiting(wait chan struct{}) {
+	for i, w := range p.waiters {
+		if w == wait {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			return
+		}
+	}
+	p.wake(1)
+}
+
+// hasCapacity reports whether an endpoint that is not draining is below the
+// connection limit
+func (p *Pool) hasCapacity() bool {
+	if p.limits.MaxConns <= 0 {
+		return true
+	}
+	for _, e := range p.endpoints {
+		if !e.isDraining() && !p.atLimit(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// freeConns returns the number of requests the endpoints that are not
+// draining can take before they reach the connection limit
+func (p *Pool) freeConns() int {
+	if p.limits.MaxConns <= 0 {
+		return len(p.waiters)
+	}
+	free := 0
+	for _, e := range p.endpoints {
+		if n := p.limits.MaxConns - e.endpoint.Stats.NumberConnections.Count(); n > 0 && !e.isDraining() {
+			free += int(n)
+		}
+	}
+	return free
+}
+
+func (p *Pool) atLimit(e *endpointElem) bool {
+	return p.limits.MaxConns > 0 && e.endpoint.Stats.NumberConnections.Count() >= p.limits.MaxConns
+}
+
+// released is called when an endpoint finishes a request and wakes up the
+// first request waiting for capacity
+func (p *Pool) released() {
+	if atomic.LoadInt32(&p.pending) == 0 {
+		return
+	}
+	p.lock.Lock()
+	p.wake(1)
+	p.lock.Unlock()
+}
+
+// wake lets up to n waiting requests through, in the order they arrived
+func (p *Pool) wake(n int) {
+	for ; n > 0 && len(p.waiters) > 0; n-- {
+		close(p.waiters[0])
+		p.waiters = p.waiters[1:]
+	}
 }
 
 func (p *Pool) PruneEndpoints() []*Endpoint {
@@ -249,18 +584,12 @@ func (p *Pool) PruneEndpoints() []*Endpoint {
 	for i := 0; i < last; {
 		e := p.endpoints[i]
 
-		if e.endpoint.useTls {
-			i++
-			continue
-		}
-
-		staleTime := now.Add(-e.endpoint.StaleThreshold)
-
-		if e.updated.Before(staleTime) {
+		if e.isStale(now) {
 			p.removeEndpoint(e)
 			prunedEndpoints = append(prunedEndpoints, e.endpoint)
 			last--
 		} else {
+			p.pruneRegistrations(e, now)
 			i++
 		}
 	}
@@ -269,22 +598,113 @@ func (p *Pool) PruneEndpoints() []*Endpoint {
 	return prunedEndpoints
 }
 
-// Returns true if the endpoint was removed from the Pool, false otherwise.
+// StaleCount returns the number of endpoints that PruneEndpoints would remove
+// and the number of endpoints in the pool.
+func (p *Pool) StaleCount() (int, int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	stale := 0
+	for _, e := range p.endpoints {
+		if e.isStale(now) {
+			stale++
+		}
+	}
+	return stale, len(p.endpoints)
+}
+
+// pruneRegistrations drops the stale registrations of an endpoint that is
+// still registered by another source.
+func (p *Pool) pruneRegistrations(e *endpointElem, now time.Time) {
+	pruned := false
+	for source, r := range e.registrations {
+		if r.isStale(now) {
+			delete(e.registrations, source)
+			pruned = true
+		}
+	}
+	if pruned {
+		p.setEndpoint(e, p.preferred(e))
+	}
+}
+
+// Remove unregisters the endpoint for its source. It returns true if the
+// endpoint was removed from the Pool, false otherwise, which includes
+// endpoints that are still registered by other sources.
 func (p *Pool) Remove(endpoint *Endpoint) bool {
-	var e *endpointElem
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	e := p.unregister(endpoint, true)
+	if e == nil {
+		return false
+	}
+
+	p.removeEndpoint(e)
+	return true
+}
 
+// Drain unregisters the endpoint for its source like Remove, but when it is
+// the last registration the endpoint stays in the pool until it is removed
+// with RemoveDrained, without being routed to. It returns true if the
+// endpoint started draining.
+func (p *Pool) Drain(endpoint *Endpoint, until time.Time) bool {
 	p.lock.Lock()
 	defer p.lock.Unlock()
-	l := len(p.endpoints)
-	if l > 0 {
-		e = p.index[endpoint.CanonicalAddr()]
-		if e != nil && e.endpoint.modificationTagSameOrNewer(endpoint) {
-			p.removeEndpoint(e)
-			return true
+
+	e := p.unregister(endpoint, false)
+	if e == nil {
+		return false
+	}
+
+	e.drainUntil = until
+	return true
+}
+
+// RemoveDrained removes the endpoint if it has been draining until now. It
+// returns false for endpoints that have been registered again meanwhile.
+func (p *Pool) RemoveDrained(endpoint *Endpoint) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	e := p.index[endpoint.CanonicalAddr()]
+	if e == nil || !e.isDraining() || time.Now().Before(e.drainUntil) {
+		return false
+	}
+
+	p.removeEndpoint(e)
+	return true
+}
+
+// unregister removes the registration of the source of the endpoint. It
+// returns the endpoint when no registrations remain; the last registration is
+// only removed when last is true.
+func (p *Pool) unregister(endpoint *Endpoint, last bool) *endpointElem {
+	e := p.index[endpoint.CanonicalAddr()]
+	if e == nil {
+		return nil
+	}
+
+	source := endpoint.Source
+	if e.endpoint.Provisional {
+		source = e.endpoint.Source
+	}
+	r, registered := e.registrations[source]
+	if !registered || !r.endpoint.modificationTagSameOrNewer(endpoint) {
+		return nil
+	}
+
+	if len(e.registrations) == 1 {
+		if last {
+			delete(e.registrations, source)
 		}
+		return e
 	}
 
-	return false
+	delete(e.registrations, source)
+	p.setEndpoint(e, p.preferred(e))
+	return nil
 }
 
 func (p *Pool) removeEndpoint(e *endpointElem) {
@@ -311,11 +731,27 @@ func (p *Pool) Endpoints(defaultLoadBalance, initial string) EndpointIterator {
 	}
 }
 
+// FindByAddr returns the endpoint with the address, nil if there is none.
+// Draining endpoints are returned as well.
+func (p *Pool) FindByAddr(addr string) *Endpoint {
+	var endpoint *Endpoint
+	p.lock.Lock()
+	e := p.index[addr]
+	if e != nil {
+		endpoint = e.endpoint
+	}
+	p.lock.Unlock()
+
+	return endpoint
+}
+
+// findById returns the endpoint with the address or instance id unless it
+// is draining or at the connection limit
 func (p *Pool) findById(id string) *Endpoint {
 	var endpoint *Endpoint
 	p.lock.Lock()
 	e := p.index[id]
-	if e != nil {
+	if e != nil && !e.isDraining() && !p.atLimit(e) {
 		endpoint = e.endpoint
 	}
 	p.lock.Unlock()
@@ -323,6 +759,20 @@ func (p *Pool) findById(id string) *Endpoint {
 	return endpoint
 }
 
+// NumDraining returns the number of endpoints that are draining
+func (p *Pool) NumDraining() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	n := 0
+	for _, e := range p.endpoints {
+		if e.isDraining() {
+			n++
+		}
+	}
+	return n
+}
+
 func (p *Pool) IsEmpty() bool {
 	p.lock.Lock()
 	l := len(p.endpoints)
@@ -335,6 +785,9 @@ func (p *Pool) MarkUpdated(t time.Time) {
 	p.lock.Lock()
 	for _, e := range p.endpoints {
 		e.updated = t
+		for _, r := range e.registrations {
+			r.updated = t
+		}
 	}
 	p.lock.Unlock()
 }
@@ -368,15 +821,130 @@ func (p *Pool) Each(f func(endpoint *Endpoint)) {
 	p.lock.Unlock()
 }
 
-func (p *Pool) MarshalJSON() ([]byte, error) {
+// EachUpdated calls f with every endpoint and the time it was last updated
+func (p *Pool) EachUpdated(f func(endpoint *Endpoint, updated time.Time)) {
 	p.lock.Lock()
-	endpoints := make([]*Endpoint, 0, len(p.endpoints))
 	for _, e := range p.endpoints {
-		endpoints = append(endpoints, e.endpoint)
+		f(e.endpoint, e.updated)
 	}
 	p.lock.Unlock()
+}
+
+// localZone returns the zone iterators are restricted to, or "" when
+// endpoints of all zones are routed to because there is no zone, the zone has
+// too few healthy endpoints or their load is too high. Endpoints at the
+// connection limit are not healthy.
+func (p *Pool) localZone(now time.Time) string {
+	if p.zone == "" {
+		return ""
+	}
+
+	healthy := 0
+	var load int64
+	for _, e := range p.endpoints {
+		if e.endpoint.Zone != p.zone || e.isDraining() || p.atLimit(e) {
+			continue
+		}
+		if e.failedAt != nil && now.Sub(*e.failedAt) <= p.retryAfterFailure {
+			continue
+		}
+		healthy++
+		load += e.endpoint.Stats.NumberConnections.Count()
+	}
+
+	if healthy == 0 || healthy < p.zoneAware.MinHealthyEndpoints {
+		return ""
+	}
+	if max := p.zoneAware.MaxLoadPerEndpoint; max > 0 && load >= max*int64(healthy) {
+		return ""
+	}
+	return p.zone
+}
+
+// weight returns the share of traffic of an endpoint relative to endpoints
+// that have warmed up, which grows from the minimum weight to 1 during the
+// slow start window after the endpoint was added.
+func (p *Pool) weight(e *endpointElem, now time.Time) float64 {
+	elapsed := now.Sub(e.added)
+	if p.slowStart.Window <= 0 || e.added.IsZero() || elapsed >= p.slowStart.Window {
+		return 1
+	}
+
+	w := math.Pow(float64(elapsed)/float64(p.slowStart.Window), 1/p.slowStart.Aggression)
+	if w < p.slowStart.MinWeight {
+		return p.slowStart.MinWeight
+	}
+	return w
+}
 
-	return json.Marshal(endpoints)
+// EndpointView is an endpoint as the routing table reports it, with the
+// sources that registered it in the pool, the slow start weight of an
+// endpoint that is warming up, and whether it is draining.
+type EndpointView struct {
+	*Endpoint
+	Sources         []string
+	SlowStartWeight *float64
+	Draining        bool
+}
+
+func (v EndpointView) MarshalJSON() ([]byte, error) {
+	return v.Endpoint.marshalJSON(v.Sources, v.SlowStartWeight, v.Draining)
+}
+
+// EndpointViews returns the endpoints of the pool with their state in the pool
+func (p *Pool) EndpointViews() []EndpointView {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	views := make([]EndpointView, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		v := EndpointView{Endpoint: e.endpoint, Sources: e.sources(), Draining: e.isDraining()}
+		if w := p.weight(e, now); w < 1 {
+			w = math.Floor(w*100) / 100
+			v.SlowStartWeight = &w
+		}
+		views = append(views, v)
+	}
+	return views
+}
+
+func (p *Pool) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.EndpointViews())
+}
+
+// isStale reports whether all registrations of the endpoint are stale
+func (e *endpointElem) isStale(now time.Time) bool {
+	for _, r := range e.registrations {
+		if !r.isStale(now) {
+			return false
+		}
+	}
+	return true
+}
+
+// sources returns the names of the sources that registered the endpoint
+func (e *endpointElem) sources() []string {
+	var sources []string
+	for source := range e.registrations {
+		if source != "" {
+			sources = append(sources, source)
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+func (r *registration) isStale(now time.Time) bool {
+	e := r.endpoint
+	if (e.useTls || !e.heartbeats()) && !e.Provisional {
+		return false
+	}
+	return r.updated.Before(now.Add(-e.StaleThreshold))
+}
+
+func (e *endpointElem) isDraining() bool {
+	return !e.drainUntil.IsZero()
 }
 
 func (e *endpointElem) failed() {
@@ -385,6 +953,10 @@ func (e *endpointElem) failed() {
 }
 
 func (e *Endpoint) MarshalJSON() ([]byte, error) {
+	return e.marshalJSON(nil, nil, false)
+}
+
+func (e *Endpoint) marshalJSON(sources []string, slowStartWeight *float64, draining bool) ([]byte, error) {
 	var jsonObj struct {
 		Address             string            `json:"address"`
 		TLS                 bool              `json:"tls"`
@@ -394,6 +966,16 @@ func (e *Endpoint) MarshalJSON() ([]byte, error) {
 		IsolationSegment    string            `json:"isolation_segment,omitempty"`
 		PrivateInstanceId   string            `json:"private_instance_id,omitempty"`
 		ServerCertDomainSAN string            `json:"server_cert_domain_san,omitempty"`
+		HeaderRules         *HeaderRules      `json:"header_rules,omitempty"`
+		Redirect            *Redirect         `json:"redirect,omitempty"`
+		HTTPSOnly           bool              `json:"https_only,omitempty"`
+		Provisional         bool              `json:"provisional,omitempty"`
+		Source              string            `json:"source,omitempty"`
+		Sources             []string          `json:"sources,omitempty"`
+		SlowStartWeight     *float64          `json:"slow_start_weight,omitempty"`
+		Draining            bool              `json:"draining,omitempty"`
+		Zone                string            `json:"zone,omitempty"`
+		RateLimit           *config.RateLimit `json:"rate_limit,omitempty"`
 	}
 
 	jsonObj.Address = e.addr
@@ -404,9 +986,25 @@ func (e *Endpoint) MarshalJSON() ([]byte, error) {
 	jsonObj.IsolationSegment = e.IsolationSegment
 	jsonObj.PrivateInstanceId = e.PrivateInstanceId
 	jsonObj.ServerCertDomainSAN = e.ServerCertDomainSAN
+	jsonObj.HeaderRules = e.HeaderRules
+	jsonObj.Redirect = e.Redirect
+	jsonObj.HTTPSOnly = e.HTTPSOnly
+	jsonObj.Provisional = e.Provisional
+	jsonObj.Source = e.Source
+	jsonObj.Sources = sources
+	jsonObj.SlowStartWeight = slowStartWeight
+	jsonObj.Draining = draining
+	jsonObj.Zone = e.Zone
+	jsonObj.RateLimit = e.RateLimit
 	return json.Marshal(jsonObj)
 }
 
+// heartbeats reports whether the source of the endpoint registers it again
+// periodically, rather than removing it when it goes away.
+func (e *Endpoint) heartbeats() bool {
+	return e.Source == "" || e.Source == SourceNATS || e.Source == SourceRoutingAPI
+}
+
 func (e *Endpoint) CanonicalAddr() string {
 	return e.addr
 }
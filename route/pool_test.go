@@ -1,6 +1,8 @@
 package route_test
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 
 	"net"
 
+	"code.cloudfoundry.org/gorouter/config"
 	"code.cloudfoundry.org/gorouter/route"
 	"code.cloudfoundry.org/routing-api/models"
 	. "github.com/onsi/ginkgo"
@@ -187,6 +190,66 @@ var _ = Describe("Pool", func() {
 		})
 	})
 
+	Context("HeaderRules", func() {
+		It("returns the header rules associated with the pool", func() {
+			rules := &route.HeaderRules{Host: "internal.example.com"}
+			endpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, HeaderRules: rules})
+			pool.Put(endpoint)
+
+			Expect(pool.HeaderRules()).To(Equal(rules))
+		})
+
+		Context("when there are no endpoints in the pool", func() {
+			It("returns nil", func() {
+				Expect(pool.HeaderRules()).To(BeNil())
+			})
+		})
+	})
+
+	Context("Redirect", func() {
+		It("returns the redirect associated with the pool", func() {
+			rd := &route.Redirect{URL: "https://new.example.com"}
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Redirect: rd}))
+
+			Expect(pool.Redirect()).To(Equal(rd))
+		})
+
+		Context("when there are no endpoints in the pool", func() {
+			It("returns nil", func() {
+				Expect(pool.Redirect()).To(BeNil())
+			})
+		})
+	})
+
+	Context("RateLimit", func() {
+		It("returns the rate limit associated with the pool", func() {
+			rl := &config.RateLimit{RequestsPerSecond: 10}
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{RateLimit: rl}))
+
+			Expect(pool.RateLimit()).To(Equal(rl))
+		})
+
+		Context("when there are no endpoints in the pool", func() {
+			It("returns nil", func() {
+				Expect(pool.RateLimit()).To(BeNil())
+			})
+		})
+	})
+
+	Context("HTTPSOnly", func() {
+		It("returns whether the pool only accepts https", func() {
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, HTTPSOnly: true}))
+
+			Expect(pool.HTTPSOnly()).To(BeTrue())
+		})
+
+		Context("when there are no endpoints in the pool", func() {
+			It("returns false", func() {
+				Expect(pool.HTTPSOnly()).To(BeFalse())
+			})
+		})
+	})
+
 	Context("EndpointFailed", func() {
 		It("prunes tls routes on hostname mismatch errors", func() {
 			endpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, UseTLS: true})
@@ -286,42 +349,125 @@ var _ = Describe("Pool", func() {
 			})
 		})
 
-		Context("Filtered pool", func() {
-			It("returns copy of the pool with non overloaded endpoints", func() {
-				Expect(pool.IsEmpty()).To(BeTrue())
-				endpoint1 := route.NewEndpoint(&route.EndpointOpts{Port: 5678})
-				endpoint1.Stats.NumberConnections.Increment()
-				endpoint1.Stats.NumberConnections.Increment()
+		Context("Connection limits", func() {
+			var endpoint1, endpoint2 *route.Endpoint
+
+			BeforeEach(func() {
+				pool.SetConnectionLimits(config.ConnectionLimits{MaxConns: 1})
+				endpoint1 = route.NewEndpoint(&route.EndpointOpts{Port: 5678, PrivateInstanceId: "instance-1"})
+				endpoint2 = route.NewEndpoint(&route.EndpointOpts{Port: 5679})
+				pool.Put(endpoint1)
+				pool.Put(endpoint2)
 				endpoint1.Stats.NumberConnections.Increment()
+			})
 
-				Expect(pool.Put(endpoint1)).To(Equal(route.ADDED))
+			It("does not route to endpoints at the limit", func() {
+				for _, lb := range []string{config.LOAD_BALANCE_RR, config.LOAD_BALANCE_LC} {
+					for i := 0; i < 5; i++ {
+						Expect(pool.Endpoints(lb, "").Next()).To(Equal(endpoint2))
+					}
+				}
+				Expect(pool.Endpoints("", "instance-1").Next()).To(Equal(endpoint2))
+			})
+
+			It("returns no endpoint when all are at the limit", func() {
+				endpoint2.Stats.NumberConnections.Increment()
+
+				Expect(pool.Endpoints(config.LOAD_BALANCE_RR, "").Next()).To(BeNil())
+				Expect(pool.Endpoints(config.LOAD_BALANCE_LC, "").Next()).To(BeNil())
+			})
+
+			It("has capacity while an endpoint is below the limit", func() {
+				Expect(pool.AwaitCapacity(context.Background())).To(Succeed())
+			})
+
+			Context("when all endpoints are at the limit", func() {
+				BeforeEach(func() {
+					endpoint2.Stats.NumberConnections.Increment()
+				})
+
+				It("rejects requests when they cannot queue", func() {
+					Expect(pool.AwaitCapacity(context.Background())).To(Equal(route.ErrPendingQueueFull))
+				})
+
+				It("rejects requests when the queue is full", func() {
+					pool.SetConnectionLimits(config.ConnectionLimits{MaxConns: 1, MaxPendingRequests: 1, PendingTimeout: time.Minute})
+					ctx, cancel := context.WithCancel(context.Background())
+					errs := make(chan error, 2)
+					for i := 0; i < 2; i++ {
+						go func() { errs <- pool.AwaitCapacity(ctx) }()
+					}
+
+					Eventually(errs).Should(Receive(Equal(route.ErrPendingQueueFull)))
+					cancel()
+					Eventually(errs).Should(Receive(Equal(context.Canceled)))
+				})
+
+				It("times out queued requests", func() {
+					pool.SetConnectionLimits(config.ConnectionLimits{MaxConns: 1, MaxPendingRequests: 1, PendingTimeout: 10 * time.Millisecond})
 
-				endpoint2 := route.NewEndpoint(&route.EndpointOpts{Port: 5679})
+					Expect(pool.AwaitCapacity(context.Background())).To(Equal(route.ErrPendingTimeout))
+				})
+
+				It("returns when the request is cancelled", func() {
+					pool.SetConnectionLimits(config.ConnectionLimits{MaxConns: 1, MaxPendingRequests: 1, PendingTimeout: time.Minute})
+					ctx, cancel := context.WithCancel(context.Background())
+					cancel()
 
-				Expect(pool.Put(endpoint2)).To(Equal(route.ADDED))
-				// verify the pool before filter has 2 endpoints
-				var len int
-				pool.Each(func(endpoint *route.Endpoint) {
-					len++
+					Expect(pool.AwaitCapacity(ctx)).To(Equal(context.Canceled))
 				})
-				Expect(len).To(Equal(2))
 
-				newPool := pool.FilteredPool(1)
-				Expect(newPool).NotTo(BeNil())
+				It("lets a queued request through when an endpoint finishes a request", func() {
+					pool.SetConnectionLimits(config.ConnectionLimits{MaxConns: 1, MaxPendingRequests: 2, PendingTimeout: time.Minute})
+					errs := make(chan error, 2)
+					for i := 0; i < 2; i++ {
+						go func() { errs <- pool.AwaitCapacity(context.Background()) }()
+					}
+					Consistently(errs, 50*time.Millisecond).ShouldNot(Receive())
+
+					pool.Endpoints("", "").PostRequest(endpoint2)
+					Eventually(errs).Should(Receive(BeNil()))
+					Consistently(errs, 50*time.Millisecond).ShouldNot(Receive())
+				})
 
-				// verify the original pool has both endpoints
-				len = 0
-				pool.Each(func(endpoint *route.Endpoint) {
-					len++
+				It("lets one queued request through per finished request", func() {
+					pool.SetConnectionLimits(config.ConnectionLimits{MaxConns: 1, MaxPendingRequests: 3, PendingTimeout: time.Minute})
+					errs := make(chan error, 3)
+					for i := 0; i < 3; i++ {
+						go func() { errs <- pool.AwaitCapacity(context.Background()) }()
+					}
+					Consistently(errs, 50*time.Millisecond).ShouldNot(Receive())
+
+					for i := 0; i < 3; i++ {
+						iter := pool.Endpoints("", "")
+						iter.PostRequest(endpoint2)
+						Eventually(errs).Should(Receive(BeNil()))
+						Consistently(errs, 20*time.Millisecond).ShouldNot(Receive())
+						iter.PreRequest(endpoint2)
+					}
 				})
-				Expect(len).To(Equal(2))
 
-				// verify newpool has an endpoint
-				newPoolLen := 0
-				newPool.Each(func(endpoint *route.Endpoint) {
-					newPoolLen++
+				It("queues requests behind the waiting requests", func() {
+					pool.SetConnectionLimits(config.ConnectionLimits{MaxConns: 1, MaxPendingRequests: 2, PendingTimeout: time.Minute})
+					first := make(chan error, 1)
+					go func() { first <- pool.AwaitCapacity(context.Background()) }()
+					Consistently(first, 50*time.Millisecond).ShouldNot(Receive())
+
+					second := make(chan error, 1)
+					pool.Endpoints("", "").PostRequest(endpoint2)
+					go func() { second <- pool.AwaitCapacity(context.Background()) }()
+					Eventually(first).Should(Receive(BeNil()))
+				})
+
+				It("lets queued requests through when an endpoint is added", func() {
+					pool.SetConnectionLimits(config.ConnectionLimits{MaxConns: 1, MaxPendingRequests: 1, PendingTimeout: time.Minute})
+					errs := make(chan error, 1)
+					go func() { errs <- pool.AwaitCapacity(context.Background()) }()
+					Consistently(errs, 50*time.Millisecond).ShouldNot(Receive())
+
+					pool.Put(route.NewEndpoint(&route.EndpointOpts{Port: 5680}))
+					Eventually(errs).Should(Receive(BeNil()))
 				})
-				Expect(newPoolLen).To(Equal(1))
 			})
 		})
 	})
@@ -364,6 +510,17 @@ var _ = Describe("Pool", func() {
 			})
 		})
 
+		Context("when the pool contains endpoints of a route source", func() {
+			It("does not prune them", func() {
+				e1 := route.NewEndpoint(&route.EndpointOpts{StaleThresholdInSeconds: 20, Source: "file"})
+				pool.Put(e1)
+				pool.MarkUpdated(time.Now().Add(-25 * time.Second))
+
+				Expect(pool.PruneEndpoints()).To(BeEmpty())
+				Expect(pool.IsEmpty()).To(BeFalse())
+			})
+		})
+
 		Context("when an endpoint has passed the stale threshold", func() {
 			It("prunes the endpoint", func() {
 				e1 := route.NewEndpoint(&route.EndpointOpts{UseTLS: false, StaleThresholdInSeconds: 20})
@@ -457,6 +614,309 @@ var _ = Describe("Pool", func() {
 		})
 	})
 
+	Context("Sources", func() {
+		var natsEndpoint, apiEndpoint *route.Endpoint
+
+		BeforeEach(func() {
+			natsEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, AppId: "nats-app", StaleThresholdInSeconds: 20, Source: route.SourceNATS})
+			apiEndpoint = route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, AppId: "api-app", StaleThresholdInSeconds: 20, Source: route.SourceRoutingAPI})
+		})
+
+		routedTo := func() *route.Endpoint {
+			return pool.FindByAddr("1.2.3.4:5678")
+		}
+
+		It("routes to the latest registration when the sources have the same precedence", func() {
+			Expect(pool.Put(natsEndpoint)).To(Equal(route.ADDED))
+			Expect(pool.Put(apiEndpoint)).To(Equal(route.UPDATED))
+			Expect(routedTo()).To(BeIdenticalTo(apiEndpoint))
+		})
+
+		Context("when a source has a higher precedence", func() {
+			BeforeEach(func() {
+				pool.SetSourcePrecedence(map[string]int{route.SourceRoutingAPI: 10})
+			})
+
+			It("routes to its registration", func() {
+				pool.Put(apiEndpoint)
+				Expect(pool.Put(natsEndpoint)).To(Equal(route.UNMODIFIED))
+				Expect(routedTo()).To(BeIdenticalTo(apiEndpoint))
+
+				pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, Source: route.SourceNATS}))
+				Expect(routedTo()).To(BeIdenticalTo(apiEndpoint))
+			})
+
+			It("routes to the registration of the other source once it is unregistered", func() {
+				pool.Put(apiEndpoint)
+				pool.Put(natsEndpoint)
+
+				Expect(pool.Remove(apiEndpoint)).To(BeFalse())
+				Expect(routedTo()).To(BeIdenticalTo(natsEndpoint))
+			})
+		})
+
+		It("keeps an endpoint that is still registered by another source", func() {
+			pool.Put(natsEndpoint)
+			pool.Put(apiEndpoint)
+
+			Expect(pool.Remove(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, Source: route.SourceRoutingAPI}))).To(BeFalse())
+			Expect(routedTo()).To(BeIdenticalTo(natsEndpoint))
+
+			Expect(pool.Remove(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, Source: route.SourceNATS}))).To(BeTrue())
+			Expect(pool.IsEmpty()).To(BeTrue())
+		})
+
+		It("compares modification tags within a source only", func() {
+			apiEndpoint.ModificationTag = models.ModificationTag{Guid: "abc", Index: 2}
+			pool.Put(apiEndpoint)
+			pool.Put(natsEndpoint)
+
+			older := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, Source: route.SourceRoutingAPI})
+			older.ModificationTag = models.ModificationTag{Guid: "abc", Index: 1}
+			Expect(pool.Put(older)).To(Equal(route.UNMODIFIED))
+			Expect(pool.Remove(older)).To(BeFalse())
+			Expect(routedTo()).To(BeIdenticalTo(natsEndpoint))
+		})
+
+		It("prunes the registrations of each source separately", func() {
+			pool.Put(natsEndpoint)
+			pool.Put(apiEndpoint)
+			pool.MarkUpdated(time.Now().Add(-25 * time.Second))
+			pool.Put(natsEndpoint)
+
+			Expect(pool.PruneEndpoints()).To(BeEmpty())
+			Expect(routedTo()).To(BeIdenticalTo(natsEndpoint))
+
+			json, err := pool.MarshalJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(json)).To(ContainSubstring(`"source":"nats","sources":["nats"]`))
+		})
+
+		It("lists the sources of an endpoint", func() {
+			pool.Put(natsEndpoint)
+			pool.Put(apiEndpoint)
+
+			json, err := pool.MarshalJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(json)).To(ContainSubstring(`"source":"routing-api","sources":["nats","routing-api"]`))
+		})
+	})
+
+	Context("Draining", func() {
+		var endpoint *route.Endpoint
+
+		BeforeEach(func() {
+			endpoint = route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, PrivateInstanceId: "instance-id"})
+			pool.Put(endpoint)
+		})
+
+		It("keeps the endpoint without routing to it", func() {
+			Expect(pool.Drain(endpoint, time.Now().Add(time.Hour))).To(BeTrue())
+
+			Expect(pool.IsEmpty()).To(BeFalse())
+			Expect(pool.NumDraining()).To(Equal(1))
+			Expect(pool.Endpoints("", "").Next()).To(BeNil())
+			Expect(pool.Endpoints("", "instance-id").Next()).To(BeNil())
+			pool.SetConnectionLimits(config.ConnectionLimits{MaxConns: 10})
+			Expect(pool.AwaitCapacity(context.Background())).To(Equal(route.ErrPendingQueueFull))
+
+			json, err := pool.MarshalJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(json)).To(ContainSubstring(`"draining":true`))
+		})
+
+		It("removes the endpoint once it has drained", func() {
+			Expect(pool.Drain(endpoint, time.Now().Add(50*time.Millisecond))).To(BeTrue())
+			Expect(pool.RemoveDrained(endpoint)).To(BeFalse())
+
+			time.Sleep(50 * time.Millisecond)
+			Expect(pool.RemoveDrained(endpoint)).To(BeTrue())
+			Expect(pool.IsEmpty()).To(BeTrue())
+		})
+
+		It("stops draining when the endpoint is registered again", func() {
+			Expect(pool.Drain(endpoint, time.Now())).To(BeTrue())
+			pool.Put(endpoint)
+
+			Expect(pool.RemoveDrained(endpoint)).To(BeFalse())
+			Expect(pool.NumDraining()).To(Equal(0))
+			Expect(pool.Endpoints("", "").Next()).To(Equal(endpoint))
+		})
+
+		It("only unregisters the source while other sources register the endpoint", func() {
+			apiEndpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, Source: route.SourceRoutingAPI})
+			pool.Put(apiEndpoint)
+
+			Expect(pool.Drain(endpoint, time.Now().Add(time.Hour))).To(BeFalse())
+			Expect(pool.NumDraining()).To(Equal(0))
+			Expect(pool.Endpoints("", "").Next()).To(Equal(apiEndpoint))
+		})
+
+		It("skips draining endpoints", func() {
+			other := route.NewEndpoint(&route.EndpointOpts{Host: "5.6.7.8", Port: 5678})
+			pool.Put(other)
+			pool.Drain(endpoint, time.Now().Add(time.Hour))
+
+			for _, lb := range []string{config.LOAD_BALANCE_RR, config.LOAD_BALANCE_LC} {
+				iter := pool.Endpoints(lb, "")
+				for i := 0; i < 10; i++ {
+					Expect(iter.Next()).To(Equal(other))
+				}
+			}
+		})
+	})
+
+	Context("Zone aware routing", func() {
+		var local1, local2, remote *route.Endpoint
+
+		zoneAware := config.ZoneAwareRoutingConfig{Enabled: true, Tag: "zone", MinHealthyEndpoints: 2}
+
+		BeforeEach(func() {
+			local1 = route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.1", Port: 8080})
+			local1.Zone = "z1"
+			local2 = route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.2", Port: 8080})
+			local2.Zone = "z1"
+			remote = route.NewEndpoint(&route.EndpointOpts{Host: "10.0.1.1", Port: 8080})
+			remote.Zone = "z2"
+			pool.Put(local1)
+			pool.Put(local2)
+			pool.Put(remote)
+		})
+
+		routedToRemote := func(lb string) int {
+			iter := pool.Endpoints(lb, "")
+			count := 0
+			for i := 0; i < 100; i++ {
+				if iter.Next() == remote {
+					count++
+				}
+			}
+			return count
+		}
+
+		It("prefers the endpoints in the zone", func() {
+			pool.SetZone("z1", zoneAware)
+
+			Expect(routedToRemote(config.LOAD_BALANCE_RR)).To(Equal(0))
+			Expect(routedToRemote(config.LOAD_BALANCE_LC)).To(Equal(0))
+		})
+
+		It("routes to all zones when the zone has too few healthy endpoints", func() {
+			pool.SetZone("z1", zoneAware)
+			pool.Drain(local1, time.Now().Add(time.Hour))
+
+			Expect(routedToRemote(config.LOAD_BALANCE_RR)).To(BeNumerically(">", 0))
+			Expect(routedToRemote(config.LOAD_BALANCE_LC)).To(BeNumerically(">", 0))
+		})
+
+		It("routes to all zones when the endpoints of the zone are overloaded", func() {
+			zoneAware.MaxLoadPerEndpoint = 5
+			pool.SetZone("z1", zoneAware)
+			setConnectionCount([]*route.Endpoint{local1, local2, remote}, []int{5, 5, 0})
+
+			Expect(routedToRemote(config.LOAD_BALANCE_RR)).To(BeNumerically(">", 0))
+			Expect(routedToRemote(config.LOAD_BALANCE_LC)).To(Equal(100))
+		})
+
+		It("routes to all zones without a zone", func() {
+			Expect(routedToRemote(config.LOAD_BALANCE_RR)).To(BeNumerically("~", 33, 1))
+		})
+
+		It("shows the zone of the endpoints", func() {
+			json, err := pool.MarshalJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(json)).To(ContainSubstring(`"zone":"z2"`))
+		})
+	})
+
+	Context("Slow start", func() {
+		var endpoint *route.Endpoint
+
+		BeforeEach(func() {
+			endpoint = route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678})
+		})
+
+		It("shows the weight of endpoints that are warming up", func() {
+			pool.SetSlowStart(config.SlowStartConfig{Window: time.Hour, MinWeight: 0.1, Aggression: 1})
+			pool.Put(endpoint)
+
+			json, err := pool.MarshalJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(json)).To(ContainSubstring(`"slow_start_weight":0.1`))
+		})
+
+		It("ramps up the weight over the window", func() {
+			pool.SetSlowStart(config.SlowStartConfig{Window: 200 * time.Millisecond, MinWeight: 0.1, Aggression: 1})
+			pool.Put(endpoint)
+			time.Sleep(100 * time.Millisecond)
+
+			var endpoints []map[string]interface{}
+			b, err := pool.MarshalJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(json.Unmarshal(b, &endpoints)).To(Succeed())
+			Expect(endpoints[0]["slow_start_weight"]).To(BeNumerically("~", 0.5, 0.2))
+
+			Eventually(func() string {
+				b, _ := pool.MarshalJSON()
+				return string(b)
+			}).ShouldNot(ContainSubstring("slow_start_weight"))
+		})
+
+		It("does not warm up endpoints that are registered again", func() {
+			pool.SetSlowStart(config.SlowStartConfig{Window: 50 * time.Millisecond, MinWeight: 0.1, Aggression: 1})
+			pool.Put(endpoint)
+			time.Sleep(60 * time.Millisecond)
+			pool.Put(route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678}))
+
+			json, err := pool.MarshalJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(json)).ToNot(ContainSubstring("slow_start_weight"))
+		})
+	})
+
+	Context("Restore", func() {
+		var restored *route.Endpoint
+
+		BeforeEach(func() {
+			restored = route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, StaleThresholdInSeconds: 20})
+			restored.Provisional = true
+		})
+
+		It("keeps the time the endpoint was last updated", func() {
+			Expect(pool.Restore(restored, time.Now().Add(-25*time.Second))).To(BeTrue())
+			Expect(pool.PruneEndpoints()).To(ConsistOf(restored))
+		})
+
+		It("does not replace an endpoint in the pool", func() {
+			registered := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678})
+			pool.Put(registered)
+
+			Expect(pool.Restore(restored, time.Now())).To(BeFalse())
+			pool.Each(func(e *route.Endpoint) { Expect(e).To(BeIdenticalTo(registered)) })
+		})
+
+		It("prunes provisional tls endpoints", func() {
+			tlsEndpoint := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678, UseTLS: true, StaleThresholdInSeconds: 20})
+			tlsEndpoint.Provisional = true
+
+			pool.Restore(tlsEndpoint, time.Now().Add(-25*time.Second))
+			Expect(pool.PruneEndpoints()).To(ConsistOf(tlsEndpoint))
+		})
+
+		It("is replaced by a registration regardless of the modification tag", func() {
+			restored.ModificationTag = models.ModificationTag{Guid: "abc", Index: 5}
+			pool.Restore(restored, time.Now())
+
+			registered := route.NewEndpoint(&route.EndpointOpts{
+				Host:            "1.2.3.4",
+				Port:            5678,
+				ModificationTag: models.ModificationTag{Guid: "abc", Index: 5},
+			})
+			Expect(pool.Put(registered)).To(Equal(route.UPDATED))
+			pool.Each(func(e *route.Endpoint) { Expect(e).To(BeIdenticalTo(registered)) })
+		})
+	})
+
 	Context("MarkUpdated", func() {
 		It("updates all endpoints", func() {
 			e1 := route.NewEndpoint(&route.EndpointOpts{Port: 5678, StaleThresholdInSeconds: 120})
@@ -478,6 +938,33 @@ var _ = Describe("Pool", func() {
 		})
 	})
 
+	Context("FindByAddr", func() {
+		It("returns the endpoint with the address", func() {
+			e1 := route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678})
+			pool.Put(e1)
+
+			Expect(pool.FindByAddr("1.2.3.4:5678")).To(Equal(e1))
+			Expect(pool.FindByAddr("1.2.3.4:1234")).To(BeNil())
+		})
+	})
+
+	Context("Equivalent", func() {
+		It("ignores the modification tag", func() {
+			opts := route.EndpointOpts{
+				Host:            "1.2.3.4",
+				Port:            5678,
+				Tags:            map[string]string{"component": "app"},
+				ModificationTag: models.ModificationTag{Guid: "abc", Index: 1},
+			}
+			e1 := route.NewEndpoint(&opts)
+			opts.ModificationTag.Index = 2
+			Expect(e1.Equivalent(route.NewEndpoint(&opts))).To(BeTrue())
+
+			opts.Tags = map[string]string{"component": "other"}
+			Expect(e1.Equivalent(route.NewEndpoint(&opts))).To(BeFalse())
+		})
+	})
+
 	Context("Each", func() {
 		It("applies a function to each endpoint", func() {
 			e1 := route.NewEndpoint(&route.EndpointOpts{Port: 5678})
@@ -571,6 +1058,27 @@ var _ = Describe("Pool", func() {
 		})
 	})
 
+	Context("when endpoints have header rules", func() {
+		var e *route.Endpoint
+		BeforeEach(func() {
+			e = route.NewEndpoint(&route.EndpointOpts{
+				Host:                    "1.2.3.4",
+				Port:                    5678,
+				StaleThresholdInSeconds: -1,
+				HeaderRules: &route.HeaderRules{
+					Host:     "internal.example.com",
+					Response: route.HeaderRuleSet{Remove: []string{"Server"}},
+				},
+			})
+		})
+		It("marshals json ", func() {
+			pool.Put(e)
+			json, err := pool.MarshalJSON()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(json)).To(Equal(`[{"address":"1.2.3.4:5678","tls":false,"ttl":-1,"tags":null,"header_rules":{"host":"internal.example.com","request":{},"response":{"remove":["Server"]}}}]`))
+		})
+	})
+
 	Context("when endpoints have empty tags", func() {
 		var e *route.Endpoint
 		BeforeEach(func() {
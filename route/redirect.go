@@ -0,0 +1,68 @@
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redirect describes a route that is answered by the router with a redirect
+// instead of being proxied to a backend.
+type Redirect struct {
+	URL           string `json:"url"`
+	StatusCode    int    `json:"status_code,omitempty"`
+	PreservePath  bool   `json:"preserve_path,omitempty"`
+	PreserveQuery bool   `json:"preserve_query,omitempty"`
+}
+
+// Validate returns an error if the redirect target is not an absolute http(s)
+// URL or the status code is not a redirect status.
+func (r *Redirect) Validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("redirect url must be an absolute http or https url: %s", r.URL)
+	}
+
+	switch r.StatusCode {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return nil
+	default:
+		return fmt.Errorf("invalid redirect status code: %d", r.StatusCode)
+	}
+}
+
+// Code returns the status code of the redirect, defaulting to 302 Found.
+func (r *Redirect) Code() int {
+	if r.StatusCode == 0 {
+		return http.StatusFound
+	}
+	return r.StatusCode
+}
+
+// Location returns the target of the redirect for the given request.
+func (r *Redirect) Location(req *http.Request) string {
+	location := r.URL
+	query := ""
+	if idx := strings.Index(location, "?"); idx >= 0 {
+		location, query = location[:idx], location[idx+1:]
+	}
+
+	if r.PreservePath {
+		location = strings.TrimSuffix(location, "/") + req.URL.EscapedPath()
+	}
+	if r.PreserveQuery && req.URL.RawQuery != "" {
+		if query != "" {
+			query += "&"
+		}
+		query += req.URL.RawQuery
+	}
+
+	if query != "" {
+		location += "?" + query
+	}
+	return location
+}
@@ -0,0 +1,70 @@
+package route_test
+
+import (
+	"net/http"
+
+	"code.cloudfoundry.org/gorouter/route"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Redirect", func() {
+	Describe("Validate", func() {
+		It("accepts absolute http and https urls", func() {
+			Expect((&route.Redirect{URL: "https://example.com/foo"}).Validate()).To(Succeed())
+			Expect((&route.Redirect{URL: "http://example.com", StatusCode: 301}).Validate()).To(Succeed())
+		})
+
+		It("rejects relative urls", func() {
+			Expect((&route.Redirect{URL: "/foo"}).Validate()).ToNot(Succeed())
+		})
+
+		It("rejects other schemes", func() {
+			Expect((&route.Redirect{URL: "ftp://example.com"}).Validate()).ToNot(Succeed())
+		})
+
+		It("rejects status codes that are not redirects", func() {
+			Expect((&route.Redirect{URL: "https://example.com", StatusCode: 200}).Validate()).ToNot(Succeed())
+		})
+	})
+
+	Describe("Code", func() {
+		It("defaults to 302", func() {
+			Expect((&route.Redirect{}).Code()).To(Equal(http.StatusFound))
+		})
+
+		It("returns the configured status code", func() {
+			Expect((&route.Redirect{StatusCode: 308}).Code()).To(Equal(http.StatusPermanentRedirect))
+		})
+	})
+
+	Describe("Location", func() {
+		var req *http.Request
+
+		BeforeEach(func() {
+			var err error
+			req, err = http.NewRequest("GET", "http://old.example.com/some/path?foo=bar", nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the url", func() {
+			rd := &route.Redirect{URL: "https://new.example.com/landing"}
+			Expect(rd.Location(req)).To(Equal("https://new.example.com/landing"))
+		})
+
+		It("appends the request path when preserving the path", func() {
+			rd := &route.Redirect{URL: "https://new.example.com/", PreservePath: true}
+			Expect(rd.Location(req)).To(Equal("https://new.example.com/some/path"))
+		})
+
+		It("appends the request query when preserving the query", func() {
+			rd := &route.Redirect{URL: "https://new.example.com/landing", PreserveQuery: true}
+			Expect(rd.Location(req)).To(Equal("https://new.example.com/landing?foo=bar"))
+		})
+
+		It("merges the request query with the query of the url", func() {
+			rd := &route.Redirect{URL: "https://new.example.com?source=old", PreservePath: true, PreserveQuery: true}
+			Expect(rd.Location(req)).To(Equal("https://new.example.com/some/path?source=old&foo=bar"))
+		})
+	})
+})
@@ -51,6 +51,14 @@ func (r *RoundRobin) next() *Endpoint {
 
 	startIdx := r.pool.nextIdx
 	curIdx := startIdx
+	now := time.Now()
+	zone := r.pool.localZone(now)
+
+	// the first available endpoint that was skipped while warming up
+	var warming *endpointElem
+	var warmingIdx int
+	// endpoints that are draining, in another zone or at the connection limit
+	skipped := 0
 	for {
 		e := r.pool.endpoints[curIdx]
 
@@ -67,12 +75,31 @@ func (r *RoundRobin) next() *Endpoint {
 			}
 		}
 
-		if e.failedAt == nil {
-			r.pool.nextIdx = curIdx
-			return e.endpoint
+		if e.isDraining() || zone != "" && e.endpoint.Zone != zone || r.pool.atLimit(e) {
+			skipped++
+		} else if e.failedAt == nil {
+			// endpoints that are warming up take their turn with a
+			// probability of their weight
+			w := r.pool.weight(e, now)
+			if w >= 1 || r.pool.random.Float64() < w {
+				r.pool.nextIdx = curIdx
+				return e.endpoint
+			}
+			if warming == nil {
+				warming, warmingIdx = e, curIdx
+			}
 		}
 
 		if curIdx == startIdx {
+			if warming != nil {
+				r.pool.nextIdx = warmingIdx
+				return warming.endpoint
+			}
+			if skipped == last {
+				return nil
+			}
+			skipped = 0
+
 			// all endpoints are marked failed so reset everything to available
 			for _, e2 := range r.pool.endpoints {
 				e2.failedAt = nil
@@ -93,4 +120,5 @@ func (r *RoundRobin) PreRequest(e *Endpoint) {
 
 func (r *RoundRobin) PostRequest(e *Endpoint) {
 	e.Stats.NumberConnections.Decrement()
+	r.pool.released()
 }
@@ -5,6 +5,7 @@ import (
 	"net"
 	"time"
 
+	"code.cloudfoundry.org/gorouter/config"
 	"code.cloudfoundry.org/gorouter/route"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -132,6 +133,37 @@ var _ = Describe("RoundRobin", func() {
 			Expect(foundEndpoint).To(Equal(endpointBar))
 		})
 
+		Context("when an endpoint is warming up", func() {
+			var warm, warming *route.Endpoint
+
+			BeforeEach(func() {
+				warm = route.NewEndpoint(&route.EndpointOpts{Host: "1.2.3.4", Port: 5678})
+				warming = route.NewEndpoint(&route.EndpointOpts{Host: "5.6.7.8", Port: 1234})
+				pool.SetSlowStart(config.SlowStartConfig{Window: time.Hour, MinWeight: 0.1, Aggression: 1})
+			})
+
+			It("sends it a share of the requests by its weight", func() {
+				// restored endpoints do not warm up
+				pool.Restore(warm, time.Now())
+				pool.Put(warming)
+
+				iter := route.NewRoundRobin(pool, "")
+				count := 0
+				for i := 0; i < 1000; i++ {
+					if iter.Next() == warming {
+						count++
+					}
+				}
+				Expect(count).To(BeNumerically("~", 90, 50))
+			})
+
+			It("returns it when it is the only endpoint", func() {
+				pool.Put(warming)
+
+				iter := route.NewRoundRobin(pool, "")
+				Expect(iter.Next()).To(Equal(warming))
+			})
+		})
 	})
 
 	Describe("Failed", func() {
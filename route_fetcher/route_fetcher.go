@@ -1,7 +1,10 @@
 package route_fetcher
 
 import (
+	"errors"
 	"os"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -24,19 +27,46 @@ type RouteFetcher struct {
 	FetchRoutesInterval                time.Duration
 	SubscriptionRetryIntervalInSeconds int
 
+	acceptTLS       bool
 	logger          logger.Logger
-	endpoints       []models.Route
 	client          routing_api.Client
 	stopEventSource int32
 	eventSource     atomic.Value
 	eventChannel    chan routing_api.Event
 
+	lock  sync.Mutex
+	index map[routeKey]indexedRoute
+	seq   int
+
 	clock clock.Clock
 }
 
+// indexedRoute is a route with the order it was added to the index in, so
+// that routes are unregistered in the order they were registered
+type indexedRoute struct {
+	models.Route
+	seq int
+}
+
+type bySeq []indexedRoute
+
+func (s bySeq) Len() int           { return len(s) }
+func (s bySeq) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s bySeq) Less(i, j int) bool { return s[i].seq < s[j].seq }
+
+type routeKey struct {
+	route string
+	ip    string
+	port  uint16
+}
+
 const (
 	TokenFetchErrors      = "token_fetch_errors"
 	SubscribeEventsErrors = "subscribe_events_errors"
+	SyncDuration          = "route_fetcher_sync_duration"
+	SyncRoutesAdded       = "route_fetcher_routes_added"
+	SyncRoutesChanged     = "route_fetcher_routes_changed"
+	SyncRoutesRemoved     = "route_fetcher_routes_removed"
 	maxRetries            = 3
 )
 
@@ -48,9 +78,11 @@ func NewRouteFetcher(logger logger.Logger, uaaClient uaa_client.Client, routeReg
 		FetchRoutesInterval:                cfg.PruneStaleDropletsInterval / 2,
 		SubscriptionRetryIntervalInSeconds: subscriptionRetryInterval,
 
+		acceptTLS:    cfg.Backends.EnableTLS,
 		client:       client,
 		logger:       logger,
 		eventChannel: make(chan routing_api.Event, 1024),
+		index:        make(map[routeKey]indexedRoute),
 		clock:        clock,
 	}
 }
@@ -155,19 +187,36 @@ func (r *RouteFetcher) subscribeToEvents(token *schema.Token) error {
 	return err
 }
 
+// HandleEvent applies the event to the registry and to the routes of the last
+// sync, so that the next sync only reports what the events did not cover.
 func (r *RouteFetcher) HandleEvent(e routing_api.Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
 	eventRoute := e.Route
+	key := keyOf(eventRoute)
+	switch e.Action {
+	case "Delete":
+		delete(r.index, key)
+	case "Upsert":
+		current, found := r.index[key]
+		if !found {
+			current.seq = r.seq
+			r.seq++
+		} else if !routeEquals(current.Route, eventRoute) {
+			r.unregister(current.Route)
+		}
+		r.index[key] = indexedRoute{Route: eventRoute, seq: current.seq}
+	default:
+		return
+	}
+
 	uri := route.Uri(eventRoute.Route)
-	endpoint := route.NewEndpoint(&route.EndpointOpts{
-		AppId:                   eventRoute.LogGuid,
-		Host:                    eventRoute.IP,
-		Port:                    uint16(eventRoute.Port),
-		ServerCertDomainSAN:     eventRoute.LogGuid,
-		StaleThresholdInSeconds: eventRoute.GetTTL(),
-		RouteServiceUrl:         eventRoute.RouteServiceUrl,
-		ModificationTag:         eventRoute.ModificationTag,
-		UseTLS:                  false,
-	})
+	endpoint, err := r.makeEndpoint(eventRoute)
+	if err != nil {
+		r.logger.Error("invalid-route", zap.String("route", eventRoute.Route), zap.Error(err))
+		return
+	}
 	switch e.Action {
 	case "Delete":
 		r.RouteRegistry.Unregister(uri, endpoint)
@@ -176,6 +225,36 @@ func (r *RouteFetcher) HandleEvent(e routing_api.Event) {
 	}
 }
 
+// makeEndpoint uses the TLS port of a route when backend TLS is enabled. The
+// log guid stands in for the server cert SAN of routes without one.
+func (r *RouteFetcher) makeEndpoint(aRoute models.Route) (*route.Endpoint, error) {
+	port, useTLS := aRoute.Port, false
+	if r.acceptTLS && aRoute.TLSPort != 0 {
+		port, useTLS = aRoute.TLSPort, true
+	} else if port == 0 {
+		return nil, errors.New("backend tls is not enabled")
+	}
+
+	san := aRoute.ServerCertDomainSAN
+	if san == "" {
+		san = aRoute.LogGuid
+	}
+
+	return route.NewEndpoint(&route.EndpointOpts{
+		AppId:                   aRoute.LogGuid,
+		Host:                    aRoute.IP,
+		Port:                    port,
+		ServerCertDomainSAN:     san,
+		PrivateInstanceId:       aRoute.InstanceId,
+		IsolationSegment:        aRoute.IsolationSegment,
+		StaleThresholdInSeconds: aRoute.GetTTL(),
+		RouteServiceUrl:         aRoute.RouteServiceUrl,
+		ModificationTag:         aRoute.ModificationTag,
+		UseTLS:                  useTLS,
+		Source:                  route.SourceRoutingAPI,
+	}), nil
+}
+
 func (r *RouteFetcher) FetchRoutes() error {
 	r.logger.Debug("syncer-fetch-routes-started")
 
@@ -219,67 +298,81 @@ func (r *RouteFetcher) fetchRoutesWithTokenRefresh() ([]models.Route, error) {
 	return routes, err
 }
 
+// refreshEndpoints registers all routes, which keeps them from going stale,
+// and unregisters the routes that are gone or have changed since the last
+// sync.
 func (r *RouteFetcher) refreshEndpoints(validRoutes []models.Route) {
-	r.deleteEndpoints(validRoutes)
-
-	r.endpoints = validRoutes
-
-	for _, aRoute := range r.endpoints {
-		r.RouteRegistry.Register(
-			route.Uri(aRoute.Route),
-			route.NewEndpoint(&route.EndpointOpts{
-				AppId:                   aRoute.LogGuid,
-				Host:                    aRoute.IP,
-				Port:                    uint16(aRoute.Port),
-				ServerCertDomainSAN:     aRoute.LogGuid,
-				StaleThresholdInSeconds: aRoute.GetTTL(),
-				RouteServiceUrl:         aRoute.RouteServiceUrl,
-				ModificationTag:         aRoute.ModificationTag,
-				UseTLS:                  false,
-			}),
-		)
-	}
-}
+	start := r.clock.Now()
 
-func (r *RouteFetcher) deleteEndpoints(validRoutes []models.Route) {
-	var diff []models.Route
+	r.lock.Lock()
+	defer r.lock.Unlock()
 
-	for _, curRoute := range r.endpoints {
-		routeFound := false
+	index := make(map[routeKey]indexedRoute, len(validRoutes))
+	for i, aRoute := range validRoutes {
+		index[keyOf(aRoute)] = indexedRoute{Route: aRoute, seq: i}
+	}
 
-		for _, validRoute := range validRoutes {
-			if routeEquals(curRoute, validRoute) {
-				routeFound = true
-				break
-			}
+	var added, changed, removed int
+	var unregistered []indexedRoute
+	for key, aRoute := range r.index {
+		validRoute, found := index[key]
+		switch {
+		case !found:
+			removed++
+		case !routeEquals(aRoute.Route, validRoute.Route):
+			changed++
+		default:
+			continue
 		}
-
-		if !routeFound {
-			diff = append(diff, curRoute)
+		unregistered = append(unregistered, aRoute)
+	}
+	sort.Sort(bySeq(unregistered))
+	for _, aRoute := range unregistered {
+		r.unregister(aRoute.Route)
+	}
+	for key := range index {
+		if _, found := r.index[key]; !found {
+			added++
 		}
 	}
 
-	for _, aRoute := range diff {
-		r.RouteRegistry.Unregister(
-			route.Uri(aRoute.Route),
-			route.NewEndpoint(&route.EndpointOpts{
-				AppId:                   aRoute.LogGuid,
-				Host:                    aRoute.IP,
-				Port:                    uint16(aRoute.Port),
-				ServerCertDomainSAN:     aRoute.LogGuid,
-				StaleThresholdInSeconds: aRoute.GetTTL(),
-				RouteServiceUrl:         aRoute.RouteServiceUrl,
-				ModificationTag:         aRoute.ModificationTag,
-				UseTLS:                  false,
-			}),
-		)
+	r.index = index
+	r.seq = len(validRoutes)
+
+	for _, aRoute := range validRoutes {
+		endpoint, err := r.makeEndpoint(aRoute)
+		if err != nil {
+			r.logger.Error("invalid-route", zap.String("route", aRoute.Route), zap.Error(err))
+			continue
+		}
+		r.RouteRegistry.Register(route.Uri(aRoute.Route), endpoint)
 	}
+
+	metrics.SendValue(SyncDuration, float64(r.clock.Since(start)/time.Millisecond), "ms")
+	metrics.SendValue(SyncRoutesAdded, float64(added), "")
+	metrics.SendValue(SyncRoutesChanged, float64(changed), "")
+	metrics.SendValue(SyncRoutesRemoved, float64(removed), "")
 }
 
-func routeEquals(current, desired models.Route) bool {
-	if current.Route == desired.Route && current.IP == desired.IP && current.Port == desired.Port {
-		return true
+func (r *RouteFetcher) unregister(aRoute models.Route) {
+	endpoint, err := r.makeEndpoint(aRoute)
+	if err != nil {
+		r.logger.Error("invalid-route", zap.String("route", aRoute.Route), zap.Error(err))
+		return
 	}
+	r.RouteRegistry.Unregister(route.Uri(aRoute.Route), endpoint)
+}
+
+func keyOf(aRoute models.Route) routeKey {
+	return routeKey{route: aRoute.Route, ip: aRoute.IP, port: aRoute.Port}
+}
 
-	return false
+func routeEquals(current, desired models.Route) bool {
+	return current.Route == desired.Route &&
+		current.IP == desired.IP &&
+		current.Port == desired.Port &&
+		current.TLSPort == desired.TLSPort &&
+		current.ServerCertDomainSAN == desired.ServerCertDomainSAN &&
+		current.IsolationSegment == desired.IsolationSegment &&
+		current.InstanceId == desired.InstanceId
 }
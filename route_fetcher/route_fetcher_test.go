@@ -155,6 +155,7 @@ var _ = Describe("RouteFetcher", func() {
 						StaleThresholdInSeconds: *expectedRoute.TTL,
 						RouteServiceUrl:         expectedRoute.RouteServiceUrl,
 						ModificationTag:         expectedRoute.ModificationTag,
+						Source:                  route.SourceRoutingAPI,
 					})))
 			}
 		})
@@ -227,10 +228,122 @@ var _ = Describe("RouteFetcher", func() {
 						StaleThresholdInSeconds: *expectedRoute.TTL,
 						RouteServiceUrl:         expectedRoute.RouteServiceUrl,
 						ModificationTag:         expectedRoute.ModificationTag,
+						Source:                  route.SourceRoutingAPI,
 					})))
 			}
 		})
 
+		Context("when routes have a tls port", func() {
+			BeforeEach(func() {
+				response[0].TLSPort = 61001
+				response[0].ServerCertDomainSAN = "san"
+				response[0].IsolationSegment = "segment"
+				response[0].InstanceId = "instance-id"
+				response[1].Port = 0
+				response[1].TLSPort = 61002
+				client.RoutesReturns(response, nil)
+			})
+
+			It("registers the tls port when backend tls is enabled", func() {
+				cfg.Backends.EnableTLS = true
+				fetcher = NewRouteFetcher(logger, uaaClient, registry, cfg, client, 0, clock)
+
+				err := fetcher.FetchRoutes()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(registry.RegisterCallCount()).To(Equal(3))
+
+				_, endpoint := registry.RegisterArgsForCall(0)
+				Expect(endpoint).To(Equal(
+					route.NewEndpoint(&route.EndpointOpts{
+						AppId:                   "guid",
+						Host:                    "1.1.1.1",
+						Port:                    61001,
+						ServerCertDomainSAN:     "san",
+						PrivateInstanceId:       "instance-id",
+						IsolationSegment:        "segment",
+						StaleThresholdInSeconds: 1,
+						RouteServiceUrl:         "rs",
+						UseTLS:                  true,
+						Source:                  route.SourceRoutingAPI,
+					})))
+
+				_, endpoint = registry.RegisterArgsForCall(1)
+				Expect(endpoint.CanonicalAddr()).To(Equal("2.2.2.2:61002"))
+				Expect(endpoint.IsTLS()).To(BeTrue())
+			})
+
+			It("registers the plain port when backend tls is disabled", func() {
+				err := fetcher.FetchRoutes()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(registry.RegisterCallCount()).To(Equal(2))
+
+				_, endpoint := registry.RegisterArgsForCall(0)
+				Expect(endpoint.CanonicalAddr()).To(Equal("1.1.1.1:1"))
+				Expect(endpoint.IsTLS()).To(BeFalse())
+				Expect(endpoint.IsolationSegment).To(Equal("segment"))
+
+				_, endpoint = registry.RegisterArgsForCall(1)
+				Expect(endpoint.CanonicalAddr()).To(Equal("3.3.3.3:3"))
+				Expect(logger).To(gbytes.Say("invalid-route"))
+			})
+
+			It("logs the invalid routes it cannot unregister", func() {
+				Expect(fetcher.FetchRoutes()).To(Succeed())
+				Expect(logger).To(gbytes.Say("invalid-route"))
+
+				client.RoutesReturns([]models.Route{response[0], response[2]}, nil)
+				Expect(fetcher.FetchRoutes()).To(Succeed())
+				Expect(registry.UnregisterCallCount()).To(Equal(0))
+				Expect(logger).To(gbytes.Say(`"message":"invalid-route".*"route":"foo"`))
+			})
+		})
+
+		It("registers routes again when their tls port, isolation segment or instance id changed", func() {
+			cfg.Backends.EnableTLS = true
+			fetcher = NewRouteFetcher(logger, uaaClient, registry, cfg, client, 0, clock)
+			client.RoutesReturns(response, nil)
+
+			err := fetcher.FetchRoutes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(registry.RegisterCallCount()).To(Equal(3))
+
+			changed := make([]models.Route, len(response))
+			copy(changed, response)
+			changed[0].TLSPort = 61001
+			changed[1].IsolationSegment = "segment"
+			changed[2].InstanceId = "instance-id"
+			client.RoutesReturns(changed, nil)
+
+			err = fetcher.FetchRoutes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(registry.UnregisterCallCount()).To(Equal(3))
+			Expect(registry.RegisterCallCount()).To(Equal(6))
+
+			_, endpoint := registry.UnregisterArgsForCall(0)
+			Expect(endpoint.CanonicalAddr()).To(Equal("1.1.1.1:1"))
+			_, endpoint = registry.RegisterArgsForCall(3)
+			Expect(endpoint.CanonicalAddr()).To(Equal("1.1.1.1:61001"))
+			_, endpoint = registry.RegisterArgsForCall(4)
+			Expect(endpoint.IsolationSegment).To(Equal("segment"))
+			_, endpoint = registry.RegisterArgsForCall(5)
+			Expect(endpoint.PrivateInstanceId).To(Equal("instance-id"))
+		})
+
+		It("emits the sync duration and the size of the diff", func() {
+			client.RoutesReturns(response, nil)
+			Expect(fetcher.FetchRoutes()).To(Succeed())
+			Expect(sender.GetValue(SyncRoutesAdded).Value).To(BeEquivalentTo(3))
+			Expect(sender.GetValue(SyncDuration).Unit).To(Equal("ms"))
+
+			changed := []models.Route{response[0], response[1]}
+			changed[1].IsolationSegment = "segment"
+			client.RoutesReturns(changed, nil)
+			Expect(fetcher.FetchRoutes()).To(Succeed())
+			Expect(sender.GetValue(SyncRoutesAdded).Value).To(BeEquivalentTo(0))
+			Expect(sender.GetValue(SyncRoutesChanged).Value).To(BeEquivalentTo(1))
+			Expect(sender.GetValue(SyncRoutesRemoved).Value).To(BeEquivalentTo(1))
+		})
+
 		Context("when the routing api returns an error", func() {
 			Context("error is not unauthorized error", func() {
 				It("returns an error", func() {
@@ -381,6 +494,20 @@ var _ = Describe("RouteFetcher", func() {
 				})
 			})
 
+			Context("and the event stream is interrupted", func() {
+				BeforeEach(func() {
+					fetcher.FetchRoutesInterval = 5 * time.Minute
+				})
+
+				It("subscribes again and fetches all routes", func() {
+					Eventually(client.RoutesCallCount).Should(Equal(1))
+					errorChannel <- errors.New("disconnected")
+
+					Eventually(client.SubscribeToEventsWithMaxRetriesCallCount).Should(Equal(2))
+					Eventually(client.RoutesCallCount).Should(Equal(2))
+				})
+			})
+
 			Context("and the event source fails to subscribe", func() {
 				Context("with error other than unauthorized", func() {
 					BeforeEach(func() {
@@ -461,11 +588,32 @@ var _ = Describe("RouteFetcher", func() {
 						StaleThresholdInSeconds: *eventRoute.TTL,
 						RouteServiceUrl:         eventRoute.RouteServiceUrl,
 						ModificationTag:         eventRoute.ModificationTag,
+						Source:                  route.SourceRoutingAPI,
 					})))
 
 			})
 		})
 
+		Context("When the event is an Upsert of a tls route", func() {
+			It("registers the tls endpoint", func() {
+				cfg.Backends.EnableTLS = true
+				fetcher = NewRouteFetcher(logger, uaaClient, registry, cfg, client, 0, clock)
+
+				eventRoute := models.NewRoute("z.a.k", 63, "42.42.42.42", "Tomato", "", 1)
+				eventRoute.TLSPort = 61001
+				eventRoute.ServerCertDomainSAN = "tomato.internal"
+				eventRoute.InstanceId = "instance-id"
+
+				fetcher.HandleEvent(routing_api.Event{Action: "Upsert", Route: eventRoute})
+				Expect(registry.RegisterCallCount()).To(Equal(1))
+				_, endpoint := registry.RegisterArgsForCall(0)
+				Expect(endpoint.CanonicalAddr()).To(Equal("42.42.42.42:61001"))
+				Expect(endpoint.IsTLS()).To(BeTrue())
+				Expect(endpoint.ServerCertDomainSAN).To(Equal("tomato.internal"))
+				Expect(endpoint.PrivateInstanceId).To(Equal("instance-id"))
+			})
+		})
+
 		Context("When the event is a DELETE", func() {
 			It("unregisters the route from the registry", func() {
 				eventRoute := models.NewRoute(
@@ -495,9 +643,59 @@ var _ = Describe("RouteFetcher", func() {
 						StaleThresholdInSeconds: *eventRoute.TTL,
 						RouteServiceUrl:         eventRoute.RouteServiceUrl,
 						ModificationTag:         eventRoute.ModificationTag,
+						Source:                  route.SourceRoutingAPI,
 					})))
 
 			})
 		})
+
+		Context("When the next sync returns the routes of the events", func() {
+			var eventRoute models.Route
+
+			BeforeEach(func() {
+				uaaClient.FetchTokenReturns(token, nil)
+				eventRoute = models.NewRoute("z.a.k", 63, "42.42.42.42", "Tomato", "", 1)
+				client.RoutesReturns([]models.Route{eventRoute}, nil)
+				Expect(fetcher.FetchRoutes()).To(Succeed())
+			})
+
+			It("does not count upserted routes as added", func() {
+				upserted := models.NewRoute("z.a.k", 64, "42.42.42.42", "Tomato", "", 1)
+				fetcher.HandleEvent(routing_api.Event{Action: "Upsert", Route: upserted})
+
+				client.RoutesReturns([]models.Route{eventRoute, upserted}, nil)
+				Expect(fetcher.FetchRoutes()).To(Succeed())
+				Expect(sender.GetValue(SyncRoutesAdded).Value).To(BeEquivalentTo(0))
+				Expect(sender.GetValue(SyncRoutesChanged).Value).To(BeEquivalentTo(0))
+			})
+
+			It("does not count deleted routes as removed", func() {
+				fetcher.HandleEvent(routing_api.Event{Action: "Delete", Route: eventRoute})
+				Expect(registry.UnregisterCallCount()).To(Equal(1))
+
+				client.RoutesReturns([]models.Route{}, nil)
+				Expect(fetcher.FetchRoutes()).To(Succeed())
+				Expect(sender.GetValue(SyncRoutesRemoved).Value).To(BeEquivalentTo(0))
+				Expect(registry.UnregisterCallCount()).To(Equal(1))
+			})
+
+			It("unregisters the previous endpoint of a changed route", func() {
+				cfg.Backends.EnableTLS = true
+				fetcher = NewRouteFetcher(logger, uaaClient, registry, cfg, client, 0, clock)
+				Expect(fetcher.FetchRoutes()).To(Succeed())
+
+				changed := eventRoute
+				changed.TLSPort = 61001
+				fetcher.HandleEvent(routing_api.Event{Action: "Upsert", Route: changed})
+				Expect(registry.UnregisterCallCount()).To(Equal(1))
+				_, endpoint := registry.UnregisterArgsForCall(0)
+				Expect(endpoint.CanonicalAddr()).To(Equal("42.42.42.42:63"))
+
+				client.RoutesReturns([]models.Route{changed}, nil)
+				Expect(fetcher.FetchRoutes()).To(Succeed())
+				Expect(sender.GetValue(SyncRoutesChanged).Value).To(BeEquivalentTo(0))
+				Expect(registry.UnregisterCallCount()).To(Equal(1))
+			})
+		})
 	})
 })
@@ -72,6 +72,7 @@ type Router struct {
 	logger              logger.Logger
 	errChan             chan error
 	routeServicesServer rss
+	restoredEndpoints   int
 }
 
 func NewRouter(logger logger.Logger, cfg *config.Config, handler http.Handler, mbusClient *nats.Conn, r *registry.RouteRegistry,
@@ -100,8 +101,11 @@ func NewRouter(logger logger.Logger, cfg *config.Config, handler http.Handler, m
 		Varz:    varz,
 		Healthz: healthz,
 		Health:  health,
-		InfoRoutes: map[string]json.Marshaler{
-			"/routes": r,
+		Handlers: map[string]http.Handler{
+			"/routes":             handlers.NewRoutes(r, logger),
+			"/routes/explain":     handlers.NewRouteExplain(r, logger),
+			"/routes/maintenance": handlers.NewMaintenance(r, logger),
+			"/routes/events":      handlers.NewRouteEvents(r, logger),
 		},
 		Logger: logger,
 	}
@@ -145,8 +149,12 @@ func (r *Router) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	// Schedule flushing active app's app_id
 	r.ScheduleFlushApps()
 
-	r.logger.Debug("Sleeping before returning success on /health endpoint to preload routing table", zap.Float64("sleep_time_seconds", r.config.StartResponseDelayInterval.Seconds()))
-	time.Sleep(r.config.StartResponseDelayInterval)
+	if r.restoredEndpoints > 0 {
+		r.logger.Info("routing-table-restored-skipping-start-response-delay", zap.Int("endpoints", r.restoredEndpoints))
+	} else {
+		r.logger.Debug("Sleeping before returning success on /health endpoint to preload routing table", zap.Float64("sleep_time_seconds", r.config.StartResponseDelayInterval.Seconds()))
+		time.Sleep(r.config.StartResponseDelayInterval)
+	}
 
 	server := &http.Server{
 		Handler:     r.handler,
@@ -198,6 +206,14 @@ func (r *Router) writePidFile(pidFile string) error {
 	return nil
 }
 
+// SetRestoredEndpoints tells the router how many endpoints the routing table
+// was restored with from a snapshot. When there are any, the routing table
+// does not need to be preloaded and Run does not wait for
+// StartResponseDelayInterval.
+func (r *Router) SetRestoredEndpoints(n int) {
+	r.restoredEndpoints = n
+}
+
 func (r *Router) OnErrOrSignal(signals <-chan os.Signal, errChan chan error) {
 	select {
 	case err := <-errChan:
@@ -409,12 +425,17 @@ func (r *Router) stopListening() {
 	r.routeServicesServer.Stop()
 }
 
+// RegisterComponent announces the router on NATS. Without a NATS connection
+// there is nothing to announce.
 func (r *Router) RegisterComponent() {
+	if r.mbusClient == nil {
+		return
+	}
 	r.component.Register(r.mbusClient)
 }
 
 func (r *Router) ScheduleFlushApps() {
-	if r.config.PublishActiveAppsInterval == 0 {
+	if r.config.PublishActiveAppsInterval == 0 || r.mbusClient == nil {
 		return
 	}
 
@@ -233,7 +233,7 @@ var _ = Describe("Router", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		config.Index = 4321
-		subscriber = ifrit.Background(mbus.NewSubscriber(mbusClient, registry, config, nil, logger.Session("subscriber")))
+		subscriber = ifrit.Background(mbus.NewSubscriber(mbusClient, registry, new(fakes.FakeRouteRegistryReporter), config, nil, logger.Session("subscriber")))
 		<-subscriber.Ready()
 	})
 
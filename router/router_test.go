@@ -107,7 +107,7 @@ var _ = Describe("Router", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		config.Index = 4321
-		subscriber := mbus.NewSubscriber(mbusClient, registry, config, nil, logger.Session("subscriber"))
+		subscriber := mbus.NewSubscriber(mbusClient, registry, fakeReporter, config, nil, logger.Session("subscriber"))
 
 		members := grouper.Members{
 			{Name: "subscriber", Runner: subscriber},
@@ -246,6 +246,26 @@ var _ = Describe("Router", func() {
 			Eventually(logger).Should(gbytes.Say("Sleeping before returning success on /health endpoint to preload routing table"))
 			verify_health(fmt.Sprintf("localhost:%d", statusPort))
 		})
+
+		It("makes the health check endpoint available immediately when endpoints were restored", func() {
+			natsPort := test_util.NextAvailPort()
+			proxyPort := test_util.NextAvailPort()
+			statusPort = test_util.NextAvailPort()
+			c = test_util.SpecConfig(statusPort, proxyPort, natsPort)
+			c.StartResponseDelayInterval = 10 * time.Second
+
+			rtr, err = initializeRouter(c, registry, varz, mbusClient, logger, routeServicesServer)
+			Expect(err).ToNot(HaveOccurred())
+			rtr.SetRestoredEndpoints(3)
+
+			signals := make(chan os.Signal)
+			readyChan := make(chan struct{})
+			go rtr.Run(signals, readyChan)
+
+			Eventually(readyChan, "2s").Should(BeClosed())
+			Eventually(logger).Should(gbytes.Say("routing-table-restored-skipping-start-response-delay"))
+			signals <- syscall.SIGUSR1
+		})
 	})
 
 	It("registry contains last updated varz", func() {
@@ -0,0 +1,246 @@
+package routesource
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tedsuo/ifrit"
+	"github.com/uber-go/zap"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+func init() {
+	Register("dns", NewDNSSource)
+}
+
+// DNSSource registers the backends of DNS services. SRV records of the lowest
+// priority are used as they are, targets are resolved to their addresses;
+// weights are ignored.
+type DNSSource struct {
+	client      *dnsClient
+	services    []config.DNSService
+	interval    time.Duration
+	minInterval time.Duration
+	sink        Sink
+	logger      logger.Logger
+
+	routes  [][]Route
+	backoff time.Duration
+}
+
+func NewDNSSource(c *config.Config, sink Sink, logger logger.Logger) (ifrit.Runner, error) {
+	dns := c.RouteSources.DNS
+	if len(dns.Services) == 0 {
+		return nil, nil
+	}
+
+	for _, s := range dns.Services {
+		if s.TLS && !c.Backends.EnableTLS {
+			return nil, fmt.Errorf("service %s: backend tls is not enabled", s.Name)
+		}
+	}
+
+	return &DNSSource{
+		client:      newDNSClient(dns.Nameservers, dns.Timeout),
+		services:    dns.Services,
+		interval:    dns.Interval,
+		minInterval: dns.MinInterval,
+		sink:        sink,
+		logger:      logger,
+		routes:      make([][]Route, len(dns.Services)),
+	}, nil
+}
+
+func (d *DNSSource) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	timer := time.NewTimer(d.refresh())
+	defer timer.Stop()
+	close(ready)
+
+	for {
+		select {
+		case <-timer.C:
+			timer.Reset(d.refresh())
+		case <-signals:
+			d.logger.Info("exited")
+			return nil
+		}
+	}
+}
+
+// refresh resolves all services and returns when they need to be resolved
+// again: after the interval, or, when a service could not be resolved, after
+// a delay that starts at the min interval and doubles up to the interval. A
+// service that cannot be resolved keeps its previous routes.
+func (d *DNSSource) refresh() time.Duration {
+	failed := false
+	var routes []Route
+
+	for i, s := range d.services {
+		r, err := d.resolve(s)
+		if err != nil {
+			d.logger.Error("error-resolving-service", zap.String("name", s.Name), zap.String("route", s.Route), zap.Error(err))
+			failed = true
+		} else {
+			d.routes[i] = r
+		}
+		routes = append(routes, d.routes[i]...)
+	}
+	d.sink.Sync(routes)
+
+	if !failed {
+		d.backoff = 0
+		return d.interval
+	}
+
+	if d.backoff == 0 {
+		d.backoff = d.minInterval
+	} else {
+		d.backoff *= 2
+	}
+	if d.backoff > d.interval {
+		d.backoff = d.interval
+	}
+	return d.backoff
+}
+
+// resolve returns the routes of a service. A name that does not exist has no
+// routes.
+func (d *DNSSource) resolve(s config.DNSService) ([]Route, error) {
+	var routes []Route
+
+	add := func(hosts []string, port uint16) {
+		for _, host := range hosts {
+			routes = append(routes, Route{
+				Uri: route.Uri(s.Route),
+				Endpoint: route.NewEndpoint(&route.EndpointOpts{
+					Host:                host,
+					Port:                port,
+					UseTLS:              s.TLS,
+					ServerCertDomainSAN: s.ServerCertDomainSAN,
+				}),
+			})
+		}
+	}
+
+	if s.Type == config.DNS_A {
+		hosts, err := d.client.lookupHosts(s.Name)
+		if err != nil {
+			return nil, err
+		}
+		add(hosts, s.Port)
+		return routes, nil
+	}
+
+	records, err := d.client.lookupSRV(s.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// the records are sorted by priority
+	for _, srv := range records {
+		if srv.Priority > records[0].Priority {
+			break
+		}
+		hosts, err := d.client.lookupHosts(srv.Target)
+		if err != nil {
+			return nil, err
+		}
+		add(hosts, srv.Port)
+	}
+	return routes, nil
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// dnsClient sends queries to the nameservers in order until one of them
+// answers, or to the nameservers of the system when none are configured.
+type dnsClient struct {
+	resolvers []*net.Resolver
+	timeout   time.Duration
+}
+
+func newDNSClient(nameservers []string, timeout time.Duration) *dnsClient {
+	c := &dnsClient{timeout: timeout}
+	if len(nameservers) == 0 {
+		c.resolvers = []*net.Resolver{net.DefaultResolver}
+		return c
+	}
+
+	for _, n := range nameservers {
+		server := n
+		if _, _, err := net.SplitHostPort(n); err != nil {
+			server = net.JoinHostPort(n, "53")
+		}
+		c.resolvers = append(c.resolvers, &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		})
+	}
+	return c
+}
+
+// lookupSRV returns the SRV records of a name, sorted by priority.
+func (c *dnsClient) lookupSRV(name string) ([]*net.SRV, error) {
+	var records []*net.SRV
+	err := c.lookup(func(ctx context.Context, r *net.Resolver) error {
+		var err error
+		_, records, err = r.LookupSRV(ctx, "", "", fqdn(name))
+		return err
+	})
+	return records, err
+}
+
+// lookupHosts returns the addresses of a name, with IPv6 addresses in
+// brackets.
+func (c *dnsClient) lookupHosts(name string) ([]string, error) {
+	var addrs []net.IPAddr
+	err := c.lookup(func(ctx context.Context, r *net.Resolver) error {
+		var err error
+		addrs, err = r.LookupIPAddr(ctx, fqdn(name))
+		return err
+	})
+
+	hosts := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			hosts = append(hosts, a.IP.String())
+		} else {
+			hosts = append(hosts, "["+a.IP.String()+"]")
+		}
+	}
+	return hosts, err
+}
+
+// lookup runs the query against the resolvers in order until one answers. A
+// name that does not exist is not an error.
+func (c *dnsClient) lookup(query func(context.Context, *net.Resolver) error) error {
+	var err error
+	for _, r := range c.resolvers {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		err = query(ctx, r)
+		cancel()
+
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return nil
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,148 @@
+package routesource_test
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/routesource"
+	"code.cloudfoundry.org/gorouter/test_util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("DNSSource", func() {
+	var (
+		server    *test_util.DNSServer
+		configObj *config.Config
+		r         *registry.RouteRegistry
+		process   ifrit.Process
+	)
+
+	const srvName = "_http._tcp.backend.service.internal"
+
+	addrs := func(uri route.Uri) []string {
+		var addrs []string
+		if pool := r.Lookup(uri); pool != nil {
+			pool.Each(func(e *route.Endpoint) { addrs = append(addrs, e.CanonicalAddr()) })
+		}
+		return addrs
+	}
+
+	BeforeEach(func() {
+		server = test_util.NewDNSServer()
+		server.SetSRV(srvName,
+			test_util.SRVRecord{Priority: 10, Port: 8080, Target: "backend-0.internal"},
+			test_util.SRVRecord{Priority: 10, Port: 8081, Target: "backend-1.internal"},
+			test_util.SRVRecord{Priority: 20, Port: 8082, Target: "backup.internal"},
+		)
+		server.SetA("backend-0.internal", "10.0.0.1")
+		server.SetA("backend-1.internal", "10.0.0.2")
+		server.SetA("backup.internal", "10.0.0.3")
+
+		var err error
+		configObj, err = config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		configObj.RouteSources.DNS.Nameservers = []string{server.Addr}
+		configObj.RouteSources.DNS.Interval = time.Hour
+		configObj.RouteSources.DNS.MinInterval = 10 * time.Millisecond
+		configObj.RouteSources.DNS.Services = []config.DNSService{
+			{Route: "backend.example.com", Name: srvName, Type: config.DNS_SRV},
+		}
+
+		r = registry.NewRouteRegistry(test_util.NewTestZapLogger("test"), configObj, new(fakes.FakeRouteRegistryReporter))
+		source.enabled = false
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive())
+			process = nil
+		}
+		server.Close()
+	})
+
+	start := func() {
+		manager, err := routesource.NewManager(configObj, r, test_util.NewTestZapLogger("test"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(manager.Sources()).To(Equal([]string{"dns"}))
+		process = ifrit.Invoke(manager)
+		Eventually(process.Ready()).Should(BeClosed())
+	}
+
+	It("registers the SRV targets of the lowest priority", func() {
+		start()
+
+		Expect(addrs("backend.example.com")).To(ConsistOf("10.0.0.1:8080", "10.0.0.2:8081"))
+		r.Lookup("backend.example.com").Each(func(e *route.Endpoint) {
+			Expect(e.Source).To(Equal("dns"))
+		})
+	})
+
+	It("registers A and AAAA records with the configured port", func() {
+		server.SetA("web.internal", "10.0.1.1", "fd00::1")
+		configObj.RouteSources.DNS.Services = []config.DNSService{
+			{Route: "web.example.com", Name: "web.internal", Type: config.DNS_A, Port: 9000},
+		}
+		start()
+
+		Expect(addrs("web.example.com")).To(ConsistOf("10.0.1.1:9000", "[fd00::1]:9000"))
+	})
+
+	It("does not resolve the services again before the interval", func() {
+		start()
+
+		Consistently(func() int { return server.Queries(srvName, test_util.DNSTypeSRV) }, 100*time.Millisecond).Should(Equal(1))
+	})
+
+	It("resolves the services again after the interval", func() {
+		configObj.RouteSources.DNS.Interval = 20 * time.Millisecond
+		start()
+
+		server.SetA("backend-0.internal", "10.0.0.4")
+		Eventually(func() []string { return addrs("backend.example.com") }).Should(ConsistOf("10.0.0.4:8080", "10.0.0.2:8081"))
+	})
+
+	It("keeps the routes when the nameserver fails", func() {
+		configObj.RouteSources.DNS.Interval = 20 * time.Millisecond
+		start()
+
+		server.SetServerFailure(true)
+		Eventually(func() int { return server.Queries(srvName, test_util.DNSTypeSRV) }).Should(BeNumerically(">", 2))
+		Expect(addrs("backend.example.com")).To(ConsistOf("10.0.0.1:8080", "10.0.0.2:8081"))
+	})
+
+	It("backs off while the nameserver fails", func() {
+		server.SetServerFailure(true)
+		configObj.RouteSources.DNS.MinInterval = 20 * time.Millisecond
+		start()
+
+		// without backing off the service would be resolved 25 times
+		time.Sleep(500 * time.Millisecond)
+		Expect(server.Queries(srvName, test_util.DNSTypeSRV)).To(BeNumerically(">", 2))
+		Expect(server.Queries(srvName, test_util.DNSTypeSRV)).To(BeNumerically("<=", 12))
+
+		server.SetServerFailure(false)
+		Eventually(func() []string { return addrs("backend.example.com") }, 2*time.Second).Should(ConsistOf("10.0.0.1:8080", "10.0.0.2:8081"))
+	})
+
+	It("removes the routes when the name no longer exists", func() {
+		configObj.RouteSources.DNS.Interval = 20 * time.Millisecond
+		start()
+
+		server.SetSRV(srvName)
+		Eventually(func() *route.Pool { return r.Lookup("backend.example.com") }).Should(BeNil())
+	})
+
+	It("rejects tls services when backend tls is disabled", func() {
+		configObj.RouteSources.DNS.Services[0].TLS = true
+
+		_, err := routesource.NewManager(configObj, r, test_util.NewTestZapLogger("test"))
+		Expect(err).To(MatchError("route source dns: service " + srvName + ": backend tls is not enabled"))
+	})
+})
@@ -0,0 +1,173 @@
+package routesource
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tedsuo/ifrit"
+	"github.com/uber-go/zap"
+	"gopkg.in/yaml.v2"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+func init() {
+	Register("file", NewFileSource)
+}
+
+// routesFile is the format of the routes file. JSON files are read as well,
+// since JSON is valid YAML.
+type routesFile struct {
+	Routes []fileRoute `yaml:"routes"`
+}
+
+type fileRoute struct {
+	Host            string            `yaml:"host"`
+	Path            string            `yaml:"path"`
+	AppId           string            `yaml:"app_id"`
+	RouteServiceUrl string            `yaml:"route_service_url"`
+	Tags            map[string]string `yaml:"tags"`
+	Endpoints       []fileEndpoint    `yaml:"endpoints"`
+}
+
+type fileEndpoint struct {
+	Host                string `yaml:"host"`
+	Port                uint16 `yaml:"port"`
+	TLS                 bool   `yaml:"tls"`
+	ServerCertDomainSAN string `yaml:"server_cert_domain_san"`
+}
+
+// FileSource registers the routes listed in a YAML or JSON file and reads the
+// file again every interval.
+type FileSource struct {
+	path      string
+	interval  time.Duration
+	acceptTLS bool
+	sink      Sink
+	logger    logger.Logger
+
+	contents []byte
+	routes   []Route
+}
+
+// NewFileSource reads the routes file of the config, so that an invalid file
+// is reported at startup.
+func NewFileSource(c *config.Config, sink Sink, logger logger.Logger) (ifrit.Runner, error) {
+	if c.RouteSources.File.Path == "" {
+		return nil, nil
+	}
+
+	f := &FileSource{
+		path:      c.RouteSources.File.Path,
+		interval:  c.RouteSources.File.Interval,
+		acceptTLS: c.Backends.EnableTLS,
+		sink:      sink,
+		logger:    logger,
+	}
+
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	f.routes, err = parseRoutesFile(b, f.acceptTLS)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", f.path, err)
+	}
+	f.contents = b
+
+	return f, nil
+}
+
+func (f *FileSource) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	f.sink.Sync(f.routes)
+	close(ready)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f.reload()
+		case <-signals:
+			f.logger.Info("exited")
+			return nil
+		}
+	}
+}
+
+func (f *FileSource) reload() {
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		f.logger.Error("error-reading-routes-file", zap.String("path", f.path), zap.Error(err))
+		return
+	}
+	if bytes.Equal(b, f.contents) {
+		return
+	}
+
+	routes, err := parseRoutesFile(b, f.acceptTLS)
+	if err != nil {
+		f.logger.Error("error-loading-routes-file", zap.String("path", f.path), zap.Error(err))
+		return
+	}
+
+	f.contents = b
+	f.sink.Sync(routes)
+	f.logger.Info("routes-file-reloaded", zap.String("path", f.path), zap.Int("routes", len(routes)))
+}
+
+func parseRoutesFile(b []byte, acceptTLS bool) ([]Route, error) {
+	var f routesFile
+	err := yaml.Unmarshal(b, &f)
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []Route
+	for _, r := range f.Routes {
+		if r.Host == "" {
+			return nil, errors.New("route without a host")
+		}
+		uri := route.Uri(r.Host + r.Path)
+		if r.Path != "" && !strings.HasPrefix(r.Path, "/") {
+			return nil, fmt.Errorf("route %s: path must start with /", uri)
+		}
+		if r.RouteServiceUrl != "" && !strings.HasPrefix(r.RouteServiceUrl, "https") {
+			return nil, fmt.Errorf("route %s: route_service_url must be https", uri)
+		}
+		if len(r.Endpoints) == 0 {
+			return nil, fmt.Errorf("route %s: no endpoints", uri)
+		}
+
+		for _, e := range r.Endpoints {
+			if e.Host == "" || e.Port == 0 {
+				return nil, fmt.Errorf("route %s: endpoints need a host and a port", uri)
+			}
+			if e.TLS && !acceptTLS {
+				return nil, fmt.Errorf("route %s: backend tls is not enabled", uri)
+			}
+
+			routes = append(routes, Route{
+				Uri: uri,
+				Endpoint: route.NewEndpoint(&route.EndpointOpts{
+					AppId:               r.AppId,
+					Host:                e.Host,
+					Port:                e.Port,
+					UseTLS:              e.TLS,
+					ServerCertDomainSAN: e.ServerCertDomainSAN,
+					RouteServiceUrl:     r.RouteServiceUrl,
+					Tags:                r.Tags,
+				}),
+			})
+		}
+	}
+	return routes, nil
+}
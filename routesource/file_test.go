@@ -0,0 +1,160 @@
+package routesource_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/routesource"
+	"code.cloudfoundry.org/gorouter/test_util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("FileSource", func() {
+	var (
+		dir, path string
+		configObj *config.Config
+		r         *registry.RouteRegistry
+		process   ifrit.Process
+	)
+
+	const routesYAML = `
+routes:
+- host: legacy.example.com
+  app_id: legacy
+  tags:
+    component: legacy
+  endpoints:
+  - host: 10.0.0.1
+    port: 8080
+  - host: 10.0.0.2
+    port: 8443
+    tls: true
+    server_cert_domain_san: legacy.internal
+- host: api.example.com
+  path: /v1
+  route_service_url: https://rs.example.com
+  endpoints:
+  - host: 10.0.1.1
+    port: 9000
+`
+
+	endpoints := func(uri route.Uri) []*route.Endpoint {
+		var eps []*route.Endpoint
+		if pool := r.Lookup(uri); pool != nil {
+			pool.Each(func(e *route.Endpoint) { eps = append(eps, e) })
+		}
+		return eps
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "route-source")
+		Expect(err).ToNot(HaveOccurred())
+		path = filepath.Join(dir, "routes.yml")
+		Expect(ioutil.WriteFile(path, []byte(routesYAML), 0644)).To(Succeed())
+
+		configObj, err = config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		configObj.Backends.EnableTLS = true
+		configObj.RouteSources.File.Path = path
+		configObj.RouteSources.File.Interval = 10 * time.Millisecond
+
+		r = registry.NewRouteRegistry(test_util.NewTestZapLogger("test"), configObj, new(fakes.FakeRouteRegistryReporter))
+		source.enabled = false
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive())
+			process = nil
+		}
+		os.RemoveAll(dir)
+	})
+
+	start := func() {
+		manager, err := routesource.NewManager(configObj, r, test_util.NewTestZapLogger("test"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(manager.Sources()).To(Equal([]string{"file"}))
+		process = ifrit.Invoke(manager)
+		Eventually(process.Ready()).Should(BeClosed())
+	}
+
+	It("registers the routes of the file", func() {
+		start()
+
+		eps := endpoints("legacy.example.com")
+		Expect(eps).To(HaveLen(2))
+		Expect(eps[0].CanonicalAddr()).To(Equal("10.0.0.1:8080"))
+		Expect(eps[0].ApplicationId).To(Equal("legacy"))
+		Expect(eps[0].Tags).To(Equal(map[string]string{"component": "legacy"}))
+		Expect(eps[0].Source).To(Equal("file"))
+		Expect(eps[1].IsTLS()).To(BeTrue())
+		Expect(eps[1].ServerCertDomainSAN).To(Equal("legacy.internal"))
+
+		eps = endpoints("api.example.com/v1/users")
+		Expect(eps).To(HaveLen(1))
+		Expect(eps[0].RouteServiceUrl).To(Equal("https://rs.example.com"))
+	})
+
+	It("applies changes to the file", func() {
+		start()
+
+		Expect(ioutil.WriteFile(path, []byte(`
+routes:
+- host: legacy.example.com
+  endpoints:
+  - host: 10.0.0.3
+    port: 8080
+`), 0644)).To(Succeed())
+
+		Eventually(func() []*route.Endpoint { return endpoints("legacy.example.com") }).Should(HaveLen(1))
+		Expect(endpoints("legacy.example.com")[0].CanonicalAddr()).To(Equal("10.0.0.3:8080"))
+		Expect(r.Lookup("api.example.com/v1")).To(BeNil())
+	})
+
+	It("keeps the routes when the file becomes invalid", func() {
+		start()
+
+		Expect(ioutil.WriteFile(path, []byte("routes:\n- endpoints: []\n"), 0644)).To(Succeed())
+		Consistently(r.NumEndpoints, 100*time.Millisecond).Should(Equal(3))
+	})
+
+	It("reads JSON files", func() {
+		Expect(ioutil.WriteFile(path, []byte(`{"routes": [{"host": "json.example.com", "endpoints": [{"host": "10.0.0.1", "port": 80}]}]}`), 0644)).To(Succeed())
+		start()
+
+		Expect(endpoints("json.example.com")).To(HaveLen(1))
+	})
+
+	Context("when the file is invalid at startup", func() {
+		It("returns an error", func() {
+			Expect(ioutil.WriteFile(path, []byte("routes:\n- host: foo.example.com\n"), 0644)).To(Succeed())
+
+			_, err := routesource.NewManager(configObj, r, test_util.NewTestZapLogger("test"))
+			Expect(err).To(MatchError("route source file: " + path + ": route foo.example.com: no endpoints"))
+		})
+
+		It("rejects tls endpoints when backend tls is disabled", func() {
+			configObj.Backends.EnableTLS = false
+
+			_, err := routesource.NewManager(configObj, r, test_util.NewTestZapLogger("test"))
+			Expect(err).To(MatchError("route source file: " + path + ": route legacy.example.com: backend tls is not enabled"))
+		})
+
+		It("returns an error when the file is missing", func() {
+			configObj.RouteSources.File.Path = filepath.Join(dir, "missing.yml")
+
+			_, err := routesource.NewManager(configObj, r, test_util.NewTestZapLogger("test"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
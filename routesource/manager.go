@@ -0,0 +1,163 @@
+package routesource
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/tedsuo/ifrit"
+	"github.com/uber-go/zap"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/registry"
+)
+
+// Manager runs the configured route sources. Stopping the manager stops the
+// sources but leaves their routes in place; disabling a source, or a source
+// that fails, removes its routes from the registry.
+type Manager struct {
+	logger logger.Logger
+
+	lock     sync.Mutex
+	sources  map[string]*source
+	order    []string
+	stopping bool
+}
+
+type source struct {
+	name    string
+	runner  ifrit.Runner
+	sink    *sink
+	process ifrit.Process
+}
+
+// NewManager builds every registered source that is configured.
+func NewManager(c *config.Config, registry registry.Registry, logger logger.Logger) (*Manager, error) {
+	m := &Manager{
+		logger:  logger,
+		sources: make(map[string]*source),
+	}
+
+	for _, name := range factoryNames() {
+		sink := newSink(name, registry, logger.Session(name))
+		runner, err := factories[name](c, sink, logger.Session(name))
+		if err != nil {
+			return nil, fmt.Errorf("route source %s: %s", name, err)
+		}
+		if runner == nil {
+			continue
+		}
+		m.add(name, runner, sink)
+	}
+
+	return m, nil
+}
+
+func (m *Manager) add(name string, runner ifrit.Runner, sink *sink) {
+	m.sources[name] = &source{name: name, runner: runner, sink: sink}
+	m.order = append(m.order, name)
+}
+
+// Sources returns the names of the running sources.
+func (m *Manager) Sources() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	names := make([]string, len(m.order))
+	copy(names, m.order)
+	return names
+}
+
+func (m *Manager) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	m.lock.Lock()
+	for _, name := range m.order {
+		s := m.sources[name]
+		s.process = ifrit.Background(s.runner)
+		go m.watch(s)
+	}
+	sources := make([]*source, 0, len(m.order))
+	for _, name := range m.order {
+		sources = append(sources, m.sources[name])
+	}
+	m.lock.Unlock()
+
+	for _, s := range sources {
+		select {
+		case <-s.process.Ready():
+		case <-s.process.Wait():
+		case sig := <-signals:
+			m.stop(sig)
+			return nil
+		}
+	}
+
+	close(ready)
+	m.logger.Info("route-sources-started", zap.Object("sources", m.Sources()))
+
+	sig := <-signals
+	m.stop(sig)
+	m.logger.Info("exited")
+	return nil
+}
+
+func (m *Manager) watch(s *source) {
+	err := <-s.process.Wait()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.stopping || m.sources[s.name] != s {
+		return
+	}
+
+	if err != nil {
+		m.logger.Error("route-source-exited", zap.String("source", s.name), zap.Error(err))
+	} else {
+		m.logger.Info("route-source-exited", zap.String("source", s.name))
+	}
+	m.disable(s)
+}
+
+func (m *Manager) stop(sig os.Signal) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.stopping = true
+	for _, name := range m.order {
+		s := m.sources[name]
+		s.process.Signal(sig)
+		<-s.process.Wait()
+	}
+}
+
+// Disable stops the source and removes all of its routes from the registry.
+func (m *Manager) Disable(name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	s, ok := m.sources[name]
+	if !ok {
+		return fmt.Errorf("unknown route source %s", name)
+	}
+
+	if s.process != nil {
+		s.process.Signal(os.Interrupt)
+		<-s.process.Wait()
+	}
+	m.disable(s)
+	return nil
+}
+
+func (m *Manager) disable(s *source) {
+	s.sink.Sync(nil)
+
+	delete(m.sources, s.name)
+	for i, n := range m.order {
+		if n == s.name {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+
+	m.logger.Info("route-source-disabled", zap.String("source", s.name))
+}
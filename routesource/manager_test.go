@@ -0,0 +1,200 @@
+package routesource_test
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/metrics/fakes"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+	"code.cloudfoundry.org/gorouter/routesource"
+	"code.cloudfoundry.org/gorouter/test_util"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+// testSource is built by the "test" factory when it is enabled
+type testSource struct {
+	lock    sync.Mutex
+	enabled bool
+	err     error
+	sink    routesource.Sink
+	stopped chan struct{}
+	fail    chan error
+}
+
+func (t *testSource) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+	select {
+	case <-signals:
+	case err := <-t.fail:
+		return err
+	}
+	close(t.stopped)
+	return nil
+}
+
+func (t *testSource) Sync(routes ...routesource.Route) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.sink.Sync(routes)
+}
+
+var source = &testSource{}
+
+func init() {
+	routesource.Register("test", func(c *config.Config, sink routesource.Sink, _ logger.Logger) (ifrit.Runner, error) {
+		source.lock.Lock()
+		defer source.lock.Unlock()
+		if source.err != nil {
+			return nil, source.err
+		}
+		if !source.enabled {
+			return nil, nil
+		}
+		source.sink = sink
+		source.stopped = make(chan struct{})
+		source.fail = make(chan error)
+		return source, nil
+	})
+}
+
+var _ = Describe("Manager", func() {
+	var (
+		manager   *routesource.Manager
+		r         *registry.RouteRegistry
+		reporter  *fakes.FakeRouteRegistryReporter
+		configObj *config.Config
+		process   ifrit.Process
+		endpoint  *route.Endpoint
+	)
+
+	BeforeEach(func() {
+		var err error
+		configObj, err = config.DefaultConfig()
+		Expect(err).ToNot(HaveOccurred())
+		configObj.PruneStaleDropletsInterval = 50 * time.Millisecond
+		configObj.DropletStaleThreshold = 50 * time.Millisecond
+
+		reporter = new(fakes.FakeRouteRegistryReporter)
+		r = registry.NewRouteRegistry(test_util.NewTestZapLogger("test"), configObj, reporter)
+
+		source.enabled = true
+		source.err = nil
+
+		manager, err = routesource.NewManager(configObj, r, test_util.NewTestZapLogger("test"))
+		Expect(err).ToNot(HaveOccurred())
+		process = ifrit.Invoke(manager)
+		Eventually(process.Ready()).Should(BeClosed())
+
+		endpoint = route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.1", Port: 8080})
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	It("runs the configured sources", func() {
+		Expect(manager.Sources()).To(Equal([]string{"test"}))
+	})
+
+	It("does not run sources that are not configured", func() {
+		source.enabled = false
+		m, err := routesource.NewManager(configObj, r, test_util.NewTestZapLogger("test"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Sources()).To(BeEmpty())
+	})
+
+	It("returns the errors of the sources", func() {
+		source.err = errors.New("bad config")
+		_, err := routesource.NewManager(configObj, r, test_util.NewTestZapLogger("test"))
+		Expect(err).To(MatchError("route source test: bad config"))
+	})
+
+	Describe("Sync", func() {
+		It("registers the routes of the source", func() {
+			source.Sync(
+				routesource.Route{Uri: "foo.example.com", Endpoint: endpoint},
+				routesource.Route{Uri: "bar.example.com/path", Endpoint: route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.2", Port: 8080})},
+			)
+
+			Expect(r.NumUris()).To(Equal(2))
+			Expect(r.NumEndpoints()).To(Equal(2))
+			Expect(endpoint.Source).To(Equal("test"))
+		})
+
+		It("does not let the routes be pruned", func() {
+			source.Sync(routesource.Route{Uri: "foo.example.com", Endpoint: endpoint})
+			r.StartPruningCycle()
+			defer r.StopPruningCycle()
+
+			Consistently(r.NumEndpoints, 200*time.Millisecond).Should(Equal(1))
+		})
+
+		It("applies the difference to the previous routes", func() {
+			source.Sync(
+				routesource.Route{Uri: "foo.example.com", Endpoint: endpoint},
+				routesource.Route{Uri: "bar.example.com", Endpoint: route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.2", Port: 8080})},
+			)
+			Expect(reporter.CaptureRegistryMessageCallCount()).To(Equal(2))
+
+			changed := route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.2", Port: 8080, Tags: map[string]string{"component": "legacy"}})
+			source.Sync(
+				routesource.Route{Uri: "foo.example.com", Endpoint: route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.1", Port: 8080})},
+				routesource.Route{Uri: "bar.example.com", Endpoint: changed},
+				routesource.Route{Uri: "baz.example.com", Endpoint: route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.3", Port: 8080})},
+			)
+			Expect(reporter.CaptureRegistryMessageCallCount()).To(Equal(4))
+			Expect(reporter.CaptureUnregistryMessageCallCount()).To(Equal(0))
+
+			var tags map[string]string
+			r.Lookup("bar.example.com").Each(func(e *route.Endpoint) { tags = e.Tags })
+			Expect(tags).To(Equal(map[string]string{"component": "legacy"}))
+
+			source.Sync(routesource.Route{Uri: "baz.example.com", Endpoint: route.NewEndpoint(&route.EndpointOpts{Host: "10.0.0.3", Port: 8080})})
+			Expect(reporter.CaptureUnregistryMessageCallCount()).To(Equal(2))
+			Expect(r.Lookup("foo.example.com")).To(BeNil())
+			Expect(r.Lookup("bar.example.com")).To(BeNil())
+			Expect(r.Lookup("baz.example.com")).ToNot(BeNil())
+		})
+	})
+
+	Describe("Disable", func() {
+		It("stops the source and removes its routes", func() {
+			source.Sync(routesource.Route{Uri: "foo.example.com", Endpoint: endpoint})
+
+			Expect(manager.Disable("test")).To(Succeed())
+			Expect(source.stopped).To(BeClosed())
+			Expect(r.NumEndpoints()).To(Equal(0))
+			Expect(manager.Sources()).To(BeEmpty())
+		})
+
+		It("returns an error for unknown sources", func() {
+			Expect(manager.Disable("missing")).To(MatchError("unknown route source missing"))
+		})
+	})
+
+	It("disables sources that fail", func() {
+		source.Sync(routesource.Route{Uri: "foo.example.com", Endpoint: endpoint})
+
+		source.fail <- errors.New("boom")
+		Eventually(manager.Sources).Should(BeEmpty())
+		Expect(r.NumEndpoints()).To(Equal(0))
+		Consistently(process.Wait()).ShouldNot(Receive())
+	})
+
+	It("keeps the routes when the manager stops", func() {
+		source.Sync(routesource.Route{Uri: "foo.example.com", Endpoint: endpoint})
+
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+		Expect(source.stopped).To(BeClosed())
+		Expect(r.NumEndpoints()).To(Equal(1))
+	})
+})
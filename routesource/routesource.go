@@ -0,0 +1,53 @@
+package routesource
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tedsuo/ifrit"
+
+	"code.cloudfoundry.org/gorouter/config"
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+// Route is an endpoint that a source asserts for a uri.
+type Route struct {
+	Uri      route.Uri
+	Endpoint *route.Endpoint
+}
+
+// Sink receives the routes of a single source.
+//
+// Sync replaces all routes the source asserted before with the given ones.
+// The registry is updated with the difference in one batch: new and changed
+// endpoints are registered, endpoints that are no longer listed are
+// unregistered. The endpoints are owned by the source and never pruned.
+type Sink interface {
+	Sync(routes []Route)
+}
+
+// Factory builds a source from the config. The runner keeps the routes of the
+// source in sync through the sink until it is signalled. A nil runner means
+// that the source is not configured.
+type Factory func(c *config.Config, sink Sink, logger logger.Logger) (ifrit.Runner, error)
+
+var factories = map[string]Factory{}
+
+// Register makes a source available under the given name. Sources call it
+// from the init function of the file that implements them.
+func Register(name string, factory Factory) {
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("routesource: source %s registered twice", name))
+	}
+	factories[name] = factory
+}
+
+func factoryNames() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,104 @@
+package routesource
+
+import (
+	"sync"
+
+	"github.com/uber-go/zap"
+
+	"code.cloudfoundry.org/gorouter/logger"
+	"code.cloudfoundry.org/gorouter/registry"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+type routeKey struct {
+	uri  route.Uri
+	addr string
+}
+
+type sink struct {
+	name     string
+	registry registry.Registry
+	logger   logger.Logger
+
+	lock   sync.Mutex
+	routes map[routeKey]Route
+}
+
+func newSink(name string, registry registry.Registry, logger logger.Logger) *sink {
+	return &sink{
+		name:     name,
+		registry: registry,
+		logger:   logger,
+		routes:   make(map[routeKey]Route),
+	}
+}
+
+func (s *sink) Sync(routes []Route) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	next := make(map[routeKey]Route, len(routes))
+	for _, r := range routes {
+		r.Endpoint.Source = s.name
+		next[routeKey{uri: r.Uri.RouteKey(), addr: r.Endpoint.CanonicalAddr()}] = r
+	}
+
+	var updates []registry.RouteUpdate
+	added, changed, removed := 0, 0, 0
+
+	for k, r := range s.routes {
+		if _, ok := next[k]; !ok {
+			updates = append(updates, registry.RouteUpdate{Uri: r.Uri, Endpoint: r.Endpoint, Unregister: true})
+			removed++
+		}
+	}
+
+	// registered in the order of the routes, which is the order of the
+	// endpoints in their pools
+	for _, r := range routes {
+		k := routeKey{uri: r.Uri.RouteKey(), addr: r.Endpoint.CanonicalAddr()}
+		if next[k].Endpoint != r.Endpoint {
+			continue
+		}
+		old, ok := s.routes[k]
+		if ok && unchanged(old.Endpoint, r.Endpoint) {
+			next[k] = old
+			continue
+		}
+		if ok {
+			changed++
+		} else {
+			added++
+		}
+		updates = append(updates, registry.RouteUpdate{Uri: r.Uri, Endpoint: r.Endpoint})
+	}
+
+	s.routes = next
+	if len(updates) == 0 {
+		return
+	}
+
+	s.registry.UpdateBatch(updates)
+	s.logger.Info("routes-synced",
+		zap.String("source", s.name),
+		zap.Int("added", added),
+		zap.Int("changed", changed),
+		zap.Int("removed", removed),
+	)
+}
+
+// Len returns the number of routes the source asserts.
+func (s *sink) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.routes)
+}
+
+// unchanged compares a registered endpoint with its new version. The registry
+// fills in the default stale threshold of the endpoints it registers.
+func unchanged(registered, endpoint *route.Endpoint) bool {
+	if endpoint.StaleThreshold == 0 {
+		endpoint.StaleThreshold = registered.StaleThreshold
+	}
+	return registered.Equivalent(endpoint)
+}
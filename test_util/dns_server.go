@@ -0,0 +1,238 @@
+package test_util
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+)
+
+// DNS record types the DNSServer answers
+const (
+	DNSTypeA    uint16 = 1
+	DNSTypeAAAA uint16 = 28
+	DNSTypeSRV  uint16 = 33
+)
+
+const (
+	dnsRCodeServerFailure = 2
+	dnsRCodeNameError     = 3
+	dnsTTL                = 300
+)
+
+// SRVRecord is the data of an SRV record
+type SRVRecord struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   string
+}
+
+type dnsKey struct {
+	name  string
+	qtype uint16
+}
+
+// DNSServer answers DNS queries over UDP on localhost with the records it
+// has been given.
+type DNSServer struct {
+	Addr string
+
+	conn *net.UDPConn
+
+	lock          sync.Mutex
+	records       map[dnsKey][][]byte
+	queries       map[dnsKey]int
+	serverFailure bool
+}
+
+func NewDNSServer() *DNSServer {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		panic(err)
+	}
+
+	s := &DNSServer{
+		Addr:    conn.LocalAddr().String(),
+		conn:    conn,
+		records: make(map[dnsKey][][]byte),
+		queries: make(map[dnsKey]int),
+	}
+	go s.serve()
+	return s
+}
+
+// SetA replaces the A and AAAA records of a name with the addresses. Without
+// addresses the name no longer has any.
+func (s *DNSServer) SetA(name string, ips ...string) {
+	var a, aaaa [][]byte
+	for _, ip := range ips {
+		parsed := net.ParseIP(ip)
+		if v4 := parsed.To4(); v4 != nil {
+			a = append(a, []byte(v4))
+		} else {
+			aaaa = append(aaaa, []byte(parsed.To16()))
+		}
+	}
+	s.set(name, DNSTypeA, a)
+	s.set(name, DNSTypeAAAA, aaaa)
+}
+
+// SetSRV replaces the SRV records of a name. Without records the name no
+// longer has any.
+func (s *DNSServer) SetSRV(name string, records ...SRVRecord) {
+	var data [][]byte
+	for _, r := range records {
+		b := make([]byte, 6)
+		binary.BigEndian.PutUint16(b[0:], r.Priority)
+		binary.BigEndian.PutUint16(b[2:], r.Weight)
+		binary.BigEndian.PutUint16(b[4:], r.Port)
+		data = append(data, append(b, encodeDNSName(r.Target)...))
+	}
+	s.set(name, DNSTypeSRV, data)
+}
+
+func (s *DNSServer) set(name string, qtype uint16, data [][]byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := dnsKey{name: canonicalName(name), qtype: qtype}
+	if len(data) == 0 {
+		delete(s.records, key)
+		return
+	}
+	s.records[key] = data
+}
+
+// SetServerFailure makes the server answer every query with SERVFAIL.
+func (s *DNSServer) SetServerFailure(fail bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.serverFailure = fail
+}
+
+// Queries returns how often the name and type have been queried.
+func (s *DNSServer) Queries(name string, qtype uint16) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.queries[dnsKey{name: canonicalName(name), qtype: qtype}]
+}
+
+func (s *DNSServer) Close() {
+	s.conn.Close()
+}
+
+func (s *DNSServer) serve() {
+	b := make([]byte, 512)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(b)
+		if err != nil {
+			return
+		}
+
+		resp := s.answer(b[:n])
+		if resp != nil {
+			s.conn.WriteToUDP(resp, addr)
+		}
+	}
+}
+
+// answer builds the response to a query with a single question. Queries it
+// cannot parse are dropped.
+func (s *DNSServer) answer(query []byte) []byte {
+	if len(query) < 12 || binary.BigEndian.Uint16(query[4:]) != 1 {
+		return nil
+	}
+	name, end, ok := decodeDNSName(query, 12)
+	if !ok || len(query) < end+4 {
+		return nil
+	}
+	qtype := binary.BigEndian.Uint16(query[end:])
+	question := query[12 : end+4]
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := dnsKey{name: canonicalName(name), qtype: qtype}
+	s.queries[key]++
+
+	var rcode uint16
+	var answers [][]byte
+	if s.serverFailure {
+		rcode = dnsRCodeServerFailure
+	} else if data, ok := s.records[key]; ok {
+		answers = data
+	} else if !s.exists(key.name) {
+		rcode = dnsRCodeNameError
+	}
+
+	// header: the id of the query, a response that is authoritative and
+	// available for recursion, with the recursion desired flag of the query
+	resp := make([]byte, 12, 512)
+	copy(resp, query[:2])
+	flags := uint16(0x8000|0x0400|0x0080) | binary.BigEndian.Uint16(query[2:])&0x0100 | rcode
+	binary.BigEndian.PutUint16(resp[2:], flags)
+	binary.BigEndian.PutUint16(resp[4:], 1)
+	binary.BigEndian.PutUint16(resp[6:], uint16(len(answers)))
+	resp = append(resp, question...)
+
+	for _, data := range answers {
+		rr := make([]byte, 12)
+		// a pointer to the name of the question
+		binary.BigEndian.PutUint16(rr[0:], 0xC00C)
+		binary.BigEndian.PutUint16(rr[2:], qtype)
+		binary.BigEndian.PutUint16(rr[4:], 1)
+		binary.BigEndian.PutUint32(rr[6:], dnsTTL)
+		binary.BigEndian.PutUint16(rr[10:], uint16(len(data)))
+		resp = append(resp, rr...)
+		resp = append(resp, data...)
+	}
+	return resp
+}
+
+func (s *DNSServer) exists(name string) bool {
+	for k := range s.records {
+		if k.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeDNSName(name string) []byte {
+	var b []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// decodeDNSName reads an uncompressed name at the offset and returns it with
+// the offset of the first byte after it.
+func decodeDNSName(b []byte, offset int) (string, int, bool) {
+	var labels []string
+	for {
+		if offset >= len(b) {
+			return "", 0, false
+		}
+		l := int(b[offset])
+		offset++
+		if l == 0 {
+			return strings.Join(labels, ".") + ".", offset, true
+		}
+		if l&0xC0 != 0 || offset+l > len(b) {
+			return "", 0, false
+		}
+		labels = append(labels, string(b[offset:offset+l]))
+		offset += l
+	}
+}
+
+func canonicalName(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}